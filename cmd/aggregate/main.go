@@ -4,259 +4,536 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"sort"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
+	"github.com/mattsblocklist/tae/internal/aggregate"
+	"github.com/mattsblocklist/tae/internal/console"
 	"github.com/mattsblocklist/tae/internal/countries"
+	"github.com/mattsblocklist/tae/internal/exitcode"
+	"github.com/mattsblocklist/tae/internal/rundir"
 	"github.com/mattsblocklist/tae/internal/scrapers"
 )
 
-// AggregationResult contains the final output.
-type AggregationResult struct {
-	// Metadata header
-	Name         string                   `json:"name"`
-	Version      string                   `json:"version"`
-	Description  string                   `json:"description"`
-	LastModified time.Time                `json:"last_modified"`
-	
-	// Data
-	Timestamp    time.Time                `json:"timestamp"`
-	TotalCodes   int                      `json:"total_codes"`
-	Countries    []CountryWithProvenance  `json:"countries"`
-	SourceStats  map[string]SourceStats   `json:"source_stats"`
-	Errors       []string                 `json:"errors,omitempty"`
-}
-
-// CountryWithProvenance includes source information.
-type CountryWithProvenance struct {
-	Alpha2    string   `json:"alpha2"`
-	Name      string   `json:"name"`
-	Sources   []string `json:"sources"`
-	RawTokens []string `json:"raw_tokens,omitempty"`
-}
-
-// SourceStats contains statistics for each source.
-type SourceStats struct {
-	URL          string    `json:"url"`
-	FetchedAt    time.Time `json:"fetched_at"`
-	ParseStatus  string    `json:"parse_status"`
-	RawCount     int       `json:"raw_count"`
-	MatchedCount int       `json:"matched_count"`
-	Error        string    `json:"error,omitempty"`
-}
-
 func main() {
 	// Command line flags
-	outputTxt := flag.String("output-txt", "data/blocked_countries.txt", "Output text file (one code per line)")
-	outputJSON := flag.String("output-json", "data/blocked_countries.json", "Output JSON file with provenance")
+	outputTxt := flag.String("output-txt", "data/blocked_countries.txt", "Output text file (one code per line; - = stdout)")
+	outputJSON := flag.String("output-json", "data/blocked_countries.json", "Output JSON file with provenance (- = stdout)")
 	sources := flag.String("sources", "", "Comma-separated list of sources to use (empty = all)")
 	verbose := flag.Bool("verbose", false, "Enable verbose output")
+	quiet := flag.Bool("quiet", false, "Only print errors; progress and the summary are suppressed")
 	timeout := flag.Duration("timeout", 60*time.Second, "HTTP request timeout")
-	workers := flag.Int("workers", 4, "Number of concurrent workers")
+	workers := flag.Int("workers", 4, "Number of concurrent workers (0 = auto)")
+	deadline := flag.Duration("deadline", 0, "Overall time limit for the run (0 = no limit)")
+	outputDir := flag.String("output-dir", "", "Write all artifacts to a timestamped directory under this path (overrides -output-txt/-output-json)")
+	maxFallback := flag.Int("max-fallback", -1, "Fail if more than this many sources used fallback data (-1 = no limit)")
+	minLive := flag.Int("min-live-sources", 0, "Fail if fewer than this many sources were fetched live")
+	withholdOutput := flag.Bool("withhold-output", false, "Don't write output when -max-fallback or -min-live-sources fails")
+	ooniLookback := flag.Duration("ooni-lookback", 0, "Only count OONI measurements from this recent window, e.g. 8760h (0 = since 2023-01-01)")
+	validateOutput := flag.Bool("validate-output", false, "Check the result against the output schema before writing; fail on violations")
+	listSources := flag.Bool("list-sources", false, "Print the available sources and exit")
+	printSchema := flag.Bool("print-schema", false, "Print the JSON schema for the output and exit")
+	preferSource := flag.String("prefer-source", "", "Comma-separated sources to list first in provenance, most authoritative first")
+	addContinent := flag.String("add-continent", "", "Comma-separated continents whose countries are always included, e.g. Asia")
+	nameOverrides := flag.String("name-overrides", "", "File of CODE,ACTION,ALIAS lines that add, remove, or remap country name aliases")
+	includeTerritories := flag.Bool("include-territories", false, "Also resolve territories and dependencies with their own codes, e.g. Western Sahara (EH), Kosovo (XK)")
+	fuzzyThreshold := flag.Float64("fuzzy-threshold", countries.DefaultFuzzyThreshold, "Lowest similarity (0-1] at which a token that matches no name is accepted as a misspelling of the closest one")
+	noFuzzy := flag.Bool("no-fuzzy", false, "Don't accept misspelled names as fuzzy matches")
+	onlyChanged := flag.Bool("only-changed", false, "Exit with status 7 when the countries are the same as in the previous output, so a wrapper can skip publishing")
+	skipUnchangedWrite := flag.Bool("skip-unchanged-write", false, "Don't rewrite the outputs when the countries are the same as in the previous output")
+	reportFile := flag.String("report", "", "Also write a Markdown report of each country and the authorities that list it, for reviewers (- = stdout)")
+	perSourceDir := flag.String("per-source-dir", "", "Also write each source's normalized codes and the raw tokens behind them to a file in this directory")
+	strict := flag.Bool("strict", false, "Fail without writing output if any token from a successfully parsed source doesn't normalize")
+	strictFallback := flag.Bool("strict-fallback", false, "With -strict, also check sources that used fallback data")
+	var headerLines stringList
+	listName := flag.String("name", aggregate.DefaultName, "Name recorded in the JSON output and the text output's header")
+	listVersion := flag.String("version", aggregate.DefaultVersion, "Semantic version recorded in the outputs, or \"auto\" to bump the previous output's patch version when the countries change")
+	listDescription := flag.String("description", aggregate.DefaultDescription, "Description recorded in the JSON output and the text output's header")
+
+	var sourceTimeouts stringList
+	flag.Var(&sourceTimeouts, "source-timeout", "Per-source HTTP timeout as \"Name=duration\", e.g. \"UK Sanctions List=90s\" (repeatable)")
+	flag.Var(&headerLines, "header-line", "Extra comment line for the text output's header, e.g. \"Contact: noc@example.com\" (repeatable)")
+	maxAge := flag.Duration("max-age", 0, "Reuse a source's cached result if it was fetched less than this long ago, e.g. 6h (0 = always fetch)")
+	resultCacheFile := flag.String("result-cache", ".aggregate-cache.json", "File holding each source's last live result, for -max-age")
+	baseline := flag.String("baseline", "", "Previously published blocked_countries.txt or .json (path or URL) whose countries stay listed until removed from it")
+	contentStateFile := flag.String("content-state", ".aggregate-state.json", "File recording each source's last content hash, to report upstream changes (empty = off)")
+	recordFile := flag.String("record", "", "Record every source request and response to this cassette file")
+	replayFile := flag.String("replay", "", "Serve source requests from this cassette file instead of the network")
 
 	flag.Parse()
+	console.SetQuiet(*quiet)
+
+	if *printSchema {
+		fmt.Print(aggregate.Schema)
+		return
+	}
 
-	fmt.Println("Country Blocklist Aggregator")
-	fmt.Println(strings.Repeat("=", 40))
+	if *listSources {
+		printSources()
+		return
+	}
 
-	// Create HTTP client
-	httpClient := &http.Client{
-		Timeout: *timeout,
+	continents := aggregate.ParseSources(*addContinent)
+	for _, name := range continents {
+		if _, _, ok := countries.ContinentCodes(name); !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown continent %q (known: %s)\n", name, strings.Join(countries.Continents(), ", "))
+			os.Exit(exitcode.Usage)
+		}
 	}
 
-	// Create scraper registry
-	registry := scrapers.DefaultRegistry(httpClient)
+	var contentState *aggregate.ContentState
+	if *contentStateFile != "" {
+		var err error
+		contentState, err = aggregate.LoadContentState(*contentStateFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitcode.Usage)
+		}
+	}
 
-	// Determine which sources to use
-	var selectedSources []string
-	if *sources != "" {
-		selectedSources = strings.Split(*sources, ",")
-		for i := range selectedSources {
-			selectedSources[i] = strings.TrimSpace(selectedSources[i])
+	var resultCache *aggregate.ResultCache
+	if *maxAge > 0 && *resultCacheFile != "" {
+		var err error
+		resultCache, err = aggregate.LoadResultCache(*resultCacheFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitcode.Usage)
 		}
+	}
+
+	timeouts, err := parseSourceTimeouts(sourceTimeouts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitcode.Usage)
+	}
+
+	var cassette *scrapers.Cassette
+	switch {
+	case *recordFile != "" && *replayFile != "":
+		fmt.Fprintln(os.Stderr, "Error: -record and -replay can't be combined")
+		os.Exit(exitcode.Usage)
+	case *recordFile != "":
+		clientTimeout := *timeout
+		for _, d := range timeouts {
+			if d > clientTimeout {
+				clientTimeout = d
+			}
+		}
+		cassette = scrapers.NewRecordingCassette(*recordFile, &http.Client{Timeout: clientTimeout})
+	case *replayFile != "":
+		cassette, err = scrapers.LoadCassette(*replayFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitcode.Usage)
+		}
+	}
+
+	if *listVersion != aggregate.VersionAuto {
+		if err := aggregate.ValidateVersion(*listVersion); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -version: %v\n", err)
+			os.Exit(exitcode.Usage)
+		}
+	}
+
+	if *fuzzyThreshold <= 0 || *fuzzyThreshold > 1 {
+		fmt.Fprintf(os.Stderr, "Error: -fuzzy-threshold must be greater than 0 and at most 1, got %v\n", *fuzzyThreshold)
+		os.Exit(exitcode.Usage)
+	}
+	if *noFuzzy {
+		*fuzzyThreshold = 0
+	}
+
+	normalizer, err := loadNormalizer(*nameOverrides, *includeTerritories, *fuzzyThreshold)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitcode.Usage)
+	}
+
+	console.Println("Country Blocklist Aggregator")
+	console.Println(strings.Repeat("=", 40))
+
+	opts := aggregate.Options{
+		Sources:       aggregate.ParseSources(*sources),
+		Workers:       *workers,
+		Timeout:       *timeout,
+		Verbose:       *verbose,
+		PreferSources: aggregate.ParseSources(*preferSource),
+		OONILookback:  *ooniLookback,
+		AddContinents: continents,
+		ContentState:  contentState,
+		HeaderLines:   headerLines,
+		Normalizer:    normalizer,
+		Name:          *listName,
+		Description:   *listDescription,
+
+		SourceTimeouts: timeouts,
+		ResultCache:    resultCache,
+		MaxAge:         *maxAge,
+		Baseline:       *baseline,
+	}
+	if *listVersion != aggregate.VersionAuto {
+		opts.Version = *listVersion
+	}
+	if cassette != nil {
+		opts.HTTPClient = cassette
+	}
+
+	if len(opts.Sources) == 0 {
+		opts.Sources = aggregate.AllSources()
+	}
+
+	if *baseline != "" {
+		console.Printf("Using %d sources plus baseline %s\n\n", len(opts.Sources), *baseline)
 	} else {
-		selectedSources = registry.Names()
+		console.Printf("Using %d sources\n\n", len(opts.Sources))
 	}
 
-	fmt.Printf("Using %d sources\n\n", len(selectedSources))
+	// Cancel in-flight scrapes on Ctrl-C or when the deadline passes. Once
+	// cancelled, the signals are released so a second Ctrl-C exits at once
+	// instead of waiting for the partial output
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func(ctx context.Context) {
+		<-ctx.Done()
+		stop()
+	}(ctx)
+	if *deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *deadline)
+		defer cancel()
+	}
 
-	// Run scrapers concurrently
-	ctx := context.Background()
-	results := runScrapers(ctx, registry, selectedSources, *workers, *verbose)
+	// Run scrapers and aggregate results
+	started := time.Now()
+	aggregated := aggregate.Run(ctx, opts)
+	previous := previousResult(*outputJSON, *outputDir)
+	changed := previous == nil || !aggregate.SameCodes(previous, aggregated)
+	if *listVersion == aggregate.VersionAuto {
+		aggregated.Version = aggregate.NextVersion(previous, aggregated)
+	}
 
-	// Create normalizer
-	normalizer := countries.NewNormalizer()
+	if cassette != nil && cassette.Recording() {
+		if err := cassette.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save cassette: %v\n", err)
+		} else {
+			console.Printf("Recorded source responses to %s\n", *recordFile)
+		}
+	}
+
+	// Saved right away so an interrupted or rejected run still spares the
+	// next one the sources it did fetch
+	if resultCache != nil {
+		if err := resultCache.Save(*resultCacheFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save result cache: %v\n", err)
+		}
+	}
 
-	// Aggregate results
-	aggregated := aggregate(results, normalizer, *verbose)
-	
-	// Set metadata
-	aggregated.Name = "UniFi Region Blocking Country List"
-	aggregated.Version = "1.0.0"
-	aggregated.Description = "Aggregated list of countries subject to sanctions, export controls, or other restrictions from multiple authoritative sources. This list is intended for use with UniFi Network's Region Blocking (GeoIP Filtering) feature to block traffic from these countries."
-	aggregated.LastModified = time.Now()
+	// Print summary. A scheduled run where nothing changed upstream or in
+	// the output only says so
+	if *onlyChanged && !changed && contentState != nil && contentState.Unchanged(aggregated) {
+		console.Println("No upstream or output changes since the last run")
+	} else {
+		printSummary(aggregated)
+		if contentState != nil {
+			printContentChanges(aggregated, contentState)
+		}
+		console.Printf("\nOutput changed: %t\n", changed)
+	}
 
-	// Print summary
-	printSummary(aggregated)
+	if *validateOutput {
+		if problems := aggregate.Validate(aggregated); len(problems) > 0 {
+			fmt.Fprintln(os.Stderr, "\nError: output failed schema validation; not written:")
+			for _, p := range problems {
+				fmt.Fprintf(os.Stderr, "  - %s\n", p)
+			}
+			os.Exit(exitcode.Verification)
+		}
+	}
+
+	if *strict {
+		if problems := strictProblems(aggregated, *strictFallback); len(problems) > 0 {
+			fmt.Fprintf(os.Stderr, "\nError: -strict: %d tokens failed to normalize; output not written:\n", len(problems))
+			for _, p := range problems {
+				fmt.Fprintf(os.Stderr, "  - %s\n", p)
+			}
+			os.Exit(exitcode.Verification)
+		}
+	}
+
+	// Refuse to trust a list built mostly from fallback data
+	live, fallback := aggregate.LiveCounts(aggregated)
+	var guardErr string
+	if *maxFallback >= 0 && fallback > *maxFallback {
+		guardErr = fmt.Sprintf("%d sources used fallback data (max %d)", fallback, *maxFallback)
+	} else if live < *minLive {
+		guardErr = fmt.Sprintf("only %d sources were fetched live (min %d)", live, *minLive)
+	}
+	if guardErr != "" && *withholdOutput {
+		fmt.Fprintf(os.Stderr, "\nError: %s; output not written\n", guardErr)
+		os.Exit(exitcode.Controller)
+	}
 
 	// Write output files
-	if err := writeOutputs(aggregated, *outputTxt, *outputJSON); err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing outputs: %v\n", err)
-		os.Exit(1)
+	if !changed && *skipUnchangedWrite {
+		console.Println("\nCountries unchanged; outputs not rewritten")
+	} else if *outputDir != "" {
+		dir, err := writeRunDir(*outputDir, aggregated, started, changed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing outputs: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+		console.Printf("\nOutput written to %s\n", dir)
+	} else {
+		if err := aggregate.WriteOutputs(aggregated, *outputTxt, *outputJSON); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing outputs: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+
+		console.Printf("\nOutput written to:\n")
+		console.Printf("  - %s\n", *outputTxt)
+		console.Printf("  - %s\n", *outputJSON)
 	}
 
-	fmt.Printf("\nOutput written to:\n")
-	fmt.Printf("  - %s\n", *outputTxt)
-	fmt.Printf("  - %s\n", *outputJSON)
-}
+	if *reportFile != "" && (changed || !*skipUnchangedWrite) {
+		if err := aggregate.WriteReport(aggregated, *reportFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+		if !console.IsStdout(*reportFile) {
+			console.Printf("Report written to %s\n", *reportFile)
+		}
+	}
 
-func runScrapers(ctx context.Context, registry *scrapers.Registry, sources []string, workers int, verbose bool) []*scrapers.ScrapeResult {
-	var (
-		wg      sync.WaitGroup
-		mu      sync.Mutex
-		results []*scrapers.ScrapeResult
-	)
-
-	work := make(chan scrapers.Scraper, len(sources))
-
-	// Queue work
-	for _, name := range sources {
-		if s, ok := registry.Get(name); ok {
-			work <- s
-		} else if verbose {
-			fmt.Printf("  [WARN] Unknown source: %s\n", name)
-		}
-	}
-	close(work)
-
-	// Start workers
-	for i := 0; i < workers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for s := range work {
-				fmt.Printf("  Fetching: %s...\n", s.Name())
-
-				result, err := s.Scrape(ctx)
-				if err != nil {
-					fmt.Printf("    [ERROR] %s: %v\n", s.Name(), err)
-					continue
-				}
-
-				if verbose {
-					fmt.Printf("    Status: %s, Raw countries: %d\n", result.ParseStatus, len(result.RawCountries))
-				}
-
-				mu.Lock()
-				results = append(results, result)
-				mu.Unlock()
-			}
-		}()
+	if *perSourceDir != "" && (changed || !*skipUnchangedWrite) {
+		if err := aggregate.WritePerSource(aggregated, *perSourceDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing per-source lists: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+		console.Printf("Per-source lists written to %s\n", *perSourceDir)
 	}
 
-	wg.Wait()
-	return results
+	// Only remember hashes once output reflecting them has been written
+	if contentState != nil {
+		contentState.Record(aggregated)
+		if err := contentState.Save(*contentStateFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save content state: %v\n", err)
+		}
+	}
+
+	if guardErr != "" {
+		fmt.Fprintf(os.Stderr, "\nError: %s\n", guardErr)
+		os.Exit(exitcode.Controller)
+	}
+
+	if aggregated.Partial {
+		fmt.Fprintf(os.Stderr, "\nRun cancelled (%v): output contains partial results\n", ctx.Err())
+		os.Exit(exitcode.Failure)
+	}
+
+	if *onlyChanged && !changed {
+		os.Exit(exitcode.Unchanged)
+	}
 }
 
-func aggregate(results []*scrapers.ScrapeResult, normalizer *countries.Normalizer, verbose bool) *AggregationResult {
-	agg := &AggregationResult{
-		Timestamp:   time.Now(),
-		SourceStats: make(map[string]SourceStats),
+// parseSourceTimeouts parses -source-timeout values, rejecting unknown
+// source names and non-positive durations.
+func parseSourceTimeouts(values []string) (map[string]time.Duration, error) {
+	if len(values) == 0 {
+		return nil, nil
 	}
 
-	// Map from country code to provenance
-	countryMap := make(map[string]*CountryWithProvenance)
+	known := aggregate.AllSources()
+	timeouts := make(map[string]time.Duration, len(values))
+	for _, v := range values {
+		i := strings.LastIndex(v, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("-source-timeout %q: want Name=duration", v)
+		}
+		name := strings.TrimSpace(v[:i])
+		d, err := time.ParseDuration(strings.TrimSpace(v[i+1:]))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("-source-timeout %q: invalid duration", v)
+		}
+		if !containsName(known, name) {
+			return nil, fmt.Errorf("-source-timeout: unknown source %q (see -list-sources)", name)
+		}
+		timeouts[name] = d
+	}
+	return timeouts, nil
+}
 
-	for _, result := range results {
-		stats := SourceStats{
-			URL:         result.URL,
-			FetchedAt:   result.FetchedAt,
-			ParseStatus: result.ParseStatus,
-			RawCount:    len(result.RawCountries),
-			Error:       result.Error,
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
 		}
+	}
+	return false
+}
 
-		matched := 0
-		for _, raw := range result.RawCountries {
-			code, ok := normalizer.Normalize(raw)
-			if !ok {
-				if verbose {
-					fmt.Printf("    [SKIP] Could not normalize: %q\n", raw)
-				}
-				continue
-			}
+// loadNormalizer builds a normalizer, extended with the territory table if
+// territories is set, with the overrides in path (if any) applied on top.
+// fuzzyThreshold is passed to SetFuzzyThreshold; 0 turns fuzzy matching off.
+func loadNormalizer(path string, territories bool, fuzzyThreshold float64) (*countries.Normalizer, error) {
+	normalizer := countries.NewNormalizer()
+	normalizer.SetFuzzyThreshold(fuzzyThreshold)
+	if territories {
+		normalizer.IncludeTerritories()
+	}
+	if path == "" {
+		return normalizer, nil
+	}
 
-			matched++
-
-			if existing, ok := countryMap[code]; ok {
-				// Add source if not already present
-				hasSource := false
-				for _, s := range existing.Sources {
-					if s == result.Source {
-						hasSource = true
-						break
-					}
-				}
-				if !hasSource {
-					existing.Sources = append(existing.Sources, result.Source)
-				}
-				existing.RawTokens = append(existing.RawTokens, raw)
-			} else {
-				countryMap[code] = &CountryWithProvenance{
-					Alpha2:    code,
-					Name:      normalizer.GetName(code),
-					Sources:   []string{result.Source},
-					RawTokens: []string{raw},
-				}
-			}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open name overrides: %w", err)
+	}
+	defer f.Close()
+
+	if err := normalizer.ApplyOverrides(f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return normalizer, nil
+}
+
+// strictProblems lists every token that failed to normalize in a source
+// that parsed successfully (or fell back, with includeFallback), as
+// "source: token".
+func strictProblems(agg *aggregate.AggregationResult, includeFallback bool) []string {
+	var problems []string
+	for _, name := range aggregate.SourceNames(agg) {
+		stats := agg.SourceStats[name]
+		fellBack := scrapers.UsedFallback(stats.ParseStatus, stats.RawCount)
+		if stats.ParseStatus != "success" && !(includeFallback && fellBack) {
+			continue
+		}
+		for _, token := range stats.UnmatchedTokens {
+			problems = append(problems, fmt.Sprintf("%s: %q", name, token))
 		}
+	}
+	return problems
+}
 
-		stats.MatchedCount = matched
-		agg.SourceStats[result.Source] = stats
+// stringList is a flag that collects every value when repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// previousResult reads the result the last run wrote, to compare against and
+// for -version auto: the latest run directory's JSON with -output-dir,
+// otherwise -output-json. It returns nil if there is none.
+func previousResult(outputJSON, outputDir string) *aggregate.AggregationResult {
+	path := outputJSON
+	if outputDir != "" {
+		path = filepath.Join(outputDir, rundir.LatestLink, aggregate.JSONFile)
+	}
+	if console.IsStdout(path) {
+		return nil
+	}
 
-		if result.Error != "" {
-			agg.Errors = append(agg.Errors, fmt.Sprintf("%s: %s", result.Source, result.Error))
+	previous, err := aggregate.ReadJSON(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Warning: can't compare with the previous output: %v\n", err)
 		}
+		return nil
 	}
+	return previous
+}
 
-	// Convert map to sorted slice
-	for _, c := range countryMap {
-		agg.Countries = append(agg.Countries, *c)
+// writeRunDir writes this run's artifacts into a new timestamped directory
+// under base and points the latest link at it.
+func writeRunDir(base string, agg *aggregate.AggregationResult, started time.Time, changed bool) (string, error) {
+	dir, err := rundir.Create(base, started)
+	if err != nil {
+		return "", err
 	}
 
-	sort.Slice(agg.Countries, func(i, j int) bool {
-		return agg.Countries[i].Alpha2 < agg.Countries[j].Alpha2
-	})
+	info := aggregate.NewRunInfo(agg, started, agg.Partial)
+	info.Changed = changed
+	if err := aggregate.WriteRunDir(agg, info, dir); err != nil {
+		return "", err
+	}
 
-	agg.TotalCodes = len(agg.Countries)
+	if err := rundir.UpdateLatest(base, dir); err != nil {
+		return "", err
+	}
 
-	return agg
+	return dir, nil
 }
 
-func printSummary(agg *AggregationResult) {
-	fmt.Println("\n" + strings.Repeat("=", 40))
-	fmt.Println("AGGREGATION SUMMARY")
-	fmt.Println(strings.Repeat("=", 40))
+// printSources lists every registered scraper without running any of them.
+func printSources() {
+	names := aggregate.AllSources()
+	sort.Strings(names)
 
-	fmt.Printf("Total unique country codes: %d\n\n", agg.TotalCodes)
+	registry := scrapers.DefaultRegistry(nil)
+	for _, name := range names {
+		s, _ := registry.Get(name)
+		info := scrapers.Describe(s)
 
-	fmt.Println("Source statistics:")
-	for name, stats := range agg.SourceStats {
+		fallback := "no"
+		if info.HasFallback {
+			fallback = "yes"
+		}
+
+		fmt.Printf("%s\n", info.Name)
+		fmt.Printf("  URL:       %s\n", info.URL)
+		fmt.Printf("  Category:  %s\n", info.Category)
+		fmt.Printf("  Fallback:  %s\n", fallback)
+		if info.Threshold != "" {
+			fmt.Printf("  Threshold: %s\n", info.Threshold)
+		}
+	}
+}
+
+func printSummary(agg *aggregate.AggregationResult) {
+	console.Println("\n" + strings.Repeat("=", 40))
+	console.Println("AGGREGATION SUMMARY")
+	console.Println(strings.Repeat("=", 40))
+
+	if agg.Partial {
+		console.Printf("PARTIAL RESULT: the run was interrupted and %d of %d sources didn't finish\n", aggregate.CancelledCount(agg), len(agg.SourceStats))
+	}
+	console.Printf("Total unique country codes: %d\n\n", agg.TotalCodes)
+
+	console.Println("Source statistics:")
+	for _, name := range aggregate.SourceNames(agg) {
+		stats := agg.SourceStats[name]
 		status := stats.ParseStatus
-		if stats.Error != "" {
+		if stats.Error != "" && status != scrapers.StatusCancelled && status != scrapers.StatusCircuitOpen && status != scrapers.StatusErrorPage {
 			status = "error"
 		}
-		fmt.Printf("  - %s: %d raw -> %d matched (%s)\n", name, stats.RawCount, stats.MatchedCount, status)
+		if stats.FallbackReason != "" {
+			if status != scrapers.StatusCircuitOpen {
+				status = "fallback"
+			}
+			status += fmt.Sprintf(", %s: %s", stats.FallbackReason, stats.FallbackDetail)
+		}
+		if stats.Cached {
+			status += ", cached"
+		}
+		console.Printf("  - %s: %d raw -> %d matched (%s)\n", name, stats.RawCount, stats.MatchedCount, status)
 	}
 
-	fmt.Println("\nCountries by source count:")
+	console.Println("\nCountries by source count:")
 	sourceCounts := make(map[int][]string)
 	for _, c := range agg.Countries {
 		n := len(c.Sources)
@@ -272,55 +549,52 @@ func printSummary(agg *AggregationResult) {
 	for _, n := range counts {
 		codes := sourceCounts[n]
 		sort.Strings(codes)
-		fmt.Printf("  %d sources: %s\n", n, strings.Join(codes, ", "))
+		console.Printf("  %d sources: %s\n", n, strings.Join(codes, ", "))
 	}
 
-	if len(agg.Errors) > 0 {
-		fmt.Println("\nWarnings/Errors:")
-		for _, e := range agg.Errors {
-			fmt.Printf("  - %s\n", e)
+	var baselineOnly []string
+	for _, c := range agg.Countries {
+		if c.BaselineOnly {
+			baselineOnly = append(baselineOnly, c.Alpha2)
 		}
 	}
-}
+	if len(baselineOnly) > 0 {
+		console.Printf("\nKept only by baseline: %s\n", strings.Join(baselineOnly, ", "))
+	}
 
-func writeOutputs(agg *AggregationResult, txtPath, jsonPath string) error {
-	// Ensure data directory exists
-	if err := os.MkdirAll("data", 0755); err != nil {
-		return fmt.Errorf("failed to create data directory: %w", err)
-	}
-
-	// Write text file with header
-	var txtBuilder strings.Builder
-	txtBuilder.WriteString("# " + agg.Name + "\n")
-	txtBuilder.WriteString("# Version: " + agg.Version + "\n")
-	txtBuilder.WriteString("# Last Modified: " + agg.LastModified.Format("2006-01-02 15:04:05 MST") + "\n")
-	txtBuilder.WriteString("#\n")
-	txtBuilder.WriteString("# " + strings.ReplaceAll(agg.Description, "\n", "\n# ") + "\n")
-	txtBuilder.WriteString("#\n")
-	txtBuilder.WriteString("# Country codes (ISO 3166-1 alpha-2)\n")
-	txtBuilder.WriteString("#\n")
-	
-	var codes []string
+	var fuzzy []string
 	for _, c := range agg.Countries {
-		codes = append(codes, c.Alpha2)
+		for _, raw := range c.RawTokens {
+			if c.MatchTypes[raw] == countries.MatchFuzzy {
+				fuzzy = append(fuzzy, fmt.Sprintf("%q -> %s (%.2f)", raw, c.Alpha2, c.MatchScores[raw]))
+			}
+		}
 	}
-	txtBuilder.WriteString(strings.Join(codes, "\n"))
-	txtBuilder.WriteString("\n")
-
-	if err := os.WriteFile(txtPath, []byte(txtBuilder.String()), 0644); err != nil {
-		return fmt.Errorf("failed to write txt file: %w", err)
+	if len(fuzzy) > 0 {
+		console.Printf("\nFuzzy matches (check these): %s\n", strings.Join(fuzzy, ", "))
 	}
 
-	// Write JSON file
-	jsonContent, err := json.MarshalIndent(agg, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %w", err)
+	if len(agg.Errors) > 0 {
+		console.Println("\nWarnings/Errors:")
+		for _, e := range agg.Errors {
+			console.Printf("  - %s\n", e)
+		}
 	}
+}
 
-	if err := os.WriteFile(jsonPath, jsonContent, 0644); err != nil {
-		return fmt.Errorf("failed to write JSON file: %w", err)
+// printContentChanges lists which sources' upstream content changed since the
+// run recorded in state.
+func printContentChanges(agg *aggregate.AggregationResult, state *aggregate.ContentState) {
+	byStatus := make(map[string][]string)
+	for _, name := range aggregate.SourceNames(agg) {
+		status := state.ContentStatus(name, agg.SourceStats[name])
+		byStatus[status] = append(byStatus[status], name)
 	}
 
-	return nil
+	console.Println("\nSource content since last run:")
+	for _, status := range []string{aggregate.ContentChanged, aggregate.ContentUnchanged, aggregate.ContentNew, aggregate.ContentNotFetched} {
+		if names := byStatus[status]; len(names) > 0 {
+			console.Printf("  %s: %s\n", status, strings.Join(names, ", "))
+		}
+	}
 }
-
@@ -0,0 +1,245 @@
+// Command audit compares the countries blocked on a UniFi controller with the
+// aggregated source list and reports, for each country, whether it is blocked,
+// whether it is sourced, and why the sources listed it.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/aggregate"
+	"github.com/mattsblocklist/tae/internal/config"
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/countries"
+	"github.com/mattsblocklist/tae/internal/exitcode"
+	"github.com/mattsblocklist/tae/internal/unifi"
+)
+
+// Audit statuses for a single country.
+const (
+	StatusOK                = "ok"
+	StatusBlockedNotSourced = "blocked_not_sourced"
+	StatusSourcedNotBlocked = "sourced_not_blocked"
+)
+
+// AuditEntry is the joined view of one country.
+type AuditEntry struct {
+	Alpha2  string   `json:"alpha2"`
+	Name    string   `json:"name"`
+	Blocked bool     `json:"blocked"`
+	Sourced bool     `json:"sourced"`
+	Status  string   `json:"status"`
+	Sources []string `json:"sources,omitempty"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// AuditReport is the full comparison between the controller and the source list.
+type AuditReport struct {
+	Timestamp         time.Time    `json:"timestamp"`
+	Site              string       `json:"site"`
+	Input             string       `json:"input"`
+	BlockedCount      int          `json:"blocked_count"`
+	SourcedCount      int          `json:"sourced_count"`
+	Countries         []AuditEntry `json:"countries"`
+	BlockedNotSourced []string     `json:"blocked_not_sourced"`
+	SourcedNotBlocked []string     `json:"sourced_not_blocked"`
+}
+
+func main() {
+	host := flag.String("host", "", "UniFi controller URL")
+	username := flag.String("username", "", "UniFi username")
+	password := flag.String("password", "", "UniFi password")
+	site := flag.String("site", "default", "UniFi site name")
+	insecure := flag.Bool("insecure", false, "Skip TLS certificate verification")
+	caCert := flag.String("ca-cert", "", "PEM file of CA certificates to trust for the controller (or UNIFI_CA_CERT_FILE env)")
+	endpoint := flag.String("endpoint", "", "Override the region blocking settings path, e.g. set/setting/usg")
+	inputJSON := flag.String("input", "data/blocked_countries.json", "Aggregated JSON file with provenance")
+	outputJSON := flag.String("output", "", "Write the report to this JSON file (- = stdout)")
+	verbose := flag.Bool("verbose", false, "Enable verbose output")
+	quiet := flag.Bool("quiet", false, "Only print errors and requested output; progress and summaries are suppressed")
+	envFile := flag.String("env-file", config.DefaultEnvFile, "Read KEY=VALUE settings from this file (ignored if missing; real env vars take precedence)")
+
+	flag.Parse()
+	console.SetQuiet(*quiet)
+
+	if err := config.LoadDotEnv(*envFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading env file: %v\n", err)
+		os.Exit(exitcode.Usage)
+	}
+
+	// Load from environment if not provided
+	if *host == "" {
+		*host = os.Getenv("UNIFI_HOST")
+	}
+	if *username == "" {
+		*username = os.Getenv("UNIFI_USERNAME")
+	}
+	if *password == "" {
+		*password = os.Getenv("UNIFI_PASSWORD")
+	}
+	if *caCert == "" {
+		*caCert = os.Getenv("UNIFI_CA_CERT_FILE")
+	}
+
+	if *host == "" || *username == "" || *password == "" {
+		fmt.Fprintln(os.Stderr, "Error: host, username, and password are required")
+		fmt.Fprintln(os.Stderr, "Use flags or environment variables: UNIFI_HOST, UNIFI_USERNAME, UNIFI_PASSWORD")
+		flag.Usage()
+		os.Exit(exitcode.Usage)
+	}
+
+	// Read the source list first so a bad path fails before logging in
+	agg, err := aggregate.ReadJSON(*inputJSON)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading source list: %v\n", err)
+		os.Exit(exitcode.Usage)
+	}
+
+	console.Printf("Connecting to %s...\n", *host)
+	client, err := unifi.NewClient(unifi.ClientConfig{
+		Host:               *host,
+		Username:           *username,
+		Password:           *password,
+		Site:               *site,
+		SkipTLSVerify:      *insecure,
+		CACertFile:         *caCert,
+		Verbose:            *verbose,
+		RegionBlockingPath: *endpoint,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect: %v\n", err)
+		os.Exit(exitcode.FromError(err))
+	}
+	defer client.Logout()
+
+	blocked, err := client.GetBlockedCountries()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get blocked countries: %v\n", err)
+		os.Exit(exitcode.FromError(err))
+	}
+
+	report := buildReport(blocked, agg, countries.NewNormalizer())
+	report.Site = client.Site()
+	report.Input = *inputJSON
+
+	printReport(report)
+
+	if *outputJSON != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err == nil {
+			err = console.WriteFile(*outputJSON, data, 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+		if !console.IsStdout(*outputJSON) {
+			console.Printf("\nReport written to %s\n", *outputJSON)
+		}
+	}
+}
+
+// buildReport joins the controller's blocked codes with the aggregated list.
+// Countries appear once, sorted by code.
+func buildReport(blocked []string, agg *aggregate.AggregationResult, normalizer *countries.Normalizer) *AuditReport {
+	entries := make(map[string]*AuditEntry)
+	entry := func(code string) *AuditEntry {
+		code = strings.ToUpper(code)
+		e, ok := entries[code]
+		if !ok {
+			e = &AuditEntry{Alpha2: code, Name: normalizer.GetName(code)}
+			entries[code] = e
+		}
+		return e
+	}
+
+	for _, code := range blocked {
+		entry(code).Blocked = true
+	}
+
+	for _, c := range agg.Countries {
+		e := entry(c.Alpha2)
+		e.Sourced = true
+		e.Sources = c.Sources
+		for _, r := range c.Rationale {
+			reason := r.Reason
+			if reason == "" {
+				reason = fmt.Sprintf("listed as %q", r.Token)
+			}
+			e.Reasons = append(e.Reasons, fmt.Sprintf("%s: %s", r.Source, reason))
+		}
+	}
+
+	report := &AuditReport{
+		Timestamp:         time.Now(),
+		BlockedNotSourced: []string{},
+		SourcedNotBlocked: []string{},
+	}
+
+	codes := make([]string, 0, len(entries))
+	for code := range entries {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		e := entries[code]
+		switch {
+		case e.Blocked && !e.Sourced:
+			e.Status = StatusBlockedNotSourced
+			report.BlockedNotSourced = append(report.BlockedNotSourced, code)
+		case e.Sourced && !e.Blocked:
+			e.Status = StatusSourcedNotBlocked
+			report.SourcedNotBlocked = append(report.SourcedNotBlocked, code)
+		default:
+			e.Status = StatusOK
+		}
+		if e.Blocked {
+			report.BlockedCount++
+		}
+		if e.Sourced {
+			report.SourcedCount++
+		}
+		report.Countries = append(report.Countries, *e)
+	}
+
+	return report
+}
+
+func printReport(report *AuditReport) {
+	console.Println("\n" + strings.Repeat("=", 40))
+	console.Println("AUDIT REPORT")
+	console.Println(strings.Repeat("=", 40))
+
+	console.Printf("%-4s  %-30s  %-7s  %-7s  %-19s  %s\n", "CODE", "NAME", "BLOCKED", "SOURCED", "STATUS", "REASONS")
+	for _, e := range report.Countries {
+		console.Printf("%-4s  %-30s  %-7s  %-7s  %-19s  %s\n",
+			e.Alpha2, truncate(e.Name, 30), yesNo(e.Blocked), yesNo(e.Sourced), e.Status, strings.Join(e.Reasons, "; "))
+	}
+
+	console.Printf("\nBlocked on controller: %d\n", report.BlockedCount)
+	console.Printf("In source list:        %d\n", report.SourcedCount)
+	console.Printf("Blocked but not sourced (%d): %s\n", len(report.BlockedNotSourced), strings.Join(report.BlockedNotSourced, ", "))
+	console.Printf("Sourced but not blocked (%d): %s\n", len(report.SourcedNotBlocked), strings.Join(report.SourcedNotBlocked, ", "))
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// truncate shortens s to at most n characters so table columns stay aligned.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
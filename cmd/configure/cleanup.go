@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattsblocklist/tae/internal/unifi"
+
+	"github.com/mattsblocklist/tae/internal/console"
+)
+
+// cleanupRegionBlocking undoes what configure set up on a site: region
+// blocking is disabled and, with opts.CleanupClear, the country list is
+// emptied. The block mode and traffic direction are left as they are.
+//
+// Region blocking lives in the site's USG setting, so there are no separate
+// objects to delete; this tool doesn't create firewall groups or rules.
+func cleanupRegionBlocking(client unifi.RegionBlockingClient, opts configureOptions) *ConfigResult {
+	result := &ConfigResult{
+		Timestamp: opts.now(),
+		DryRun:    opts.DryRun,
+		Cleanup:   true,
+	}
+
+	setting, err := client.GetRegionBlockingSettings()
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to get current config: %v", err)
+		return result
+	}
+
+	current := stateFromSetting(setting)
+	result.PreviousCodes = current.Codes
+
+	want := current
+	want.Enabled = false
+	if opts.CleanupClear {
+		want.Codes = nil
+		result.RemovedCodes = current.Codes
+	}
+	result.DesiredCodes = nonNil(want.Codes)
+	result.ResultingCodes = result.DesiredCodes
+	result.Changed = current.Enabled || len(result.RemovedCodes) > 0
+
+	if !result.Changed {
+		console.Println("\nNothing to clean up - region blocking is already disabled")
+		result.Verified = true
+		return result
+	}
+
+	console.Printf("\nCleanup:\n")
+	if current.Enabled {
+		console.Println("  Disable region blocking")
+	}
+	if len(result.RemovedCodes) > 0 {
+		console.Printf("  Clear countries: %s\n", strings.Join(result.RemovedCodes, ", "))
+	} else if len(current.Codes) > 0 {
+		console.Printf("  Keep countries: %s\n", strings.Join(current.Codes, ", "))
+	}
+
+	if opts.DryRun {
+		if opts.Verbose {
+			if err := printPayload(client, want.Enabled, want.Codes, want.Block, want.Direction); err != nil {
+				result.Error = fmt.Sprintf("failed to build payload: %v", err)
+				return result
+			}
+		}
+		console.Println("\n[DRY RUN] Changes not applied")
+		return result
+	}
+
+	if err := client.UpdateRegionBlockingSettings(want.Enabled, want.Codes, want.Block, want.Direction); err != nil {
+		result.Error = fmt.Sprintf("failed to apply cleanup: %v", err)
+		return result
+	}
+	result.Applied = true
+
+	console.Println("Cleanup applied successfully")
+
+	// The client fills in its defaults for an unset mode or direction
+	if want.Block == "" {
+		want.Block = "block"
+	}
+	if want.Direction == "" {
+		want.Direction = "both"
+	}
+	verifyResult(result, client, want, opts)
+
+	return result
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/clock"
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/exitcode"
+	"github.com/mattsblocklist/tae/internal/unifi"
+)
+
+// fakeRegionClient is an in-memory controller. Updates change its state
+// unless the field is listed in ignore, which makes the controller accept an
+// update without persisting that field, as some firmware does.
+type fakeRegionClient struct {
+	enabled   bool
+	codes     []string
+	block     string
+	direction string
+
+	ignore  map[string]bool
+	updates []regionBlockingState
+}
+
+var _ unifi.RegionBlockingClient = (*fakeRegionClient)(nil)
+
+func newFakeRegionClient(enabled bool, codes ...string) *fakeRegionClient {
+	return &fakeRegionClient{
+		enabled:   enabled,
+		codes:     codes,
+		block:     "block",
+		direction: unifi.DirectionBoth,
+	}
+}
+
+func (f *fakeRegionClient) GetRegionBlockingSettings() (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"key":                                "usg",
+		"geo_ip_filtering_enabled":           f.enabled,
+		"geo_ip_filtering_countries":         strings.Join(f.codes, ","),
+		"geo_ip_filtering_block":             f.block,
+		"geo_ip_filtering_traffic_direction": f.direction,
+	}, nil
+}
+
+func (f *fakeRegionClient) GetBlockedCountries() ([]string, error) {
+	return append([]string{}, f.codes...), nil
+}
+
+func (f *fakeRegionClient) UpdateRegionBlockingSettings(enabled bool, codes []string, block, direction string) error {
+	f.updates = append(f.updates, regionBlockingState{Enabled: enabled, Codes: codes, Block: block, Direction: direction})
+	if !f.ignore[fieldEnabled] {
+		f.enabled = enabled
+	}
+	if !f.ignore[fieldCountries] {
+		f.codes = append([]string{}, codes...)
+	}
+	if !f.ignore[fieldBlock] {
+		f.block = block
+	}
+	if !f.ignore[fieldDirection] {
+		f.direction = direction
+	}
+	return nil
+}
+
+func (f *fakeRegionClient) ValidateRegionBlockingSettings(bool, []string, string, string) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeRegionClient) RegionBlockingPayload(enabled bool, codes []string, block, direction string) (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+func (f *fakeRegionClient) EnsureBlockedCountries(add, remove []string) ([]string, error) {
+	f.codes = unifi.ApplyCodeChanges(f.codes, add, remove)
+	return f.codes, nil
+}
+
+func (f *fakeRegionClient) GetSupportedCountries() ([]string, error) {
+	return []string{"CN", "CU", "IR", "KP", "RU", "SY"}, nil
+}
+
+func (f *fakeRegionClient) GetBlockedCountriesByDirection() (unifi.DirectionalCountries, error) {
+	return unifi.DirectionalCountries{Inbound: f.codes, Outbound: f.codes}, nil
+}
+
+func (f *fakeRegionClient) UpdateRegionBlockingDirectional(enabled bool, inbound, outbound []string, block string) error {
+	return f.UpdateRegionBlockingSettings(enabled, unifi.ApplyCodeChanges(inbound, outbound, nil), block, unifi.DirectionBoth)
+}
+
+func TestMain(m *testing.M) {
+	console.SetQuiet(true)
+	os.Exit(m.Run())
+}
+
+// sameCodes compares code lists, treating nil and empty as equal.
+func sameCodes(a, b []string) bool {
+	return len(a) == len(b) && (len(a) == 0 || reflect.DeepEqual(a, b))
+}
+
+func TestConfigureRegionBlocking(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  *fakeRegionClient
+		desired []string
+		enable  bool
+		dryRun  bool
+
+		wantChanged  bool
+		wantUpdates  int
+		wantAdded    []string
+		wantRemoved  []string
+		wantVerified bool
+		wantFailed   []string
+		wantExit     int
+	}{
+		{
+			name:         "no change",
+			client:       newFakeRegionClient(true, "IR", "RU"),
+			desired:      []string{"IR", "RU"},
+			enable:       true,
+			wantVerified: true,
+		},
+		{
+			name:         "add only",
+			client:       newFakeRegionClient(true, "RU"),
+			desired:      []string{"IR", "RU"},
+			enable:       true,
+			wantChanged:  true,
+			wantUpdates:  1,
+			wantAdded:    []string{"IR"},
+			wantVerified: true,
+		},
+		{
+			name:         "remove only",
+			client:       newFakeRegionClient(true, "IR", "KP", "RU"),
+			desired:      []string{"IR", "RU"},
+			enable:       true,
+			wantChanged:  true,
+			wantUpdates:  1,
+			wantRemoved:  []string{"KP"},
+			wantVerified: true,
+		},
+		{
+			name:         "enable toggle",
+			client:       newFakeRegionClient(false, "IR", "RU"),
+			desired:      []string{"IR", "RU"},
+			enable:       true,
+			wantChanged:  true,
+			wantUpdates:  1,
+			wantVerified: true,
+		},
+		{
+			name:    "dry run applies nothing",
+			client:  newFakeRegionClient(true, "RU"),
+			desired: []string{"IR", "RU"},
+			enable:  true,
+			dryRun:  true,
+
+			wantChanged: true,
+			wantAdded:   []string{"IR"},
+		},
+		{
+			name: "verification mismatch",
+			client: func() *fakeRegionClient {
+				f := newFakeRegionClient(false, "RU")
+				f.ignore = map[string]bool{fieldEnabled: true}
+				return f
+			}(),
+			desired:     []string{"IR", "RU"},
+			enable:      true,
+			wantChanged: true,
+			wantUpdates: 1,
+			wantAdded:   []string{"IR"},
+			wantFailed:  []string{fieldEnabled},
+			wantExit:    exitcode.Verification,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := configureOptions{
+				Enable: tt.enable,
+				DryRun: tt.dryRun,
+				Clock:  clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+			}
+			result := configureRegionBlocking(tt.client, tt.desired, opts)
+
+			if result.Error != "" {
+				t.Fatalf("unexpected error: %s", result.Error)
+			}
+			if result.Changed != tt.wantChanged {
+				t.Errorf("Changed = %v, want %v", result.Changed, tt.wantChanged)
+			}
+			if len(tt.client.updates) != tt.wantUpdates {
+				t.Fatalf("updates = %d, want %d", len(tt.client.updates), tt.wantUpdates)
+			}
+			if tt.wantUpdates > 0 {
+				got := tt.client.updates[0]
+				if got.Enabled != tt.enable || !reflect.DeepEqual(got.Codes, tt.desired) {
+					t.Errorf("update = %+v, want enabled %v and codes %v", got, tt.enable, tt.desired)
+				}
+			}
+			if !sameCodes(result.AddedCodes, tt.wantAdded) {
+				t.Errorf("AddedCodes = %v, want %v", result.AddedCodes, tt.wantAdded)
+			}
+			if !sameCodes(result.RemovedCodes, tt.wantRemoved) {
+				t.Errorf("RemovedCodes = %v, want %v", result.RemovedCodes, tt.wantRemoved)
+			}
+			if result.Applied != (tt.wantUpdates > 0) {
+				t.Errorf("Applied = %v, want %v", result.Applied, tt.wantUpdates > 0)
+			}
+			if result.Verified != tt.wantVerified {
+				t.Errorf("Verified = %v, want %v", result.Verified, tt.wantVerified)
+			}
+			if tt.wantFailed != nil {
+				if got := failedFields(result.VerifiedFields); !reflect.DeepEqual(got, tt.wantFailed) {
+					t.Errorf("failed fields = %v, want %v", got, tt.wantFailed)
+				}
+				if result.VerifiedBy != verifiedByReadBack {
+					t.Errorf("VerifiedBy = %q, want %q", result.VerifiedBy, verifiedByReadBack)
+				}
+			}
+			if got := resultExitCode(result); got != tt.wantExit {
+				t.Errorf("exit code = %d, want %d", got, tt.wantExit)
+			}
+		})
+	}
+}
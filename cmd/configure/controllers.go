@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/concurrency"
+	"github.com/mattsblocklist/tae/internal/config"
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/unifi"
+)
+
+// MultiControllerResult combines the per-controller results of a run against
+// the controllers in a config file.
+type MultiControllerResult struct {
+	Timestamp   time.Time       `json:"timestamp"`
+	Status      string          `json:"status"` // success, partial, or failure
+	Controllers []*ConfigResult `json:"controllers"`
+}
+
+// Controller fan-out sizing: the work is network-bound, so allow several
+// per CPU, but keep the number of simultaneous logins modest.
+const (
+	controllerWorkersPerCPU = 4
+	maxControllerWorkers    = 8
+)
+
+// loadControllers reads the controller profiles from a config file, keeping
+// only the named ones if names is non-empty.
+func loadControllers(path string, names []string) ([]config.ControllerProfile, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Controllers) == 0 {
+		return nil, fmt.Errorf("%s has no controllers", path)
+	}
+
+	profiles := cfg.Controllers
+	if len(names) > 0 {
+		byName := make(map[string]config.ControllerProfile, len(profiles))
+		for _, p := range profiles {
+			byName[p.Name] = p
+		}
+		profiles = nil
+		for _, name := range names {
+			p, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("controller %q not found in %s", name, path)
+			}
+			profiles = append(profiles, p)
+		}
+	}
+
+	for _, p := range profiles {
+		if p.Username == "" || p.Password == "" {
+			return nil, fmt.Errorf("controller %q: username and password are required", p.Name)
+		}
+	}
+
+	return profiles, nil
+}
+
+// clientConfigFor builds the client config for one controller profile. base
+// carries the command-line settings that apply to every controller.
+func clientConfigFor(p config.ControllerProfile, base unifi.ClientConfig) unifi.ClientConfig {
+	cfg := base
+	cfg.Host = p.Host
+	cfg.Username = p.Username
+	cfg.Password = p.Password
+	cfg.Site = p.Site
+	cfg.SkipTLSVerify = base.SkipTLSVerify || p.SkipTLSVerify
+	if p.CACertFile != "" {
+		cfg.CACertFile = p.CACertFile
+	}
+	cfg.UserAgent = p.UserAgent
+	cfg.Headers = p.Headers
+	cfg.DisableCSRF = p.DisableCSRF
+	return cfg
+}
+
+// applyControllers configures each controller on a bounded worker pool, each
+// with its own client. A failure on one controller doesn't stop the others.
+// Results are returned in profile order.
+func applyControllers(profiles []config.ControllerProfile, base unifi.ClientConfig, codes []string, opts configureOptions, state *managedState, workers int) []*ConfigResult {
+	if workers <= 0 {
+		workers = concurrency.AutoWorkers(len(profiles), controllerWorkersPerCPU, maxControllerWorkers)
+	}
+	if workers > len(profiles) {
+		workers = len(profiles)
+	}
+
+	results := make([]*ConfigResult, len(profiles))
+	work := make(chan int, len(profiles))
+	for i := range profiles {
+		work <- i
+	}
+	close(work)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				p := profiles[i]
+				console.Printf("\n[%s] Configuring site %s at %s\n", p.Name, p.Site, p.Host)
+
+				ctrlOpts := opts
+				ctrlOpts.State = state
+				ctrlOpts.Controller = p.Name
+				result := applySite(clientConfigFor(p, base), codes, ctrlOpts)
+				result.Controller = p.Name
+				results[i] = result
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func printMultiControllerSummary(multi *MultiControllerResult) {
+	console.Println("\n" + strings.Repeat("=", 72))
+	console.Println("MULTI-CONTROLLER SUMMARY")
+	console.Println(strings.Repeat("=", 72))
+
+	console.Printf("%-20s %-12s %-12s %-8s %-8s %-8s %s\n", "CONTROLLER", "SITE", "STATUS", "CHANGED", "ADDED", "REMOVED", "VERIFIED")
+	for _, r := range multi.Controllers {
+		status := "ok"
+		switch {
+		case r.Error != "":
+			status = "failed"
+		case r.Unsupported:
+			status = "unsupported"
+		}
+		console.Printf("%-20s %-12s %-12s %-8v %-8d %-8d %v\n", r.Controller, r.Site, status, r.Changed, len(r.AddedCodes), len(r.RemovedCodes), r.Verified)
+	}
+
+	for _, r := range multi.Controllers {
+		if r.Error != "" {
+			console.Printf("  - %s: %s\n", r.Controller, r.Error)
+		}
+	}
+
+	console.Printf("\nOverall: %s\n", multi.Status)
+}
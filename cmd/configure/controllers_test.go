@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattsblocklist/tae/internal/unifi"
+)
+
+const controllersTOML = `[[controllers]]
+name = "home"
+host = "https://10.0.0.1"
+username = "admin"
+password = "secret"
+
+[[controllers]]
+name = "branch"
+host = "https://10.0.0.2"
+username = "admin"
+password = "secret"
+site = "branch"
+skip_tls_verify = true
+ca_cert_file = "/etc/branch-ca.pem"
+user_agent = "branch-agent"
+`
+
+func writeControllers(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "controllers.toml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadControllersTOML(t *testing.T) {
+	path := writeControllers(t, controllersTOML)
+
+	profiles, err := loadControllers(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[0].Name != "home" || profiles[1].Name != "branch" {
+		t.Fatalf("profiles = %+v, want home and branch", profiles)
+	}
+
+	profiles, err = loadControllers(path, []string{"branch"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 1 || profiles[0].Site != "branch" {
+		t.Errorf("profiles = %+v, want only branch", profiles)
+	}
+
+	if _, err := loadControllers(path, []string{"office"}); err == nil || !strings.Contains(err.Error(), `"office" not found`) {
+		t.Errorf("err = %v, want the unknown controller named", err)
+	}
+	missing := writeControllers(t, "[[controllers]]\nhost = \"h\"\nusername = \"admin\"\n")
+	if _, err := loadControllers(missing, nil); err == nil || !strings.Contains(err.Error(), "username and password are required") {
+		t.Errorf("err = %v, want missing credentials reported", err)
+	}
+}
+
+// TestClientConfigPrecedence checks how a TOML profile combines with the
+// command-line settings: the profile's connection settings win, -insecure
+// applies to every controller, and -ca-cert fills in where a profile has
+// none.
+func TestClientConfigPrecedence(t *testing.T) {
+	profiles, err := loadControllers(writeControllers(t, controllersTOML), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	home, branch := profiles[0], profiles[1]
+
+	base := unifi.ClientConfig{
+		Host:               "https://flag.example.com",
+		Username:           "flag-user",
+		Password:           "flag-pass",
+		CACertFile:         "/etc/flag-ca.pem",
+		Verbose:            true,
+		RegionBlockingPath: "set/setting/usg",
+	}
+
+	cfg := clientConfigFor(home, base)
+	if cfg.Host != home.Host || cfg.Username != "admin" || cfg.Password != "secret" || cfg.Site != "default" {
+		t.Errorf("home connection = %s %s %s %s, want the profile's", cfg.Host, cfg.Username, cfg.Password, cfg.Site)
+	}
+	if cfg.SkipTLSVerify {
+		t.Error("home skips TLS verification though neither flag nor profile asked")
+	}
+	if cfg.CACertFile != base.CACertFile {
+		t.Errorf("home CA file = %q, want the flag's %q", cfg.CACertFile, base.CACertFile)
+	}
+	if !cfg.Verbose || cfg.RegionBlockingPath != base.RegionBlockingPath {
+		t.Errorf("home lost the flag-only settings: %+v", cfg)
+	}
+
+	cfg = clientConfigFor(branch, base)
+	if !cfg.SkipTLSVerify {
+		t.Error("branch verifies TLS though its profile sets skip_tls_verify")
+	}
+	if cfg.CACertFile != "/etc/branch-ca.pem" || cfg.UserAgent != "branch-agent" {
+		t.Errorf("branch CA file %q and user agent %q, want the profile's", cfg.CACertFile, cfg.UserAgent)
+	}
+
+	base.SkipTLSVerify = true
+	if cfg := clientConfigFor(home, base); !cfg.SkipTLSVerify {
+		t.Error("-insecure didn't apply to a profile without skip_tls_verify")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/fetch"
+)
+
+// maxInputSize caps a fetched input or hash file. Country lists are a few
+// kilobytes, so anything near this is a wrong URL.
+const maxInputSize = 1 << 20
+
+// inputFetcher fetches -input-url and -input-sha256-url content with
+// fetch.Get, retrying with backoff on network errors, 429, and 5xx
+// responses.
+type inputFetcher struct {
+	retries int
+	timeout time.Duration
+}
+
+func newInputFetcher(retries int, timeout time.Duration) *inputFetcher {
+	return &inputFetcher{
+		retries: retries,
+		timeout: timeout,
+	}
+}
+
+// inputFetchError is a failure to fetch an -input-url or -input-sha256-url,
+// which is the remote side's fault rather than the invocation's.
+type inputFetchError struct{ err error }
+
+func (e *inputFetchError) Error() string { return "failed to fetch URL: " + e.err.Error() }
+func (e *inputFetchError) Unwrap() error { return e.err }
+
+// fetch retrieves the body of url, requiring a 2xx response that isn't an
+// HTML page and fits in maxInputSize.
+func (f *inputFetcher) fetch(ctx context.Context, url string) ([]byte, error) {
+	resp, err := fetch.Get(ctx, url, fetch.Options{
+		Timeout: f.timeout,
+		Retries: f.retries,
+		MaxSize: maxInputSize,
+		OnRetry: func(err error, wait time.Duration) {
+			console.Printf("Fetching %s failed (%v), retrying in %s\n", url, err, wait)
+		},
+	})
+	if err != nil {
+		return nil, &inputFetchError{err}
+	}
+
+	// A login or error page served with 200 must not be read as a list
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && mediaType == "text/html" {
+		return nil, &inputFetchError{fmt.Errorf("unexpected content type %s", mediaType)}
+	}
+	return resp.Body, nil
+}
+
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/mattsblocklist/tae/internal/countries"
+	"github.com/mattsblocklist/tae/internal/exitcode"
+	"github.com/mattsblocklist/tae/internal/scrapers"
+	"github.com/mattsblocklist/tae/internal/unifi"
+)
+
+// defaultInput is read when neither -input nor -input-url is given.
+const defaultInput = "data/blocked_countries.txt"
+
+// errInputHash is returned for input content that doesn't match the
+// expected hash.
+var errInputHash = errors.New("input hash mismatch")
+
+// InputContribution records what one -input or -input-url source
+// contributed to the desired codes. Enabled and Mode are set only if the
+// input declared them.
+type InputContribution struct {
+	Input   string   `json:"input"`
+	Codes   []string `json:"codes"`
+	Enabled *bool    `json:"enabled,omitempty"`
+	Mode    string   `json:"mode,omitempty"`
+	// Filtered lists the countries the input listed that fell below the
+	// -min-sources/-category bar and so aren't in Codes.
+	Filtered []string `json:"filtered,omitempty"`
+}
+
+// inputFilter is the provenance bar set by -min-sources and -category. A
+// country in a JSON input is kept if at least MinSources of its sources (at
+// least one, if only Categories is set) are in one of Categories, or in any
+// category if Categories is empty. The zero value keeps every country.
+type inputFilter struct {
+	MinSources int
+	Categories []string
+}
+
+// active reports whether the filter can drop anything.
+func (f inputFilter) active() bool {
+	return f.MinSources > 0 || len(f.Categories) > 0
+}
+
+// keeps reports whether a country with the given sources meets the bar;
+// categories maps each source to its category.
+func (f inputFilter) keeps(sources []string, categories map[string]string) bool {
+	counted := 0
+	for _, source := range sources {
+		if len(f.Categories) == 0 || containsFold(f.Categories, categories[source]) {
+			counted++
+		}
+	}
+	need := f.MinSources
+	if need < 1 {
+		need = 1
+	}
+	return counted >= need
+}
+
+// loadInputs reads every input file and URL, in that order, fetching URLs
+// with fetcher. expectedHash, if set, is checked against the content of the
+// single input, and filter is applied to JSON inputs.
+func loadInputs(ctx context.Context, fetcher *inputFetcher, files, urls []string, expectedHash string, filter inputFilter) ([]*InputContribution, error) {
+	var inputs []*InputContribution
+	load := func(location string, fromURL bool) error {
+		var url, path string
+		if fromURL {
+			url = location
+		} else {
+			path = location
+		}
+		content, err := readInput(ctx, fetcher, path, url, expectedHash)
+		if err != nil {
+			return fmt.Errorf("%s: %w", location, err)
+		}
+		in, err := parseInput(content, isJSONInput(location, content), filter)
+		if err != nil {
+			return fmt.Errorf("%s: %w", location, err)
+		}
+		in.Input = location
+		inputs = append(inputs, in)
+		return nil
+	}
+
+	for _, f := range files {
+		if err := load(f, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, u := range urls {
+		if err := load(u, true); err != nil {
+			return nil, err
+		}
+	}
+	return inputs, nil
+}
+
+// inputExitCode maps a loadInputs error to an exit status. A URL that
+// couldn't be fetched is a remote failure and content that doesn't match the
+// expected hash is Failure; anything else, such as an unreadable or invalid
+// local file, is Usage.
+func inputExitCode(err error) int {
+	var fetchErr *inputFetchError
+	switch {
+	case errors.As(err, &fetchErr):
+		return exitcode.FromError(err)
+	case errors.Is(err, errInputHash):
+		return exitcode.Failure
+	default:
+		return exitcode.Usage
+	}
+}
+
+// readInput returns the content of url, or of filePath if url is empty,
+// after checking it against expectedHash.
+func readInput(ctx context.Context, fetcher *inputFetcher, filePath, url, expectedHash string) ([]byte, error) {
+	var content []byte
+	var err error
+
+	if url != "" {
+		// Fetch from URL
+		content, err = fetcher.fetch(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Read from file
+		content, err = os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	// Verify integrity before trusting any of the content
+	if expectedHash != "" {
+		if actual := scrapers.HashContent(content); actual != expectedHash {
+			return nil, fmt.Errorf("%w: expected %s, got %s", errInputHash, expectedHash, actual)
+		}
+	}
+
+	return content, nil
+}
+
+// isJSONInput reports whether an input is the aggregate's JSON output rather
+// than a text list: its file or URL path ends in .json, or its content
+// starts with "{".
+func isJSONInput(location string, content []byte) bool {
+	name := location
+	if u, err := url.Parse(location); err == nil && u.Scheme != "" {
+		name = u.Path
+	}
+	if strings.EqualFold(path.Ext(name), ".json") {
+		return true
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(content), []byte("{"))
+}
+
+// parseInput reads the codes, and any enabled or mode setting, from either
+// the aggregate's JSON output (countries[].alpha2, with optional top-level
+// "enabled" and "mode") or a text list of one code per line, where header
+// comments such as "# Enabled: false" and "# Mode: inbound" declare them.
+// filter is checked against each JSON country's sources and the categories
+// in its rationale; a text list has no provenance to filter on, so an active
+// filter rejects it.
+func parseInput(content []byte, isJSON bool, filter inputFilter) (*InputContribution, error) {
+	in := &InputContribution{Codes: []string{}}
+
+	trimmed := bytes.TrimSpace(content)
+	if isJSON {
+		var doc struct {
+			Countries []struct {
+				Alpha2    string   `json:"alpha2"`
+				Sources   []string `json:"sources"`
+				Rationale []struct {
+					Source   string `json:"source"`
+					Category string `json:"category"`
+				} `json:"rationale"`
+			} `json:"countries"`
+			Enabled *bool  `json:"enabled"`
+			Mode    string `json:"mode"`
+		}
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON input: %w", err)
+		}
+		hasProvenance := false
+		for _, c := range doc.Countries {
+			if len(c.Alpha2) != 2 {
+				continue
+			}
+			code := strings.ToUpper(c.Alpha2)
+			hasProvenance = hasProvenance || len(c.Sources) > 0
+			if filter.active() {
+				categories := make(map[string]string, len(c.Rationale))
+				for _, r := range c.Rationale {
+					categories[r.Source] = r.Category
+				}
+				if !filter.keeps(c.Sources, categories) {
+					in.Filtered = append(in.Filtered, code)
+					continue
+				}
+			}
+			in.Codes = append(in.Codes, code)
+		}
+		if filter.active() && len(doc.Countries) > 0 && !hasProvenance {
+			return nil, fmt.Errorf("-min-sources and -category need the aggregate's JSON output with provenance, but no country lists sources")
+		}
+		in.Enabled = doc.Enabled
+		in.Mode = doc.Mode
+	} else {
+		if filter.active() {
+			return nil, fmt.Errorf("-min-sources and -category need the aggregate's JSON output with provenance, not a text list")
+		}
+		// One code per line; comments and blank lines are skipped
+		scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "#") {
+				if err := parseDirective(in, strings.TrimSpace(strings.TrimPrefix(line, "#"))); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			// Validate it looks like a country code (2 letters)
+			if len(line) == 2 {
+				in.Codes = append(in.Codes, strings.ToUpper(line))
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if in.Mode != "" {
+		mode, err := parseMode(in.Mode)
+		if err != nil {
+			return nil, err
+		}
+		in.Mode = mode
+	}
+	return in, nil
+}
+
+// parseDirective applies an "Enabled:" or "Mode:" comment to in. Other
+// comments are ignored.
+func parseDirective(in *InputContribution, comment string) error {
+	key, value, ok := strings.Cut(comment, ":")
+	if !ok {
+		return nil
+	}
+	value = strings.TrimSpace(value)
+
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "enabled":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid Enabled value %q", value)
+		}
+		in.Enabled = &enabled
+	case "mode":
+		in.Mode = value
+	}
+	return nil
+}
+
+// parseMode validates a traffic direction given to -mode or by an input.
+func parseMode(s string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(s)); mode {
+	case unifi.DirectionBoth, unifi.DirectionInbound, unifi.DirectionOutbound:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid mode %q (want both, inbound, or outbound)", s)
+	}
+}
+
+// mergeInputs returns the union of the inputs' codes, sorted.
+func mergeInputs(inputs []*InputContribution) []string {
+	sets := make([][]string, len(inputs))
+	for i, in := range inputs {
+		sets[i] = in.Codes
+	}
+	return countries.Union(sets...)
+}
+
+// mergedEnabled returns the enabled flag the inputs declare. ok is false if
+// none declares one; inputs that disagree are an error.
+func mergedEnabled(inputs []*InputContribution) (enabled, ok bool, err error) {
+	var from string
+	for _, in := range inputs {
+		if in.Enabled == nil {
+			continue
+		}
+		if ok && *in.Enabled != enabled {
+			return false, false, fmt.Errorf("inputs disagree on enabled (%s: %v, %s: %v); set -enable explicitly", from, enabled, in.Input, *in.Enabled)
+		}
+		enabled, ok, from = *in.Enabled, true, in.Input
+	}
+	return enabled, ok, nil
+}
+
+// mergedMode returns the traffic direction the inputs declare, or "" if
+// none does; inputs that disagree are an error.
+func mergedMode(inputs []*InputContribution) (string, error) {
+	var mode, from string
+	for _, in := range inputs {
+		if in.Mode == "" {
+			continue
+		}
+		if mode != "" && in.Mode != mode {
+			return "", fmt.Errorf("inputs disagree on mode (%s: %s, %s: %s); set -mode explicitly", from, mode, in.Input, in.Mode)
+		}
+		mode, from = in.Mode, in.Input
+	}
+	return mode, nil
+}
+
+// containsFold reports whether list holds s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// stringList is a flag that collects every value when repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
@@ -3,31 +3,120 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
+	"os/signal"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/mattsblocklist/tae/internal/clock"
+	"github.com/mattsblocklist/tae/internal/config"
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/countries"
+	"github.com/mattsblocklist/tae/internal/exitcode"
 	"github.com/mattsblocklist/tae/internal/unifi"
 )
 
 // ConfigResult contains the result of a configuration operation.
+// VerifiedFields reports, per applied field, whether the controller read it
+// back as sent; Verified is true only when every field did. VerifiedBy says
+// whether that was seen in the set response or had to be read back.
 type ConfigResult struct {
-	Timestamp    time.Time `json:"timestamp"`
-	DryRun       bool      `json:"dry_run"`
-	Changed      bool      `json:"changed"`
-	PreviousCodes []string `json:"previous_codes,omitempty"`
-	DesiredCodes  []string `json:"desired_codes"`
-	AddedCodes    []string `json:"added_codes,omitempty"`
-	RemovedCodes  []string `json:"removed_codes,omitempty"`
-	Verified      bool     `json:"verified"`
-	Error         string   `json:"error,omitempty"`
+	Timestamp       time.Time       `json:"timestamp"`
+	Controller      string          `json:"controller,omitempty"`
+	Host            string          `json:"host,omitempty"`
+	Site            string          `json:"site,omitempty"`
+	DryRun          bool            `json:"dry_run"`
+	Changed         bool            `json:"changed"`
+	Applied         bool            `json:"applied"`
+	PreviousCodes   []string        `json:"previous_codes,omitempty"`
+	DesiredCodes    []string        `json:"desired_codes"`
+	AddedCodes      []string        `json:"added_codes,omitempty"`
+	RemovedCodes    []string        `json:"removed_codes,omitempty"`
+	PreservedCodes  []string        `json:"preserved_codes,omitempty"`
+	ResultingCodes  []string        `json:"resulting_codes,omitempty"`
+	Verified        bool            `json:"verified"`
+	VerifiedFields  map[string]bool `json:"verified_fields,omitempty"`
+	ConvergeSeconds float64         `json:"converge_seconds,omitempty"`
+	VerifiedBy      string          `json:"verified_by,omitempty"`
+	DroppedCodes    []string        `json:"dropped_codes,omitempty"`
+	Cleanup         bool            `json:"cleanup,omitempty"`
+	Unsupported     bool            `json:"unsupported,omitempty"`
+	// Fallback is set on an Unsupported result to the other way the
+	// controller can block countries, fallbackCountryGroup, if it has one.
+	Fallback           string   `json:"fallback,omitempty"`
+	ValidationProblems []string `json:"validation_problems,omitempty"`
+	Error              string   `json:"error,omitempty"`
+
+	// Inputs records what each -input and -input-url contributed to
+	// DesiredCodes.
+	Inputs []*InputContribution `json:"inputs,omitempty"`
+
+	// exitCode classifies Error when it isn't a plain controller failure.
+	exitCode int
+}
+
+// configureOptions controls how desired codes are applied to a site.
+type configureOptions struct {
+	Enable  bool
+	DryRun  bool
+	Verbose bool
+	// Mode is the traffic direction to block; empty means both.
+	Mode string
+	// PreserveUnknown keeps controller codes that this tool didn't set.
+	PreserveUnknown bool
+	// Managed lists the codes this tool applied to the site previously.
+	// With State set, applySite looks them up itself under Controller and
+	// the site the client resolved, the key recordState saves them under.
+	Managed    []string
+	State      *managedState
+	Controller string
+	// Add and Remove switch to ensure mode: only these codes are changed
+	// and everything else on the controller is left alone.
+	Add    []string
+	Remove []string
+	// VerifyTimeout bounds how long to poll for the change to show up
+	// after apply; zero means a single immediate check.
+	VerifyTimeout time.Duration
+	// Webhook, if set, is notified after each apply that changed a site.
+	Webhook *webhook
+	// Cleanup disables region blocking instead of applying codes, and
+	// CleanupClear also empties the country list.
+	Cleanup      bool
+	CleanupClear bool
+	// Strict fails a site whose country table lacks a desired code instead
+	// of dropping the code with a warning.
+	Strict bool
+	// Clock stamps results and paces verification polling. Nil uses the
+	// wall clock.
+	Clock clock.Clock
+	// Inputs records each input's contribution to the desired codes.
+	Inputs []*InputContribution
+}
+
+// mode returns the traffic direction to apply.
+func (o configureOptions) mode() string {
+	if o.Mode == "" {
+		return unifi.DirectionBoth
+	}
+	return o.Mode
+}
+
+// now returns the current time from opts.Clock.
+func (o configureOptions) now() time.Time {
+	return clock.Or(o.Clock).Now()
+}
+
+// MultiSiteResult combines the per-site results of a multi-site run.
+type MultiSiteResult struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Status    string          `json:"status"` // success, partial, or failure
+	Sites     []*ConfigResult `json:"sites"`
 }
 
 func main() {
@@ -36,16 +125,54 @@ func main() {
 	username := flag.String("username", "", "UniFi username")
 	password := flag.String("password", "", "UniFi password")
 	site := flag.String("site", "default", "UniFi site name")
+	sitesList := flag.String("sites", "", "Comma-separated site names to configure (overrides -site)")
 	insecure := flag.Bool("insecure", false, "Skip TLS certificate verification")
-	inputFile := flag.String("input", "data/blocked_countries.txt", "Input file with country codes")
-	inputURL := flag.String("input-url", "", "URL to fetch country codes from (overrides -input)")
+	caCert := flag.String("ca-cert", "", "PEM file of CA certificates to trust for the controller (or UNIFI_CA_CERT_FILE env)")
+	var inputFiles, inputURLs stringList
+	flag.Var(&inputFiles, "input", "Input file with country codes, text or JSON (repeatable; default "+defaultInput+" unless -input-url is given)")
+	flag.Var(&inputURLs, "input-url", "URL to fetch country codes from (repeatable; combined with any -input)")
+	inputSHA256 := flag.String("input-sha256", "", "Expected SHA256 of the input content; abort on mismatch")
+	inputSHA256URL := flag.String("input-sha256-url", "", "URL of a sha256sum-style file with the expected input hash")
+	inputTimeout := flag.Duration("input-timeout", 30*time.Second, "Timeout for each -input-url or -input-sha256-url fetch attempt")
+	inputRetries := flag.Int("input-retries", 2, "Retries for a failed -input-url or -input-sha256-url fetch (network errors, 429, 5xx)")
+	minSources := flag.Int("min-sources", 0, "Apply only countries the JSON inputs say at least this many sources list (0 = no minimum)")
+	categories := flag.String("category", "", "Comma-separated source categories, e.g. sanctions; apply only JSON-input countries listed by sources in them, counting only those toward -min-sources")
 	dryRun := flag.Bool("dry-run", false, "Show what would change without applying")
 	verbose := flag.Bool("verbose", false, "Enable verbose output")
-	outputJSON := flag.String("output", "", "Write result to JSON file")
-	endpoint := flag.String("endpoint", "", "Override the region blocking endpoint path")
-	enable := flag.Bool("enable", true, "Enable region blocking (set to false to disable)")
+	quiet := flag.Bool("quiet", false, "Only print errors and requested output; progress and summaries are suppressed")
+	outputJSON := flag.String("output", "", "Write result to JSON file (- = stdout)")
+	endpoint := flag.String("endpoint", "", "Override the region blocking settings path, e.g. set/setting/usg")
+	enable := flag.Bool("enable", true, "Enable region blocking (set to false to disable); overrides any enabled setting in the inputs")
+	mode := flag.String("mode", "", "Traffic direction to block: both, inbound, or outbound (default both); overrides any mode in the inputs")
+	preserveUnknown := flag.Bool("preserve-unknown", false, "Keep codes on the controller that this tool didn't add (e.g. added manually in the UI)")
+	addCodes := flag.String("add", "", "Comma-separated codes to ensure are blocked, leaving others alone (alternative to -input)")
+	removeCodes := flag.String("remove", "", "Comma-separated codes to ensure are not blocked, leaving others alone (alternative to -input)")
+	verifyTimeout := flag.Duration("verify-timeout", 60*time.Second, "How long to poll for the applied change while the controller provisions (0 = check once)")
+	stateFile := flag.String("state-file", ".configure-state.json", "File recording which codes this tool manages per site")
+	envFile := flag.String("env-file", config.DefaultEnvFile, "Read KEY=VALUE settings from this file (ignored if missing; real env vars take precedence)")
+	configFile := flag.String("config", "", "YAML (or .toml) config with a controllers list; configures each controller instead of -host")
+	controllerNames := flag.String("controllers", "", "Comma-separated controller names from -config to configure (empty = all)")
+	cleanup := flag.Bool("cleanup", false, "Disable region blocking and forget this tool's state for the site (uninstall)")
+	cleanupClear := flag.Bool("cleanup-clear", false, "With -cleanup, also clear the country list")
+	webhookURL := flag.String("webhook-url", "", "POST a JSON summary to this URL after each apply that changes the controller")
+	webhookRetries := flag.Int("webhook-retries", 3, "Retries for a failed webhook delivery")
+	webhookTimeout := flag.Duration("webhook-timeout", 10*time.Second, "Timeout for each webhook attempt")
+	strict := flag.Bool("strict", false, "Fail instead of dropping desired codes the controller's country table (stat/ccode) doesn't list")
+	controllerWorkers := flag.Int("controller-workers", 0, "Number of controllers to configure concurrently (0 = auto)")
+	delayBetweenSites := flag.Duration("delay-between-sites", 0, "With -sites, pause this long after a site that was changed before applying the next")
+	waitForSettle := flag.Bool("wait-for-settle", false, "With -sites, don't move on until a changed site reads back as applied, for up to -settle-timeout")
+	settleTimeout := flag.Duration("settle-timeout", 5*time.Minute, "How long -wait-for-settle polls each changed site")
 
 	flag.Parse()
+	console.SetQuiet(*quiet)
+
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	if err := config.LoadDotEnv(*envFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading env file: %v\n", err)
+		os.Exit(exitcode.Usage)
+	}
 
 	// Load from environment if not provided
 	if *host == "" {
@@ -57,129 +184,511 @@ func main() {
 	if *password == "" {
 		*password = os.Getenv("UNIFI_PASSWORD")
 	}
+	if *caCert == "" {
+		*caCert = os.Getenv("UNIFI_CA_CERT_FILE")
+	}
 
-	if *host == "" || *username == "" || *password == "" {
+	var profiles []config.ControllerProfile
+	if *configFile != "" {
+		var err error
+		profiles, err = loadControllers(*configFile, splitList(*controllerNames))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading controllers: %v\n", err)
+			os.Exit(exitcode.Usage)
+		}
+	} else if *host == "" || *username == "" || *password == "" {
 		fmt.Fprintln(os.Stderr, "Error: host, username, and password are required")
 		fmt.Fprintln(os.Stderr, "Use flags or environment variables: UNIFI_HOST, UNIFI_USERNAME, UNIFI_PASSWORD")
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitcode.Usage)
 	}
 
-	// Load desired country codes
-	codes, err := loadCodes(*inputFile, *inputURL)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading country codes: %v\n", err)
-		os.Exit(1)
+	var (
+		err         error
+		codes       []string
+		inputs      []*InputContribution
+		add, remove []string
+	)
+	ensureMode := *addCodes != "" || *removeCodes != ""
+
+	if *cleanup && ensureMode {
+		fmt.Fprintln(os.Stderr, "Error: -cleanup can't be combined with -add or -remove")
+		os.Exit(exitcode.Usage)
+	}
+	filter := inputFilter{MinSources: *minSources, Categories: splitList(*categories)}
+	if filter.MinSources < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -min-sources can't be negative")
+		os.Exit(exitcode.Usage)
+	}
+	if filter.active() && (*cleanup || ensureMode) {
+		fmt.Fprintln(os.Stderr, "Error: -min-sources and -category filter -input and -input-url; they can't be combined with -cleanup, -add, or -remove")
+		os.Exit(exitcode.Usage)
+	}
+
+	if *cleanup {
+		// Undo this tool's changes instead of applying a list
+		if *cleanupClear {
+			console.Println("Cleanup: disabling region blocking and clearing the country list")
+		} else {
+			console.Println("Cleanup: disabling region blocking")
+		}
+	} else if ensureMode {
+		// Change only the listed codes instead of applying a full list
+		add, remove, err = parseEnsureCodes(*addCodes, *removeCodes)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitcode.Usage)
+		}
+		console.Printf("Ensuring %d codes blocked and %d codes not blocked\n", len(add), len(remove))
+	} else {
+		if len(inputFiles) == 0 && len(inputURLs) == 0 {
+			inputFiles = stringList{defaultInput}
+		}
+		if len(inputFiles)+len(inputURLs) > 1 && (*inputSHA256 != "" || *inputSHA256URL != "") {
+			fmt.Fprintln(os.Stderr, "Error: -input-sha256 and -input-sha256-url need exactly one input")
+			os.Exit(exitcode.Usage)
+		}
+
+		// Stop fetching inputs on Ctrl-C; the default handling is restored
+		// once they are loaded
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		fetcher := newInputFetcher(*inputRetries, *inputTimeout)
+
+		// Resolve the expected input hash, if any
+		expectedHash := strings.ToLower(strings.TrimSpace(*inputSHA256))
+		if expectedHash == "" && *inputSHA256URL != "" {
+			expectedHash, err = fetchExpectedHash(ctx, fetcher, *inputSHA256URL)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error fetching input hash: %v\n", err)
+				os.Exit(exitcode.FromError(err))
+			}
+		}
+
+		// Load desired country codes: the union of every input
+		inputs, err = loadInputs(ctx, fetcher, inputFiles, inputURLs, expectedHash, filter)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading country codes: %v\n", err)
+			os.Exit(inputExitCode(err))
+		}
+		codes = mergeInputs(inputs)
+
+		// An explicit -enable or -mode wins over what the inputs declare
+		if !explicit["enable"] {
+			declared, ok, err := mergedEnabled(inputs)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitcode.Usage)
+			}
+			if ok {
+				*enable = declared
+			}
+		}
+		if !explicit["mode"] {
+			*mode, err = mergedMode(inputs)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitcode.Usage)
+			}
+		}
+
+		if len(codes) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: no country codes loaded")
+			os.Exit(exitcode.Usage)
+		}
+
+		console.Printf("Loaded %d country codes to apply\n", len(codes))
+		if len(inputs) > 1 {
+			for _, in := range inputs {
+				console.Printf("  - %s: %d codes\n", in.Input, len(in.Codes))
+			}
+		}
+		for _, in := range inputs {
+			if len(in.Filtered) > 0 {
+				console.Printf("Left out %d countries from %s below the provenance bar: %s\n", len(in.Filtered), in.Input, strings.Join(in.Filtered, ", "))
+			}
+		}
+		if *verbose {
+			console.Printf("Codes: %s\n", strings.Join(codes, ", "))
+		}
 	}
 
-	if len(codes) == 0 {
-		fmt.Fprintln(os.Stderr, "Error: no country codes loaded")
-		os.Exit(1)
+	if *mode != "" {
+		if *mode, err = parseMode(*mode); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -mode: %v\n", err)
+			os.Exit(exitcode.Usage)
+		}
 	}
 
-	fmt.Printf("Loaded %d country codes to apply\n", len(codes))
-	if *verbose {
-		fmt.Printf("Codes: %s\n", strings.Join(codes, ", "))
+	// Catch a malformed override before connecting to any site
+	if *endpoint != "" {
+		if _, err := unifi.ParseSettingPath(*endpoint); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -endpoint: %v\n", err)
+			os.Exit(exitcode.Usage)
+		}
 	}
 
 	if *dryRun {
-		fmt.Println("\n[DRY RUN MODE - No changes will be applied]")
+		console.Println("\n[DRY RUN MODE - No changes will be applied]")
 	}
 
-	// Connect to UniFi
-	fmt.Printf("\nConnecting to %s...\n", *host)
-
-	client, err := unifi.NewClient(unifi.ClientConfig{
+	clientCfg := unifi.ClientConfig{
 		Host:          *host,
 		Username:      *username,
 		Password:      *password,
-		Site:          *site,
 		SkipTLSVerify: *insecure,
+		CACertFile:    *caCert,
 		Verbose:       *verbose,
-	})
+		// Empty keeps the default usg setting
+		RegionBlockingPath: *endpoint,
+	}
+
+	sites := []string{*site}
+	if *sitesList != "" {
+		sites = splitList(*sitesList)
+	}
+
+	state, err := loadState(*stateFile)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to connect: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+		os.Exit(exitcode.Usage)
 	}
-	defer client.Logout()
 
-	fmt.Println("Connected successfully")
+	opts := configureOptions{
+		Enable:          *enable,
+		Mode:            *mode,
+		Inputs:          inputs,
+		DryRun:          *dryRun,
+		Verbose:         *verbose,
+		PreserveUnknown: *preserveUnknown,
+		Add:             add,
+		Remove:          remove,
+		VerifyTimeout:   *verifyTimeout,
+		Cleanup:         *cleanup,
+		CleanupClear:    *cleanupClear,
+		Strict:          *strict,
+	}
+	if *webhookURL != "" {
+		opts.Webhook = newWebhook(*webhookURL, *webhookRetries, *webhookTimeout)
+	}
 
-	// Run the configuration
-	result := configureRegionBlocking(client, codes, *endpoint, *enable, *dryRun, *verbose)
+	// Independent controllers: each gets its own client and runs concurrently
+	if len(profiles) > 0 {
+		multi := &MultiControllerResult{Timestamp: time.Now()}
+		multi.Controllers = applyControllers(profiles, clientCfg, codes, opts, state, *controllerWorkers)
+		for _, r := range multi.Controllers {
+			if !r.Unsupported {
+				printResult(r)
+			}
+		}
+		if !ensureMode {
+			for _, r := range multi.Controllers {
+				recordState(*stateFile, state, r)
+			}
+		}
+		multi.Status = multiSiteStatus(multi.Controllers)
+
+		printMultiControllerSummary(multi)
+
+		if *outputJSON != "" {
+			if err := saveResult(*outputJSON, multi); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving result: %v\n", err)
+			} else if !console.IsStdout(*outputJSON) {
+				console.Printf("\nResult saved to %s\n", *outputJSON)
+			}
+		}
+
+		if code := combinedExitCode(multi.Controllers); code != exitcode.OK {
+			os.Exit(code)
+		}
+		return
+	}
+
+	// Single site: keep the original output and exit behavior
+	if len(sites) == 1 {
+		clientCfg.Site = sites[0]
+		opts.State = state
+		result := applySite(clientCfg, codes, opts)
+		// Ensure mode doesn't define the full managed set
+		if !ensureMode {
+			recordState(*stateFile, state, result)
+		}
+
+		// Print result
+		printResult(result)
 
-	// Print result
-	printResult(result)
+		// Save result if requested
+		if *outputJSON != "" {
+			if err := saveResult(*outputJSON, result); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving result: %v\n", err)
+			} else if !console.IsStdout(*outputJSON) {
+				console.Printf("\nResult saved to %s\n", *outputJSON)
+			}
+		}
+
+		if code := resultExitCode(result); code != exitcode.OK {
+			os.Exit(code)
+		}
+		return
+	}
+
+	// Multiple sites: a failure on one site must not stop the others.
+	// Sites are applied one at a time, and the delay and settle wait keep
+	// their gateways from all provisioning at once.
+	multi := &MultiSiteResult{Timestamp: time.Now()}
+	previousApplied := false
+	for _, s := range sites {
+		if previousApplied && *delayBetweenSites > 0 {
+			console.Printf("\nWaiting %s before the next site\n", *delayBetweenSites)
+			clock.Or(opts.Clock).Sleep(*delayBetweenSites)
+		}
+
+		console.Printf("\n%s\nSite: %s\n%s\n", strings.Repeat("#", 40), s, strings.Repeat("#", 40))
+
+		cfg := clientCfg
+		cfg.Site = s
+		siteOpts := opts
+		siteOpts.State = state
+		if *waitForSettle && siteOpts.VerifyTimeout < *settleTimeout {
+			// The verification poll returns as soon as the site reads back
+			// as applied, so a longer timeout only waits while it hasn't
+			siteOpts.VerifyTimeout = *settleTimeout
+		}
+		result := applySite(cfg, codes, siteOpts)
+		if !result.Unsupported {
+			printResult(result)
+		}
+		if *waitForSettle && result.Applied && !result.Verified {
+			console.Printf("Warning: site %s did not settle within %s; moving on\n", s, *settleTimeout)
+		}
+		multi.Sites = append(multi.Sites, result)
+		previousApplied = result.Applied
+	}
+	if !ensureMode {
+		for _, r := range multi.Sites {
+			recordState(*stateFile, state, r)
+		}
+	}
+	multi.Status = multiSiteStatus(multi.Sites)
+
+	printMultiSiteSummary(multi)
 
-	// Save result if requested
 	if *outputJSON != "" {
-		if err := saveResult(*outputJSON, result); err != nil {
+		if err := saveResult(*outputJSON, multi); err != nil {
 			fmt.Fprintf(os.Stderr, "Error saving result: %v\n", err)
-		} else {
-			fmt.Printf("\nResult saved to %s\n", *outputJSON)
+		} else if !console.IsStdout(*outputJSON) {
+			console.Printf("\nResult saved to %s\n", *outputJSON)
 		}
 	}
 
-	if result.Error != "" {
-		os.Exit(1)
+	if code := combinedExitCode(multi.Sites); code != exitcode.OK {
+		os.Exit(code)
 	}
 }
 
-func loadCodes(filePath, url string) ([]string, error) {
-	var content []byte
-	var err error
+// fallbackCountryGroup is the ConfigResult.Fallback for controllers that
+// can block countries with a firewall rule and a country group.
+const fallbackCountryGroup = "firewall_country_group"
 
-	if url != "" {
-		// Fetch from URL
-		resp, err := http.Get(url)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch URL: %w", err)
-		}
-		defer resp.Body.Close()
+// applySite connects to one site and configures it. Every failure is recorded
+// in the returned result so callers can carry on with other sites.
+func applySite(cfg unifi.ClientConfig, codes []string, opts configureOptions) *ConfigResult {
+	// Connect to UniFi
+	console.Printf("\nConnecting to %s...\n", cfg.Host)
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	client, err := unifi.NewClient(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect: %v\n", err)
+		return &ConfigResult{
+			Timestamp:    opts.now(),
+			Site:         cfg.Site,
+			DryRun:       opts.DryRun,
+			DesiredCodes: codes,
+			Inputs:       opts.Inputs,
+			Error:        fmt.Sprintf("failed to connect: %v", err),
+			exitCode:     exitcode.FromError(err),
 		}
+	}
+	defer client.Logout()
 
-		content, err = io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response: %w", err)
+	console.Println("Connected successfully")
+
+	// The client may have fallen back to the controller's only site
+	if opts.State != nil {
+		opts.Managed = opts.State.Sites[stateKey(opts.Controller, client.Site())]
+	}
+
+	// Make sure region blocking is available before computing any changes
+	supported, err := client.SupportsGeoIPFiltering()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to check region blocking support: %v\n", err)
+		return &ConfigResult{
+			Timestamp:    opts.now(),
+			Site:         client.Site(),
+			DryRun:       opts.DryRun,
+			DesiredCodes: codes,
+			Inputs:       opts.Inputs,
+			Error:        fmt.Sprintf("failed to check region blocking support: %v", err),
 		}
+	}
+	if !supported {
+		result := &ConfigResult{
+			Timestamp:    opts.now(),
+			Site:         client.Site(),
+			DryRun:       opts.DryRun,
+			DesiredCodes: codes,
+			Inputs:       opts.Inputs,
+			Unsupported:  true,
+		}
+		console.Println("\nThis controller/firmware does not support region blocking (no geo_ip_filtering settings found).")
+		countryGroups, err := client.SupportsCountryGroups()
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Warning: failed to check firewall group support: %v\n", err)
+			console.Println("Suggestion: upgrade the gateway firmware to a version that offers Region Blocking.")
+		case countryGroups:
+			result.Fallback = fallbackCountryGroup
+			console.Println("It does support country firewall groups: block these countries with a firewall rule")
+			console.Println("and a country group instead, or upgrade the gateway firmware to one that offers Region Blocking.")
+		default:
+			console.Println("It doesn't support country firewall groups either; upgrade the gateway firmware")
+			console.Println("to a version that offers Region Blocking.")
+		}
+		return result
+	}
+
+	// Run the configuration
+	var result *ConfigResult
+	if opts.Cleanup {
+		result = cleanupRegionBlocking(client, opts)
+	} else if len(opts.Add) > 0 || len(opts.Remove) > 0 {
+		result = ensureRegionBlocking(client, opts)
 	} else {
-		// Read from file
-		content, err = os.ReadFile(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read file: %w", err)
+		result = configureRegionBlocking(client, codes, opts)
+	}
+	result.Site = client.Site()
+	result.Host = cfg.Host
+	result.Inputs = opts.Inputs
+
+	if result.Applied && result.Changed && opts.Webhook != nil {
+		if err := opts.Webhook.notify(result); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: webhook notification failed: %v\n", err)
 		}
 	}
 
-	// Parse codes (one per line, skip comments and blank lines)
-	var codes []string
-	scanner := bufio.NewScanner(strings.NewReader(string(content)))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// Skip comments (lines starting with #) and blank lines
-		if line != "" && !strings.HasPrefix(line, "#") {
-			// Validate it looks like a country code (2 uppercase letters)
-			if len(line) == 2 {
-				codes = append(codes, strings.ToUpper(line))
-			}
+	return result
+}
+
+// Overall status values for a multi-site run.
+const (
+	statusSuccess = "success"
+	statusPartial = "partial"
+	statusFailure = "failure"
+)
+
+// resultExitCode maps one site's result to an exit status: an error's class,
+// or Verification for an apply the controller didn't persist as sent.
+func resultExitCode(r *ConfigResult) int {
+	switch {
+	case r.Error != "" && r.exitCode != exitcode.OK:
+		return r.exitCode
+	case r.Error != "":
+		return exitcode.Controller
+	case r.Applied && !r.Verified:
+		return exitcode.Verification
+	default:
+		return exitcode.OK
+	}
+}
+
+// combinedExitCode maps the results of a multi-site or multi-controller run
+// to one exit status.
+func combinedExitCode(results []*ConfigResult) int {
+	codes := make([]int, len(results))
+	for i, r := range results {
+		codes[i] = resultExitCode(r)
+	}
+	return exitcode.Combine(codes)
+}
+
+// multiSiteStatus summarizes per-site results into one overall status.
+func multiSiteStatus(results []*ConfigResult) string {
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
 		}
 	}
 
-	return codes, scanner.Err()
+	switch {
+	case failed == 0:
+		return statusSuccess
+	case failed == len(results):
+		return statusFailure
+	default:
+		return statusPartial
+	}
 }
 
-func configureRegionBlocking(client *unifi.Client, desiredCodes []string, endpointOverride string, enable, dryRun, verbose bool) *ConfigResult {
+func printMultiSiteSummary(multi *MultiSiteResult) {
+	console.Println("\n" + strings.Repeat("=", 60))
+	console.Println("MULTI-SITE SUMMARY")
+	console.Println(strings.Repeat("=", 60))
+
+	console.Printf("%-20s %-12s %-8s %-8s %-8s %s\n", "SITE", "STATUS", "CHANGED", "ADDED", "REMOVED", "VERIFIED")
+	for _, r := range multi.Sites {
+		status := "ok"
+		switch {
+		case r.Error != "":
+			status = "failed"
+		case r.Unsupported:
+			status = "unsupported"
+		}
+		console.Printf("%-20s %-12s %-8v %-8d %-8d %v\n", r.Site, status, r.Changed, len(r.AddedCodes), len(r.RemovedCodes), r.Verified)
+	}
+
+	for _, r := range multi.Sites {
+		if r.Error != "" {
+			console.Printf("  - %s: %s\n", r.Site, r.Error)
+		}
+	}
+
+	console.Printf("\nOverall: %s\n", multi.Status)
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// fetchExpectedHash reads a hash from a sha256sum-style file ("<hash>  <name>").
+func fetchExpectedHash(ctx context.Context, fetcher *inputFetcher, url string) (string, error) {
+	content, err := fetcher.fetch(ctx, url)
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 || len(fields[0]) != 64 {
+		return "", fmt.Errorf("no SHA256 hash found at %s", url)
+	}
+
+	return strings.ToLower(fields[0]), nil
+}
+
+func configureRegionBlocking(client unifi.RegionBlockingClient, desiredCodes []string, opts configureOptions) *ConfigResult {
 	result := &ConfigResult{
-		Timestamp:    time.Now(),
-		DryRun:       dryRun,
+		Timestamp:    opts.now(),
+		DryRun:       opts.DryRun,
 		DesiredCodes: desiredCodes,
 	}
 
-	// Use the discovered endpoint for region blocking (usg setting)
-	// endpointOverride is ignored since we now use the specific API methods
-
 	// Fetch current configuration using the new API
 	currentCodes, err := client.GetBlockedCountries()
 	if err != nil {
@@ -187,8 +696,8 @@ func configureRegionBlocking(client *unifi.Client, desiredCodes []string, endpoi
 		return result
 	}
 
-	if verbose {
-		fmt.Printf("Current blocked countries: %v\n", currentCodes)
+	if opts.Verbose {
+		console.Printf("Current blocked countries: %v\n", currentCodes)
 	}
 
 	result.PreviousCodes = currentCodes
@@ -196,141 +705,499 @@ func configureRegionBlocking(client *unifi.Client, desiredCodes []string, endpoi
 	// Check current enabled state
 	setting, err := client.GetRegionBlockingSettings()
 	currentEnabled := false
+	currentMode := unifi.DirectionBoth
 	if err == nil {
 		if enabledVal, ok := setting["geo_ip_filtering_enabled"].(bool); ok {
 			currentEnabled = enabledVal
 		}
+		if modeVal, ok := setting["geo_ip_filtering_traffic_direction"].(string); ok && modeVal != "" {
+			currentMode = modeVal
+		}
+	}
+	mode := opts.mode()
+
+	// Codes the controller would silently ignore are dropped up front, so
+	// verification isn't left comparing against codes that can't persist
+	supportedCodes, err := checkSupported(client, desiredCodes, result, opts)
+	if err != nil {
+		result.Error = err.Error()
+		result.exitCode = exitcode.Verification
+		return result
+	}
+
+	// Codes we didn't add are carried over instead of removed
+	applyCodes := supportedCodes
+	if opts.PreserveUnknown {
+		result.PreservedCodes = unmanagedCodes(currentCodes, desiredCodes, opts.Managed)
+		if len(result.PreservedCodes) > 0 {
+			applyCodes = append(append([]string{}, supportedCodes...), result.PreservedCodes...)
+		}
 	}
 
 	// Calculate diff
-	added, removed := diffCodes(currentCodes, desiredCodes)
+	added, removed := diffCodes(currentCodes, applyCodes)
 	result.AddedCodes = added
 	result.RemovedCodes = removed
-	result.Changed = len(added) > 0 || len(removed) > 0 || currentEnabled != enable
+	result.Changed = len(added) > 0 || len(removed) > 0 || currentEnabled != opts.Enable || currentMode != mode
+
+	if len(result.PreservedCodes) > 0 {
+		console.Printf("\nPreserved (manual): %s\n", strings.Join(result.PreservedCodes, ", "))
+	}
 
 	if !result.Changed {
-		fmt.Println("\nNo changes needed - configuration already matches")
+		console.Println("\nNo changes needed - configuration already matches")
 		result.Verified = true
 		return result
 	}
 
-	fmt.Printf("\nChanges required:\n")
-	if currentEnabled != enable {
-		fmt.Printf("  Enable: %v -> %v\n", currentEnabled, enable)
+	console.Printf("\nChanges required:\n")
+	if currentEnabled != opts.Enable {
+		console.Printf("  Enable: %v -> %v\n", currentEnabled, opts.Enable)
+	}
+	if currentMode != mode {
+		console.Printf("  Mode: %s -> %s\n", currentMode, mode)
 	}
 	if len(added) > 0 {
-		fmt.Printf("  Adding: %s\n", strings.Join(added, ", "))
+		console.Printf("  Adding: %s\n", strings.Join(added, ", "))
 	}
 	if len(removed) > 0 {
-		fmt.Printf("  Removing: %s\n", strings.Join(removed, ", "))
+		console.Printf("  Removing: %s\n", strings.Join(removed, ", "))
 	}
 
-	if dryRun {
-		fmt.Println("\n[DRY RUN] Changes not applied")
+	if opts.DryRun {
+		// Check the payload shape so problems surface before a real apply
+		problems, err := client.ValidateRegionBlockingSettings(opts.Enable, applyCodes, "block", mode)
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to validate payload: %v", err)
+			return result
+		}
+		result.ValidationProblems = problems
+		if len(problems) > 0 {
+			console.Println("\nPayload validation problems:")
+			for _, p := range problems {
+				console.Printf("  - %s\n", p)
+			}
+		} else {
+			console.Println("\nPayload validation: OK")
+		}
+
+		if opts.Verbose {
+			if err := printPayload(client, opts.Enable, applyCodes, "block", mode); err != nil {
+				result.Error = fmt.Sprintf("failed to build payload: %v", err)
+				return result
+			}
+		}
+
+		console.Println("\n[DRY RUN] Changes not applied")
 		return result
 	}
 
 	// Apply changes using the new API
-	if err := client.UpdateRegionBlockingSettings(enable, desiredCodes, "block", "both"); err != nil {
+	if err := client.UpdateRegionBlockingSettings(opts.Enable, applyCodes, "block", mode); err != nil {
 		result.Error = fmt.Sprintf("failed to apply changes: %v", err)
 		return result
 	}
 
-	fmt.Println("Configuration applied successfully")
+	result.Applied = true
+
+	console.Println("Configuration applied successfully")
+
+	// Verify. The controller provisions the gateway after an update, and
+	// reads during that window can return the old values, so poll until the
+	// change shows up or the timeout passes.
+	want := regionBlockingState{Enabled: opts.Enable, Codes: applyCodes, Block: "block", Direction: mode}
+	verifyResult(result, client, want, opts)
 
-	// Verify
-	newCodes, err := client.GetBlockedCountries()
+	return result
+}
+
+// printPayload shows the exact body an apply would POST, for comparing
+// against a HAR capture of the UI's request.
+func printPayload(client unifi.RegionBlockingClient, enabled bool, codes []string, block, direction string) error {
+	payload, err := client.RegionBlockingPayload(enabled, codes, block, direction)
 	if err != nil {
-		result.Error = fmt.Sprintf("failed to verify: %v", err)
-		return result
+		return err
+	}
+
+	data, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	console.Printf("\nRequest body:\n%s\n", data)
+	return nil
+}
+
+// regionBlockingState is the set of region blocking fields this tool writes.
+type regionBlockingState struct {
+	Enabled   bool
+	Codes     []string
+	Block     string
+	Direction string
+}
+
+// Field names used in ConfigResult.VerifiedFields.
+const (
+	fieldEnabled   = "enabled"
+	fieldCountries = "countries"
+	fieldBlock     = "block"
+	fieldDirection = "traffic_direction"
+)
+
+// compareState reports, for each field, whether got matches want.
+func compareState(got, want regionBlockingState) map[string]bool {
+	gotCodes := append([]string{}, got.Codes...)
+	wantCodes := append([]string{}, want.Codes...)
+	sort.Strings(gotCodes)
+	sort.Strings(wantCodes)
+
+	return map[string]bool{
+		fieldEnabled:   got.Enabled == want.Enabled,
+		fieldCountries: equalCodes(gotCodes, wantCodes),
+		fieldBlock:     got.Block == want.Block,
+		fieldDirection: got.Direction == want.Direction,
+	}
+}
+
+// stateFromSetting reads the fields this tool writes from a USG setting.
+func stateFromSetting(setting map[string]interface{}) regionBlockingState {
+	var state regionBlockingState
+	state.Enabled, _ = setting["geo_ip_filtering_enabled"].(bool)
+	state.Codes = unifi.CountryCodesFromSetting(setting)
+	state.Block, _ = setting["geo_ip_filtering_block"].(string)
+	state.Direction, _ = setting["geo_ip_filtering_traffic_direction"].(string)
+	return state
+}
+
+// failedFields returns the sorted names of fields that did not verify.
+func failedFields(fields map[string]bool) []string {
+	var failed []string
+	for name, ok := range fields {
+		if !ok {
+			failed = append(failed, name)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+// Values of ConfigResult.VerifiedBy.
+const (
+	verifiedBySetResponse = "set_response"
+	verifiedByReadBack    = "read_back"
+)
+
+// verifyResult records on result whether the apply persisted. If the set
+// response echoed the setting with every field as sent, that is enough;
+// otherwise verifyApplied reads it back.
+func verifyResult(result *ConfigResult, client unifi.RegionBlockingClient, want regionBlockingState, opts configureOptions) {
+	if echo, ok := client.(interface {
+		AppliedSetting() (map[string]interface{}, bool)
+	}); ok {
+		if setting, ok := echo.AppliedSetting(); ok {
+			fields := compareState(stateFromSetting(setting), want)
+			if len(failedFields(fields)) == 0 {
+				result.VerifiedFields = fields
+				result.Verified = true
+				result.VerifiedBy = verifiedBySetResponse
+				return
+			}
+			if opts.Verbose {
+				console.Println("Set response differs from what was sent; reading the setting back")
+			}
+		}
 	}
 
-	// Check if the new config matches desired
-	sort.Strings(newCodes)
-	sort.Strings(desiredCodes)
+	fields, elapsed, err := verifyApplied(client, want, opts.VerifyTimeout, opts.Verbose, clock.Or(opts.Clock))
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to verify: %v", err)
+		return
+	}
 
-	result.Verified = len(newCodes) == len(desiredCodes)
+	result.VerifiedFields = fields
+	result.VerifiedBy = verifiedByReadBack
+	failed := failedFields(fields)
+	result.Verified = len(failed) == 0
 	if result.Verified {
-		for i := range newCodes {
-			if newCodes[i] != desiredCodes[i] {
-				result.Verified = false
-				break
+		result.ConvergeSeconds = elapsed.Seconds()
+	} else {
+		console.Printf("Fields not persisted by the controller: %s\n", strings.Join(failed, ", "))
+	}
+}
+
+// verifyApplied polls the controller with backoff until every region
+// blocking field matches want or timeout elapses. It returns the per-field
+// result of the last read and how long it took. A read error is only
+// returned if no read succeeded before the deadline. Time is read and slept
+// through clk.
+func verifyApplied(client unifi.RegionBlockingClient, want regionBlockingState, timeout time.Duration, verbose bool, clk clock.Clock) (map[string]bool, time.Duration, error) {
+	start := clk.Now()
+	deadline := start.Add(timeout)
+	delay := time.Second
+	var (
+		lastErr error
+		fields  map[string]bool
+	)
+
+	for {
+		setting, err := client.GetRegionBlockingSettings()
+		if err == nil {
+			fields = compareState(stateFromSetting(setting), want)
+			if len(failedFields(fields)) == 0 {
+				return fields, clock.Since(clk, start), nil
 			}
+		} else {
+			lastErr = err
+		}
+
+		remaining := deadline.Sub(clk.Now())
+		if remaining <= 0 {
+			break
+		}
+		if delay > remaining {
+			delay = remaining
+		}
+		if verbose {
+			console.Printf("Controller not converged yet, re-checking in %s\n", delay)
+		}
+		clk.Sleep(delay)
+		if delay < 10*time.Second {
+			delay *= 2
 		}
 	}
 
-	return result
+	if fields == nil {
+		return nil, clock.Since(clk, start), lastErr
+	}
+	return fields, clock.Since(clk, start), nil
 }
 
-// discoverRegionBlockingEndpoint and getCurrentBlockedCountries are no longer needed
-// as we now use the specific API methods in the unifi client.
+// equalCodes reports whether two sorted code lists are identical.
+func equalCodes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
 
-// extractCountryCodesFromData and isUpperAlpha removed - no longer needed
-// as we use the specific API methods in the unifi client
+// ensureRegionBlocking adds opts.Add and drops opts.Remove from the blocked
+// list without touching any other code or the enabled flag.
+func ensureRegionBlocking(client unifi.RegionBlockingClient, opts configureOptions) *ConfigResult {
+	result := &ConfigResult{
+		Timestamp: opts.now(),
+		DryRun:    opts.DryRun,
+	}
 
-// applyBlockedCountries is no longer needed as we use client.UpdateRegionBlockingSettings
+	setting, err := client.GetRegionBlockingSettings()
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to get current config: %v", err)
+		return result
+	}
 
-func diffCodes(current, desired []string) (added, removed []string) {
-	currentSet := make(map[string]bool)
-	desiredSet := make(map[string]bool)
+	add, err := checkSupported(client, opts.Add, result, opts)
+	if err != nil {
+		result.Error = err.Error()
+		result.exitCode = exitcode.Verification
+		return result
+	}
+	opts.Add = add
 
-	for _, c := range current {
-		currentSet[c] = true
+	currentCodes := unifi.CountryCodesFromSetting(setting)
+	enabled, _ := setting["geo_ip_filtering_enabled"].(bool)
+	block, _ := setting["geo_ip_filtering_block"].(string)
+	direction, _ := setting["geo_ip_filtering_traffic_direction"].(string)
+	result.PreviousCodes = currentCodes
+
+	resulting := unifi.ApplyCodeChanges(currentCodes, opts.Add, opts.Remove)
+	result.DesiredCodes = resulting
+	result.ResultingCodes = resulting
+
+	added, removed := diffCodes(currentCodes, resulting)
+	result.AddedCodes = added
+	result.RemovedCodes = removed
+	result.Changed = len(added) > 0 || len(removed) > 0
+
+	if !result.Changed {
+		console.Println("\nNo changes needed - requested codes already in place")
+		result.Verified = true
+		return result
 	}
-	for _, c := range desired {
-		desiredSet[c] = true
+
+	console.Printf("\nChanges required:\n")
+	if len(added) > 0 {
+		console.Printf("  Adding: %s\n", strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		console.Printf("  Removing: %s\n", strings.Join(removed, ", "))
+	}
+	console.Printf("  Resulting: %s\n", strings.Join(resulting, ", "))
+
+	if opts.DryRun {
+		if opts.Verbose {
+			if err := printPayload(client, enabled, resulting, block, direction); err != nil {
+				result.Error = fmt.Sprintf("failed to build payload: %v", err)
+				return result
+			}
+		}
+		console.Println("\n[DRY RUN] Changes not applied")
+		return result
+	}
+
+	applied, err := client.EnsureBlockedCountries(opts.Add, opts.Remove)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to apply changes: %v", err)
+		return result
+	}
+	result.DesiredCodes = applied
+	result.ResultingCodes = applied
+
+	result.Applied = true
+
+	console.Println("Configuration applied successfully")
+
+	// The enabled flag and mode are sent back unchanged, with the client's
+	// defaults filling in any that were unset
+	want := regionBlockingState{Enabled: enabled, Codes: applied, Block: block, Direction: direction}
+	if want.Block == "" {
+		want.Block = "block"
+	}
+	if want.Direction == "" {
+		want.Direction = "both"
 	}
+	verifyResult(result, client, want, opts)
 
-	for c := range desiredSet {
-		if !currentSet[c] {
-			added = append(added, c)
+	return result
+}
+
+// parseEnsureCodes splits and validates the -add and -remove lists.
+func parseEnsureCodes(addList, removeList string) ([]string, []string, error) {
+	normalizer := countries.NewNormalizer()
+
+	parse := func(list string) ([]string, error) {
+		var codes []string
+		for _, code := range splitList(list) {
+			code = strings.ToUpper(code)
+			if !normalizer.IsValidCode(code) {
+				return nil, fmt.Errorf("invalid country code %q", code)
+			}
+			codes = append(codes, code)
 		}
+		return codes, nil
+	}
+
+	add, err := parse(addList)
+	if err != nil {
+		return nil, nil, err
+	}
+	remove, err := parse(removeList)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	for c := range currentSet {
-		if !desiredSet[c] {
-			removed = append(removed, c)
+	return add, remove, nil
+}
+
+// unmanagedCodes returns current codes that are neither desired nor known to
+// have been applied by this tool, i.e. codes someone added by hand.
+func unmanagedCodes(current, desired, managed []string) []string {
+	ours := make(map[string]bool)
+	for _, c := range desired {
+		ours[c] = true
+	}
+	for _, c := range managed {
+		ours[c] = true
+	}
+
+	var unknown []string
+	for _, c := range current {
+		if !ours[c] {
+			unknown = append(unknown, c)
 		}
 	}
+	sort.Strings(unknown)
 
-	sort.Strings(added)
-	sort.Strings(removed)
+	return unknown
+}
 
-	return
+// discoverRegionBlockingEndpoint and getCurrentBlockedCountries are no longer needed
+// as we now use the specific API methods in the unifi client.
+
+// extractCountryCodesFromData and isUpperAlpha removed - no longer needed
+// as we use the specific API methods in the unifi client
+
+// applyBlockedCountries is no longer needed as we use client.UpdateRegionBlockingSettings
+
+func diffCodes(current, desired []string) (added, removed []string) {
+	return countries.Difference(desired, current), countries.Difference(current, desired)
 }
 
 func printResult(result *ConfigResult) {
-	fmt.Println("\n" + strings.Repeat("=", 40))
-	fmt.Println("CONFIGURATION RESULT")
-	fmt.Println(strings.Repeat("=", 40))
+	console.Println("\n" + strings.Repeat("=", 40))
+	console.Println("CONFIGURATION RESULT")
+	console.Println(strings.Repeat("=", 40))
+
+	if result.Controller != "" {
+		console.Printf("Controller: %s (site %s)\n", result.Controller, result.Site)
+	}
 
 	if result.DryRun {
-		fmt.Println("Mode: DRY RUN (no changes applied)")
+		console.Println("Mode: DRY RUN (no changes applied)")
 	} else {
-		fmt.Println("Mode: APPLY")
+		console.Println("Mode: APPLY")
 	}
 
-	fmt.Printf("Changed: %v\n", result.Changed)
+	console.Printf("Changed: %v\n", result.Changed)
+
+	if result.Unsupported {
+		console.Println("Region blocking: not supported")
+		if result.Fallback != "" {
+			console.Printf("Fallback: %s\n", result.Fallback)
+		}
+	}
 
 	if result.Changed {
-		fmt.Printf("Added: %d codes\n", len(result.AddedCodes))
-		fmt.Printf("Removed: %d codes\n", len(result.RemovedCodes))
+		console.Printf("Added: %d codes\n", len(result.AddedCodes))
+		console.Printf("Removed: %d codes\n", len(result.RemovedCodes))
+	}
+
+	if len(result.PreservedCodes) > 0 {
+		console.Printf("Preserved (manual): %d codes\n", len(result.PreservedCodes))
+	}
+
+	if len(result.DroppedCodes) > 0 {
+		console.Printf("Dropped (unsupported): %s\n", strings.Join(result.DroppedCodes, ", "))
 	}
 
 	if !result.DryRun && result.Changed {
-		fmt.Printf("Verified: %v\n", result.Verified)
+		console.Printf("Verified: %v\n", result.Verified)
+		if result.VerifiedBy == verifiedBySetResponse {
+			console.Println("Confirmed by the set response")
+		} else if result.Verified {
+			console.Printf("Converged in: %.1fs\n", result.ConvergeSeconds)
+		} else if failed := failedFields(result.VerifiedFields); len(failed) > 0 {
+			console.Printf("Not persisted: %s\n", strings.Join(failed, ", "))
+		}
+	}
+
+	if len(result.ValidationProblems) > 0 {
+		console.Printf("Validation problems: %d\n", len(result.ValidationProblems))
 	}
 
 	if result.Error != "" {
-		fmt.Printf("Error: %s\n", result.Error)
+		console.Printf("Error: %s\n", result.Error)
 	}
 }
 
-func saveResult(path string, result *ConfigResult) error {
+func saveResult(path string, result interface{}) error {
 	data, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	return console.WriteFile(path, data, 0644)
 }
 
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// managedState records which codes this tool applied to each site, so later
+// runs can tell our codes apart from ones added manually in the UI.
+type managedState struct {
+	UpdatedAt time.Time           `json:"updated_at"`
+	Sites     map[string][]string `json:"sites"`
+}
+
+// loadState reads the state file, returning empty state if it doesn't exist.
+func loadState(path string) (*managedState, error) {
+	state := &managedState{Sites: make(map[string][]string)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+	if state.Sites == nil {
+		state.Sites = make(map[string][]string)
+	}
+
+	return state, nil
+}
+
+// recordState stores a successful apply's desired codes as managed by us, or
+// drops the site after a cleanup.
+// Preserved manual codes are deliberately not recorded. Failures to write
+// the state are reported but don't fail the run.
+func recordState(path string, state *managedState, result *ConfigResult) {
+	if path == "" || result.DryRun || result.Unsupported || result.Error != "" {
+		return
+	}
+
+	// After cleanup the tool no longer manages any codes on the site
+	if result.Cleanup {
+		delete(state.Sites, stateKey(result.Controller, result.Site))
+	} else {
+		state.Sites[stateKey(result.Controller, result.Site)] = result.DesiredCodes
+	}
+	state.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save state: %v\n", err)
+	}
+}
+
+// stateKey identifies a site in the state file. Sites on named controllers
+// are qualified so the same site name on two controllers doesn't collide.
+func stateKey(controller, site string) string {
+	if controller == "" {
+		return site
+	}
+	return controller + "/" + site
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattsblocklist/tae/internal/unifi"
+
+	"github.com/mattsblocklist/tae/internal/console"
+)
+
+// checkSupported returns the codes the controller's country table lists,
+// recording the rest in result.DroppedCodes. With opts.Strict any such code
+// is an error instead. If the table can't be read the codes are returned
+// unchecked, since older controllers don't all expose it.
+func checkSupported(client unifi.RegionBlockingClient, codes []string, result *ConfigResult, opts configureOptions) ([]string, error) {
+	if len(codes) == 0 {
+		return codes, nil
+	}
+
+	table, err := client.GetSupportedCountries()
+	if err != nil {
+		console.Printf("Warning: can't check codes against the controller's country table: %v\n", err)
+		return codes, nil
+	}
+
+	supported := make(map[string]bool, len(table))
+	for _, code := range table {
+		supported[code] = true
+	}
+
+	var kept, dropped []string
+	for _, code := range codes {
+		if supported[code] {
+			kept = append(kept, code)
+		} else {
+			dropped = append(dropped, code)
+		}
+	}
+	if len(dropped) == 0 {
+		return codes, nil
+	}
+
+	result.DroppedCodes = dropped
+	if opts.Strict {
+		return nil, fmt.Errorf("controller doesn't support %d desired codes: %s", len(dropped), strings.Join(dropped, ", "))
+	}
+	console.Printf("Warning: dropping %d codes the controller doesn't support: %s\n", len(dropped), strings.Join(dropped, ", "))
+
+	return kept, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// webhook delivers change notifications to an HTTP endpoint, e.g. a Slack or
+// Teams incoming webhook relay or an audit log collector.
+type webhook struct {
+	url     string
+	retries int
+	client  *http.Client
+}
+
+// webhookPayload is the summary POSTed after a change is applied.
+type webhookPayload struct {
+	Event          string          `json:"event"`
+	Timestamp      time.Time       `json:"timestamp"`
+	Host           string          `json:"host"`
+	Controller     string          `json:"controller,omitempty"`
+	Site           string          `json:"site"`
+	AddedCodes     []string        `json:"added_codes"`
+	RemovedCodes   []string        `json:"removed_codes"`
+	ResultingCodes []string        `json:"resulting_codes"`
+	Verified       bool            `json:"verified"`
+	VerifiedFields map[string]bool `json:"verified_fields,omitempty"`
+	Error          string          `json:"error,omitempty"`
+}
+
+func newWebhook(url string, retries int, timeout time.Duration) *webhook {
+	return &webhook{
+		url:     url,
+		retries: retries,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// notify POSTs a summary of result, retrying with backoff on network errors
+// and non-2xx responses.
+func (w *webhook) notify(result *ConfigResult) error {
+	resulting := result.ResultingCodes
+	if resulting == nil {
+		resulting = result.DesiredCodes
+	}
+	payload := webhookPayload{
+		Event:          "region_blocking_changed",
+		Timestamp:      result.Timestamp,
+		Host:           result.Host,
+		Controller:     result.Controller,
+		Site:           result.Site,
+		AddedCodes:     nonNil(result.AddedCodes),
+		RemovedCodes:   nonNil(result.RemovedCodes),
+		ResultingCodes: nonNil(resulting),
+		Verified:       result.Verified,
+		VerifiedFields: result.VerifiedFields,
+		Error:          result.Error,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	delay := time.Second
+	for attempt := 0; ; attempt++ {
+		err = w.post(body)
+		if err == nil || attempt >= w.retries {
+			break
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+	if err != nil {
+		return fmt.Errorf("giving up after %d attempts: %w", w.retries+1, err)
+	}
+	return nil
+}
+
+func (w *webhook) post(body []byte) error {
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// nonNil returns codes, or an empty list if it is nil, so the payload always
+// has arrays rather than nulls.
+func nonNil(codes []string) []string {
+	if codes == nil {
+		return []string{}
+	}
+	return codes
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/unifi"
+)
+
+// Capabilities summarizes what the controller supports, derived from the
+// endpoints that responded and the settings keys it has. Evidence names
+// what each true field was derived from.
+type Capabilities struct {
+	SupportsGeoIP       bool     `json:"supports_geo_ip"`
+	HasTrafficRules     bool     `json:"has_traffic_rules"`
+	HasThreatManagement bool     `json:"has_threat_management"`
+	HasFirewallRules    bool     `json:"has_firewall_rules"`
+	HasCountryGroups    bool     `json:"has_country_groups"`
+	Evidence            []string `json:"evidence,omitempty"`
+}
+
+// Path and settings key fragments that indicate each capability.
+var (
+	geoIPMarkers            = []string{"geo-ip", "geoip", "geo_ip", "geo-blocking", "region-blocking", "region_blocking", "country-blocking", "country-restriction", "country_restriction"}
+	trafficRuleMarkers      = []string{"trafficrules"}
+	threatManagementMarkers = []string{"threat-management", "threatmanagement", "threat_management"}
+	firewallRuleMarkers     = []string{"rest/firewallrule"}
+)
+
+// detectCapabilities derives the capabilities from the found endpoints,
+// the firewall group types, and, if analyzed, the settings keys. It must run
+// after analyzeSettings and detectFirewallGroupTypes.
+func detectCapabilities(dr *DiscoveryResult) *Capabilities {
+	c := &Capabilities{}
+	note := func(field *bool, name, from string) {
+		*field = true
+		c.Evidence = append(c.Evidence, fmt.Sprintf("%s: %s", name, from))
+	}
+
+	for _, ep := range dr.Endpoints {
+		path := strings.ToLower(ep.Path)
+		if containsAny(path, geoIPMarkers) {
+			note(&c.SupportsGeoIP, "supports_geo_ip", ep.Path+" responded")
+		}
+		if containsAny(path, trafficRuleMarkers) {
+			note(&c.HasTrafficRules, "has_traffic_rules", ep.Path+" responded")
+		}
+		if containsAny(path, threatManagementMarkers) {
+			note(&c.HasThreatManagement, "has_threat_management", ep.Path+" responded")
+		}
+		if containsAny(path, firewallRuleMarkers) {
+			note(&c.HasFirewallRules, "has_firewall_rules", ep.Path+" responded")
+		}
+	}
+
+	for _, t := range dr.FirewallGroupTypes {
+		if t == unifi.FirewallGroupCountry {
+			note(&c.HasCountryGroups, "has_country_groups", "firewall group types "+strings.Join(dr.FirewallGroupTypes, ", "))
+		}
+	}
+
+	if dr.RegionBlocking != nil && dr.RegionBlocking.EndpointFound && !c.SupportsGeoIP {
+		note(&c.SupportsGeoIP, "supports_geo_ip", dr.RegionBlocking.Endpoint+" returned geo data")
+	}
+
+	if sa := dr.SettingsAnalysis; sa != nil {
+		for _, key := range sa.Keys {
+			lower := strings.ToLower(key)
+			if containsAny(lower, geoIPMarkers) {
+				note(&c.SupportsGeoIP, "supports_geo_ip", "setting key "+key)
+			}
+			if containsAny(lower, threatManagementMarkers) {
+				note(&c.HasThreatManagement, "has_threat_management", "setting key "+key)
+			}
+		}
+		if !c.HasThreatManagement && len(sa.ThreatKeys) > 0 {
+			note(&c.HasThreatManagement, "has_threat_management", "threat setting keys "+strings.Join(sa.ThreatKeys, ", "))
+		}
+	}
+
+	return c
+}
+
+// detectFirewallGroupTypes records the firewall group types the controller
+// supports. A controller without rest/firewallgroup just has none.
+func detectFirewallGroupTypes(client *unifi.Client, dr *DiscoveryResult, verbose bool) {
+	types, err := client.FirewallGroupTypes()
+	if err != nil {
+		if verbose {
+			console.Printf("Could not check firewall group types: %v\n", err)
+		}
+		return
+	}
+	dr.FirewallGroupTypes = types
+}
+
+// containsAny reports whether s contains any of the fragments.
+func containsAny(s string, fragments []string) bool {
+	for _, f := range fragments {
+		if strings.Contains(s, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// yesNo renders a capability for the summary.
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
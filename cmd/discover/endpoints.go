@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadEndpointFile reads candidate paths for -endpoint-file, one per line.
+// Blank lines and lines starting with "#" are skipped. Paths take the same
+// forms as the built-in lists, a leading "/" is dropped so they compare
+// equal to built-in paths, and {site} is the only placeholder.
+func loadEndpointFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open endpoint file: %w", err)
+	}
+	defer f.Close()
+
+	var endpoints []string
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest := strings.ReplaceAll(line, "{site}", ""); strings.ContainsAny(rest, "{}") {
+			return nil, fmt.Errorf("%s line %d: unknown placeholder in %q (only {site} is supported)", path, lineNum, line)
+		}
+		endpoints = append(endpoints, strings.TrimPrefix(line, "/"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read endpoint file: %w", err)
+	}
+
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("%s lists no endpoints", path)
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Keyword sets used to classify discovery results. path marks endpoints
+// that may hold region blocking, geo also matches their response data and
+// setting keys, and security and threat classify setting keys.
+const (
+	keywordsPath     = "path"
+	keywordsGeo      = "geo"
+	keywordsSecurity = "security"
+	keywordsThreat   = "threat"
+)
+
+// keywordSets maps a set name to its lowercase keywords.
+type keywordSets map[string][]string
+
+// defaultKeywords returns the built-in keyword sets.
+func defaultKeywords() keywordSets {
+	return keywordSets{
+		keywordsPath:     {"geo", "region", "country", "block", "restrict", "cybersecure", "threat"},
+		keywordsGeo:      {"geo", "region", "country", "block"},
+		keywordsSecurity: {"security", "firewall", "threat", "cybersecure"},
+		keywordsThreat:   {"threat", "ips", "ids", "malware"},
+	}
+}
+
+// match reports whether s contains any keyword of the named set, ignoring
+// case.
+func (k keywordSets) match(set, s string) bool {
+	s = strings.ToLower(s)
+	for _, kw := range k[set] {
+		if strings.Contains(s, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply changes the sets as described by one keyword spec:
+//
+//	SET=kw,kw   replaces SET's keywords
+//	SET+=kw,kw  adds to SET's keywords
+//	kw,kw       adds to the path and geo sets
+func (k keywordSets) apply(spec string) error {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil
+	}
+
+	name, list, found := strings.Cut(spec, "=")
+	if !found {
+		k.add(keywordsPath, splitKeywords(spec))
+		k.add(keywordsGeo, splitKeywords(spec))
+		return nil
+	}
+
+	extend := strings.HasSuffix(name, "+")
+	name = strings.ToLower(strings.TrimSpace(strings.TrimSuffix(name, "+")))
+	if _, ok := k[name]; !ok {
+		return fmt.Errorf("unknown keyword set %q (known: %s)", name, strings.Join(k.names(), ", "))
+	}
+
+	words := splitKeywords(list)
+	if extend {
+		k.add(name, words)
+	} else {
+		if len(words) == 0 {
+			return fmt.Errorf("keyword set %s can't be empty", name)
+		}
+		k[name] = words
+	}
+	return nil
+}
+
+// add appends the words not already in set.
+func (k keywordSets) add(set string, words []string) {
+	for _, w := range words {
+		known := false
+		for _, existing := range k[set] {
+			if existing == w {
+				known = true
+				break
+			}
+		}
+		if !known {
+			k[set] = append(k[set], w)
+		}
+	}
+}
+
+// names returns the set names in sorted order.
+func (k keywordSets) names() []string {
+	names := make([]string, 0, len(k))
+	for name := range k {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadKeywords builds the keyword sets: the defaults, then each line of
+// file (if any), then each flag spec, so flags win over the file.
+func loadKeywords(file string, specs []string) (keywordSets, error) {
+	k := defaultKeywords()
+
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open keywords file: %w", err)
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		lineNum := 0
+		for scanner.Scan() {
+			lineNum++
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if err := k.apply(line); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", file, lineNum, err)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read keywords file: %w", err)
+		}
+	}
+
+	for _, spec := range specs {
+		if err := k.apply(spec); err != nil {
+			return nil, fmt.Errorf("-keywords %q: %w", spec, err)
+		}
+	}
+
+	return k, nil
+}
+
+// splitKeywords splits a comma-separated list into lowercase keywords.
+func splitKeywords(s string) []string {
+	var words []string
+	for _, w := range strings.Split(s, ",") {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// stringList is a flag that collects every value when repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
@@ -6,12 +6,19 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/mattsblocklist/tae/internal/concurrency"
+	"github.com/mattsblocklist/tae/internal/config"
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/exitcode"
+	"github.com/mattsblocklist/tae/internal/rundir"
 	"github.com/mattsblocklist/tae/internal/unifi"
 )
 
@@ -21,9 +28,28 @@ type DiscoveryResult struct {
 	Site             string                  `json:"site"`
 	TotalTested      int                     `json:"total_tested"`
 	FoundEndpoints   int                     `json:"found_endpoints"`
+	Capabilities     *Capabilities           `json:"capabilities,omitempty"`
 	Endpoints        []*unifi.EndpointResult `json:"endpoints"`
+	FoundBySource    map[string]int          `json:"found_by_source,omitempty"`
 	RegionBlocking   *RegionBlockingInfo     `json:"region_blocking,omitempty"`
 	SettingsAnalysis *SettingsAnalysis       `json:"settings_analysis,omitempty"`
+	// FirewallGroupTypes are the firewall group types the controller
+	// supports; see unifi.Client.FirewallGroupTypes
+	FirewallGroupTypes []string `json:"firewall_group_types,omitempty"`
+	// Relogins counts how often the session expired mid-run and was
+	// renewed; endpoints tested afterwards are marked after_reauth
+	Relogins int `json:"relogins,omitempty"`
+}
+
+// RunInfo records the timing of a discovery run for -output-dir.
+type RunInfo struct {
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	ControllerURL   string    `json:"controller_url"`
+	Site            string    `json:"site"`
+	TotalTested     int       `json:"total_tested"`
+	FoundEndpoints  int       `json:"found_endpoints"`
 }
 
 type RegionBlockingInfo struct {
@@ -47,12 +73,28 @@ func main() {
 	password := flag.String("password", "", "UniFi password")
 	site := flag.String("site", "default", "UniFi site name")
 	insecure := flag.Bool("insecure", false, "Skip TLS certificate verification")
-	output := flag.String("output", "", "Output file path (JSON format)")
+	caCert := flag.String("ca-cert", "", "PEM file of CA certificates to trust for the controller (or UNIFI_CA_CERT_FILE env)")
+	output := flag.String("output", "", "Output file path (JSON format; - = stdout)")
+	outputDir := flag.String("output-dir", "", "Write discovery.json and run.json to a timestamped directory under this path")
 	verbose := flag.Bool("verbose", false, "Enable verbose output")
-	workers := flag.Int("workers", 5, "Number of concurrent workers")
+	quiet := flag.Bool("quiet", false, "Only print errors and requested output; progress and summaries are suppressed")
+	workers := flag.Int("workers", 5, "Number of concurrent workers (0 = auto)")
 	regionOnly := flag.Bool("region-only", false, "Only test region blocking candidate endpoints")
+	endpointFile := flag.String("endpoint-file", "", "File of extra endpoint paths to test, one per line; {site} is replaced with the site name")
+	replaceEndpoints := flag.Bool("replace", false, "Test only the -endpoint-file paths instead of adding them to the built-in lists")
+	appendEndpoints := flag.Bool("append", false, "Add the -endpoint-file paths to the built-in lists (the default)")
+	var keywordSpecs stringList
+	flag.Var(&keywordSpecs, "keywords", "Classification keywords: \"SET=kw,...\" replaces a set (path, geo, security, threat), \"SET+=kw,...\" extends it, a bare list extends path and geo (repeatable)")
+	keywordsFile := flag.String("keywords-file", "", "File of -keywords specs, one per line, applied before any -keywords flags")
+	envFile := flag.String("env-file", config.DefaultEnvFile, "Read KEY=VALUE settings from this file (ignored if missing; real env vars take precedence)")
 
 	flag.Parse()
+	console.SetQuiet(*quiet)
+
+	if err := config.LoadDotEnv(*envFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading env file: %v\n", err)
+		os.Exit(exitcode.Usage)
+	}
 
 	// Validate required flags or try environment variables
 	if *host == "" {
@@ -64,15 +106,43 @@ func main() {
 	if *password == "" {
 		*password = os.Getenv("UNIFI_PASSWORD")
 	}
+	if *caCert == "" {
+		*caCert = os.Getenv("UNIFI_CA_CERT_FILE")
+	}
 
 	if *host == "" || *username == "" || *password == "" {
 		fmt.Fprintln(os.Stderr, "Error: host, username, and password are required")
 		fmt.Fprintln(os.Stderr, "Use flags or environment variables: UNIFI_HOST, UNIFI_USERNAME, UNIFI_PASSWORD")
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitcode.Usage)
+	}
+
+	keywords, err := loadKeywords(*keywordsFile, keywordSpecs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitcode.Usage)
+	}
+
+	if *replaceEndpoints && *appendEndpoints {
+		fmt.Fprintln(os.Stderr, "Error: -replace and -append can't be combined")
+		os.Exit(exitcode.Usage)
+	}
+	if (*replaceEndpoints || *appendEndpoints) && *endpointFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: -replace and -append need -endpoint-file")
+		os.Exit(exitcode.Usage)
 	}
+	var customEndpoints []string
+	if *endpointFile != "" {
+		customEndpoints, err = loadEndpointFile(*endpointFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitcode.Usage)
+		}
+	}
+
+	started := time.Now()
 
-	fmt.Printf("Connecting to UniFi controller at %s...\n", *host)
+	console.Printf("Connecting to UniFi controller at %s...\n", *host)
 
 	// Create client
 	client, err := unifi.NewClient(unifi.ClientConfig{
@@ -81,34 +151,42 @@ func main() {
 		Password:      *password,
 		Site:          *site,
 		SkipTLSVerify: *insecure,
+		CACertFile:    *caCert,
 		Verbose:       *verbose,
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to connect: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitcode.FromError(err))
 	}
 	defer client.Logout()
 
-	fmt.Println("Authentication successful!")
+	console.Println("Authentication successful!")
 
 	// Build list of endpoints to test
-	var endpoints []string
+	var endpoints []endpointCandidate
 	if *regionOnly {
-		endpoints = buildRegionBlockingEndpoints(*site)
-		fmt.Printf("Testing %d region blocking candidate endpoints...\n", len(endpoints))
+		endpoints = buildRegionBlockingEndpoints(client.Site(), customEndpoints, *replaceEndpoints)
+		console.Printf("Testing %d region blocking candidate endpoints...\n", len(endpoints))
 	} else {
-		endpoints = buildAllEndpoints(*site)
-		fmt.Printf("Testing %d endpoints...\n", len(endpoints))
+		endpoints = buildAllEndpoints(client.Site(), customEndpoints, *replaceEndpoints)
+		console.Printf("Testing %d endpoints...\n", len(endpoints))
 	}
 
 	// Test endpoints concurrently
-	results := testEndpoints(client, endpoints, *workers, *verbose)
+	results, relogins, err := testEndpoints(client, endpoints, *workers, *verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitcode.FromError(err))
+	}
 
 	// Analyze results
-	discoveryResult := analyzeResults(client, results, *site)
+	discoveryResult := analyzeResults(client, results, client.Site(), keywords)
+	discoveryResult.Relogins = relogins
 
 	// Analyze settings endpoint for geo-related keys
-	analyzeSettings(client, discoveryResult, *verbose)
+	analyzeSettings(client, discoveryResult, keywords, *verbose)
+	detectFirewallGroupTypes(client, discoveryResult, *verbose)
+	discoveryResult.Capabilities = detectCapabilities(discoveryResult)
 
 	// Output results
 	printSummary(discoveryResult)
@@ -116,62 +194,156 @@ func main() {
 	if *output != "" {
 		if err := saveResults(*output, discoveryResult); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to save results: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitcode.Failure)
+		}
+		if !console.IsStdout(*output) {
+			console.Printf("\nResults saved to %s\n", *output)
 		}
-		fmt.Printf("\nResults saved to %s\n", *output)
+	}
+
+	if *outputDir != "" {
+		dir, err := saveRunDir(*outputDir, discoveryResult, started)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save results: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+		console.Printf("\nResults saved to %s\n", dir)
 	}
 }
 
-func buildRegionBlockingEndpoints(site string) []string {
-	var endpoints []string
+// endpointCandidate is a path to test and the candidate lists it came from.
+type endpointCandidate struct {
+	Path    string
+	Sources []string
+}
 
-	for _, ep := range unifi.RegionBlockingCandidates {
-		ep = strings.ReplaceAll(ep, "{site}", site)
-		endpoints = append(endpoints, ep)
+// endpointSet collects candidates with {site} filled in. A path in several
+// lists is tested once, tagged with each list.
+type endpointSet struct {
+	site      string
+	index     map[string]int
+	endpoints []endpointCandidate
+}
+
+func newEndpointSet(site string) *endpointSet {
+	return &endpointSet{site: site, index: make(map[string]int)}
+}
+
+func (s *endpointSet) add(paths []string, source string) {
+	for _, ep := range paths {
+		ep = strings.ReplaceAll(ep, "{site}", s.site)
+		i, ok := s.index[ep]
+		if !ok {
+			s.index[ep] = len(s.endpoints)
+			s.endpoints = append(s.endpoints, endpointCandidate{Path: ep, Sources: []string{source}})
+			continue
+		}
+		known := false
+		for _, existing := range s.endpoints[i].Sources {
+			if existing == source {
+				known = true
+				break
+			}
+		}
+		if !known {
+			s.endpoints[i].Sources = append(s.endpoints[i].Sources, source)
+		}
 	}
+}
 
-	return endpoints
+// buildRegionBlockingEndpoints returns the region blocking candidates plus
+// custom, or only custom if replace is set.
+func buildRegionBlockingEndpoints(site string, custom []string, replace bool) []endpointCandidate {
+	set := newEndpointSet(site)
+	if !replace {
+		set.add(unifi.RegionBlockingCandidates, unifi.EndpointSourceRegionBlocking)
+	}
+	set.add(custom, unifi.EndpointSourceFile)
+	return set.endpoints
 }
 
-func buildAllEndpoints(site string) []string {
-	seen := make(map[string]bool)
-	var endpoints []string
+// buildAllEndpoints returns every built-in candidate list plus custom, or
+// only custom if replace is set.
+func buildAllEndpoints(site string, custom []string, replace bool) []endpointCandidate {
+	set := newEndpointSet(site)
+	if !replace {
+		set.add(unifi.KnownEndpoints, unifi.EndpointSourceKnown)
+		set.add(unifi.V2Endpoints, unifi.EndpointSourceV2)
+		set.add(unifi.RegionBlockingCandidates, unifi.EndpointSourceRegionBlocking)
+	}
+	set.add(custom, unifi.EndpointSourceFile)
+	return set.endpoints
+}
 
-	addEndpoint := func(ep string) {
-		ep = strings.ReplaceAll(ep, "{site}", site)
-		if !seen[ep] {
-			seen[ep] = true
-			endpoints = append(endpoints, ep)
+// testEndpoints tests every candidate and returns the results sorted by
+// path, and how often the session had to be renewed. A 401 sent before the
+// last renewal may only mean the session had expired, and a 429 only that
+// the controller was busy, so those endpoints are tested again at the end.
+// It fails if the session expired and logging in again didn't work.
+func testEndpoints(client *unifi.Client, endpoints []endpointCandidate, workerCount int, verbose bool) ([]*unifi.EndpointResult, int, error) {
+	guard := &sessionGuard{client: client}
+	tested := runEndpointTests(guard, endpoints, workerCount, verbose)
+
+	var results []*unifi.EndpointResult
+	var retry []endpointCandidate
+	rateLimited := 0
+	for _, t := range tested {
+		switch {
+		case t.result.StatusCode == http.StatusUnauthorized && t.epoch < guard.epoch:
+			retry = append(retry, endpointCandidate{Path: t.result.Path, Sources: t.result.Sources})
+		case t.result.StatusCode == http.StatusTooManyRequests:
+			retry = append(retry, endpointCandidate{Path: t.result.Path, Sources: t.result.Sources})
+			rateLimited++
+		default:
+			results = append(results, t.result)
 		}
 	}
 
-	// Add known endpoints
-	for _, ep := range unifi.KnownEndpoints {
-		addEndpoint(ep)
+	if len(retry) > 0 && guard.failed() == nil {
+		if rateLimited > 0 {
+			console.Printf("Controller rate limited %d requests; retrying in %s\n", rateLimited, rateLimitPause)
+			time.Sleep(rateLimitPause)
+		}
+		console.Printf("Testing %d endpoints again...\n", len(retry))
+		for _, t := range runEndpointTests(guard, retry, workerCount, verbose) {
+			results = append(results, t.result)
+		}
 	}
 
-	// Add v2 endpoints
-	for _, ep := range unifi.V2Endpoints {
-		addEndpoint(ep)
+	if err := guard.failed(); err != nil {
+		return nil, guard.epoch, fmt.Errorf("session expired during discovery: %w", err)
 	}
 
-	// Add region blocking candidates
-	for _, ep := range unifi.RegionBlockingCandidates {
-		addEndpoint(ep)
-	}
+	// Sort by path
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+
+	return results, guard.epoch, nil
+}
 
-	return endpoints
+// testedEndpoint is a result and the session epoch it was obtained in.
+type testedEndpoint struct {
+	result *unifi.EndpointResult
+	epoch  int
 }
 
-func testEndpoints(client *unifi.Client, endpoints []string, workerCount int, verbose bool) []*unifi.EndpointResult {
+// runEndpointTests tests endpoints concurrently through guard, in no
+// particular order.
+func runEndpointTests(guard *sessionGuard, endpoints []endpointCandidate, workerCount int, verbose bool) []testedEndpoint {
 	var (
 		wg      sync.WaitGroup
 		mu      sync.Mutex
-		results []*unifi.EndpointResult
+		results []testedEndpoint
 	)
 
+	// All requests go to the one controller, so auto sizing stays modest
+	if workerCount <= 0 {
+		workerCount = concurrency.AutoWorkers(len(endpoints), 4, 10)
+	}
+
 	// Create work channel
-	work := make(chan string, len(endpoints))
+	work := make(chan endpointCandidate, len(endpoints))
 	for _, ep := range endpoints {
 		work <- ep
 	}
@@ -182,27 +354,31 @@ func testEndpoints(client *unifi.Client, endpoints []string, workerCount int, ve
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for ep := range work {
-				result, err := client.TestEndpoint(ep)
+			for candidate := range work {
+				ep := candidate.Path
+				result, epoch, err := guard.test(ep)
 				if err != nil {
-					if verbose {
-						fmt.Printf("  [ERROR] %s: %v\n", ep, err)
+					if verbose && guard.failed() == nil {
+						console.Printf("  [ERROR] %s: %v\n", ep, err)
 					}
 					continue
 				}
+				result.Sources = candidate.Sources
+				result.AfterReauth = epoch > 0
+				guard.observe(result, epoch)
 
 				mu.Lock()
-				results = append(results, result)
+				results = append(results, testedEndpoint{result: result, epoch: epoch})
 				mu.Unlock()
 
 				if verbose {
 					if result.Exists {
-						fmt.Printf("  [FOUND] %s (status: %d, size: %d)\n", ep, result.StatusCode, result.ResponseSize)
+						console.Printf("  [FOUND] %s [%s] (status: %d, size: %d) -> %s\n", ep, strings.Join(result.Sources, ","), result.StatusCode, result.ResponseSize, result.FullURL)
 					} else {
-						fmt.Printf("  [MISS]  %s (status: %d)\n", ep, result.StatusCode)
+						console.Printf("  [MISS]  %s (status: %d) -> %s\n", ep, result.StatusCode, result.FullURL)
 					}
 				} else if result.Exists {
-					fmt.Printf("  Found: %s\n", ep)
+					console.Printf("  Found: %s\n", ep)
 				}
 			}
 		}()
@@ -210,15 +386,10 @@ func testEndpoints(client *unifi.Client, endpoints []string, workerCount int, ve
 
 	wg.Wait()
 
-	// Sort by path
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Path < results[j].Path
-	})
-
 	return results
 }
 
-func analyzeResults(client *unifi.Client, results []*unifi.EndpointResult, site string) *DiscoveryResult {
+func analyzeResults(client *unifi.Client, results []*unifi.EndpointResult, site string, keywords keywordSets) *DiscoveryResult {
 	dr := &DiscoveryResult{
 		Timestamp:     time.Now(),
 		ControllerURL: client.BaseURL(),
@@ -238,82 +409,51 @@ func analyzeResults(client *unifi.Client, results []*unifi.EndpointResult, site
 	dr.FoundEndpoints = len(foundEndpoints)
 	dr.Endpoints = foundEndpoints
 
+	// Count hits per candidate list; a path in several lists counts in each
+	dr.FoundBySource = make(map[string]int)
+	for _, ep := range foundEndpoints {
+		for _, source := range ep.Sources {
+			dr.FoundBySource[source]++
+		}
+	}
+
 	// Look for region blocking indicators in found endpoints
-	geoKeywords := []string{"geo", "region", "country", "block", "restrict", "cybersecure", "threat"}
 	for _, ep := range foundEndpoints {
-		pathLower := strings.ToLower(ep.Path)
-		for _, kw := range geoKeywords {
-			if strings.Contains(pathLower, kw) {
-				// Check if response contains country/region data
-				if strings.Contains(ep.ResponseSample, "country") ||
-					strings.Contains(ep.ResponseSample, "geo") ||
-					strings.Contains(ep.ResponseSample, "region") ||
-					strings.Contains(ep.ResponseSample, "block") {
-					dr.RegionBlocking.EndpointFound = true
-					dr.RegionBlocking.Endpoint = ep.Path
-					dr.RegionBlocking.Notes = "Found endpoint with geo-related response data"
-					break
-				}
-			}
+		// Check if response contains country/region data
+		if keywords.match(keywordsPath, ep.Path) && keywords.match(keywordsGeo, ep.ResponseSample) {
+			dr.RegionBlocking.EndpointFound = true
+			dr.RegionBlocking.Endpoint = ep.Path
+			dr.RegionBlocking.Notes = "Found endpoint with geo-related response data"
 		}
 	}
 
 	return dr
 }
 
-func analyzeSettings(client *unifi.Client, dr *DiscoveryResult, verbose bool) {
+func analyzeSettings(client *unifi.Client, dr *DiscoveryResult, keywords keywordSets, verbose bool) {
 	// Fetch the settings endpoint to look for geo-related configuration
-	body, status, err := client.Get("rest/setting")
-	if err != nil || status != 200 {
+	settings, err := client.GetAllSettings()
+	if err != nil {
 		if verbose {
-			fmt.Printf("Could not analyze settings endpoint: %v (status: %d)\n", err, status)
+			console.Printf("Could not analyze settings endpoint: %v\n", err)
 		}
 		return
 	}
 
-	var settings []map[string]interface{}
-	if err := json.Unmarshal(body, &settings); err != nil {
-		// Try alternate format
-		var wrapper struct {
-			Data []map[string]interface{} `json:"data"`
-		}
-		if err := json.Unmarshal(body, &wrapper); err != nil {
-			if verbose {
-				fmt.Printf("Could not parse settings: %v\n", err)
-			}
-			return
-		}
-		settings = wrapper.Data
-	}
-
 	analysis := &SettingsAnalysis{}
 
-	geoKeywords := []string{"geo", "region", "country", "block"}
-	securityKeywords := []string{"security", "firewall", "threat", "cybersecure"}
-	threatKeywords := []string{"threat", "ips", "ids", "malware"}
-
 	for _, s := range settings {
 		if key, ok := s["key"].(string); ok {
 			analysis.Keys = append(analysis.Keys, key)
 
-			keyLower := strings.ToLower(key)
-			for _, kw := range geoKeywords {
-				if strings.Contains(keyLower, kw) {
-					analysis.GeoRelated = append(analysis.GeoRelated, key)
-					break
-				}
+			if keywords.match(keywordsGeo, key) {
+				analysis.GeoRelated = append(analysis.GeoRelated, key)
 			}
-			for _, kw := range securityKeywords {
-				if strings.Contains(keyLower, kw) {
-					analysis.SecurityKeys = append(analysis.SecurityKeys, key)
-					break
-				}
+			if keywords.match(keywordsSecurity, key) {
+				analysis.SecurityKeys = append(analysis.SecurityKeys, key)
 			}
-			for _, kw := range threatKeywords {
-				if strings.Contains(keyLower, kw) {
-					analysis.ThreatKeys = append(analysis.ThreatKeys, key)
-					break
-				}
+			if keywords.match(keywordsThreat, key) {
+				analysis.ThreatKeys = append(analysis.ThreatKeys, key)
 			}
 		}
 	}
@@ -329,70 +469,131 @@ func analyzeSettings(client *unifi.Client, dr *DiscoveryResult, verbose bool) {
 }
 
 func printSummary(dr *DiscoveryResult) {
-	fmt.Println("\n" + strings.Repeat("=", 60))
-	fmt.Println("DISCOVERY SUMMARY")
-	fmt.Println(strings.Repeat("=", 60))
+	console.Println("\n" + strings.Repeat("=", 60))
+	console.Println("DISCOVERY SUMMARY")
+	console.Println(strings.Repeat("=", 60))
+
+	console.Printf("Controller: %s\n", dr.ControllerURL)
+	console.Printf("Site: %s\n", dr.Site)
+	console.Printf("Endpoints tested: %d\n", dr.TotalTested)
+	console.Printf("Endpoints found: %d\n", dr.FoundEndpoints)
+	if dr.Relogins > 0 {
+		console.Printf("Session expired and was renewed %d times; endpoints marked after_reauth were tested in a new session, and earlier 401s were tested again\n", dr.Relogins)
+	}
 
-	fmt.Printf("Controller: %s\n", dr.ControllerURL)
-	fmt.Printf("Site: %s\n", dr.Site)
-	fmt.Printf("Endpoints tested: %d\n", dr.TotalTested)
-	fmt.Printf("Endpoints found: %d\n", dr.FoundEndpoints)
+	if c := dr.Capabilities; c != nil {
+		console.Println("\nCapabilities:")
+		console.Printf("  Geo-IP / region blocking: %s\n", yesNo(c.SupportsGeoIP))
+		console.Printf("  Traffic rules:            %s\n", yesNo(c.HasTrafficRules))
+		console.Printf("  Threat management:        %s\n", yesNo(c.HasThreatManagement))
+		console.Printf("  Firewall rules:           %s\n", yesNo(c.HasFirewallRules))
+		console.Printf("  Country firewall groups:  %s\n", yesNo(c.HasCountryGroups))
+	}
 
 	if dr.FoundEndpoints > 0 {
-		fmt.Println("\nFound endpoints:")
+		console.Printf("Found by source: %s\n", formatSourceCounts(dr.FoundBySource))
+
+		console.Println("\nFound endpoints:")
 		for _, ep := range dr.Endpoints {
-			fmt.Printf("  - %s (size: %d bytes)\n", ep.Path, ep.ResponseSize)
+			console.Printf("  - %s [%s] (%s, size: %d bytes)\n", ep.Path, strings.Join(ep.Sources, ","), ep.Scope, ep.ResponseSize)
 		}
 	}
 
-	fmt.Println("\n" + strings.Repeat("-", 60))
-	fmt.Println("REGION BLOCKING ANALYSIS")
-	fmt.Println(strings.Repeat("-", 60))
+	console.Println("\n" + strings.Repeat("-", 60))
+	console.Println("REGION BLOCKING ANALYSIS")
+	console.Println(strings.Repeat("-", 60))
 
 	if dr.RegionBlocking.EndpointFound {
-		fmt.Printf("Status: FOUND\n")
-		fmt.Printf("Endpoint: %s\n", dr.RegionBlocking.Endpoint)
+		console.Printf("Status: FOUND\n")
+		console.Printf("Endpoint: %s\n", dr.RegionBlocking.Endpoint)
 		if dr.RegionBlocking.Notes != "" {
-			fmt.Printf("Notes: %s\n", dr.RegionBlocking.Notes)
+			console.Printf("Notes: %s\n", dr.RegionBlocking.Notes)
 		}
 	} else {
-		fmt.Println("Status: NOT FOUND (may require UI capture)")
-		fmt.Println("Recommendation: Use browser DevTools to capture the API call")
-		fmt.Println("when toggling Region Blocking in Settings -> CyberSecure")
+		console.Println("Status: NOT FOUND (may require UI capture)")
+		console.Println("Recommendation: Use browser DevTools to capture the API call")
+		console.Println("when toggling Region Blocking in Settings -> CyberSecure")
 	}
 
 	if dr.SettingsAnalysis != nil {
-		fmt.Println("\n" + strings.Repeat("-", 60))
-		fmt.Println("SETTINGS ANALYSIS")
-		fmt.Println(strings.Repeat("-", 60))
+		console.Println("\n" + strings.Repeat("-", 60))
+		console.Println("SETTINGS ANALYSIS")
+		console.Println(strings.Repeat("-", 60))
 
-		fmt.Printf("Total setting keys: %d\n", len(dr.SettingsAnalysis.Keys))
+		console.Printf("Total setting keys: %d\n", len(dr.SettingsAnalysis.Keys))
 
 		if len(dr.SettingsAnalysis.GeoRelated) > 0 {
-			fmt.Printf("Geo-related keys: %v\n", dr.SettingsAnalysis.GeoRelated)
+			console.Printf("Geo-related keys: %v\n", dr.SettingsAnalysis.GeoRelated)
 		}
 		if len(dr.SettingsAnalysis.SecurityKeys) > 0 {
-			fmt.Printf("Security keys: %v\n", dr.SettingsAnalysis.SecurityKeys)
+			console.Printf("Security keys: %v\n", dr.SettingsAnalysis.SecurityKeys)
 		}
 		if len(dr.SettingsAnalysis.ThreatKeys) > 0 {
-			fmt.Printf("Threat keys: %v\n", dr.SettingsAnalysis.ThreatKeys)
+			console.Printf("Threat keys: %v\n", dr.SettingsAnalysis.ThreatKeys)
 		}
 
 		// Print all keys if verbose
 		if len(dr.SettingsAnalysis.Keys) > 0 {
-			fmt.Println("\nAll setting keys:")
+			console.Println("\nAll setting keys:")
 			for _, k := range dr.SettingsAnalysis.Keys {
-				fmt.Printf("  - %s\n", k)
+				console.Printf("  - %s\n", k)
 			}
 		}
 	}
 }
 
+// formatSourceCounts renders per-list hit counts, region blocking first.
+func formatSourceCounts(counts map[string]int) string {
+	sources := []string{unifi.EndpointSourceRegionBlocking, unifi.EndpointSourceKnown, unifi.EndpointSourceV2}
+	parts := make([]string, 0, len(sources))
+	for _, source := range sources {
+		parts = append(parts, fmt.Sprintf("%s=%d", source, counts[source]))
+	}
+	return strings.Join(parts, " ")
+}
+
 func saveResults(path string, dr *DiscoveryResult) error {
 	data, err := json.MarshalIndent(dr, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	return console.WriteFile(path, data, 0644)
+}
+
+// saveRunDir writes discovery.json and run.json into a new timestamped
+// directory under base and points the latest link at it.
+func saveRunDir(base string, dr *DiscoveryResult, started time.Time) (string, error) {
+	dir, err := rundir.Create(base, started)
+	if err != nil {
+		return "", err
+	}
+
+	if err := saveResults(filepath.Join(dir, "discovery.json"), dr); err != nil {
+		return "", err
+	}
+
+	finished := time.Now()
+	info := RunInfo{
+		StartedAt:       started,
+		FinishedAt:      finished,
+		DurationSeconds: finished.Sub(started).Seconds(),
+		ControllerURL:   dr.ControllerURL,
+		Site:            dr.Site,
+		TotalTested:     dr.TotalTested,
+		FoundEndpoints:  dr.FoundEndpoints,
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "run.json"), data, 0644); err != nil {
+		return "", err
+	}
+
+	if err := rundir.UpdateLatest(base, dir); err != nil {
+		return "", err
+	}
+
+	return dir, nil
 }
 
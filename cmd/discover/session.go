@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/unifi"
+)
+
+// sessionExpiryRun is how many 401s in a row make discover check whether
+// the session expired mid-run, rather than every endpoint being protected.
+const sessionExpiryRun = 3
+
+// rateLimitPause is how long discover waits before testing rate-limited
+// endpoints again.
+const rateLimitPause = 5 * time.Second
+
+// sessionGuard notices a session expiring partway through a run. Requests
+// hold it shared; after a run of 401s one worker takes it exclusively,
+// checks the session, and logs in again if it expired, so no request is
+// sent mid-login. Each relogin starts a new epoch.
+type sessionGuard struct {
+	client *unifi.Client
+
+	rw    sync.RWMutex
+	epoch int
+	err   error // a relogin failed; testing stops
+
+	mu  sync.Mutex
+	run int // consecutive 401s
+}
+
+// test runs TestEndpoint under the guard and returns the epoch it ran in.
+func (g *sessionGuard) test(path string) (*unifi.EndpointResult, int, error) {
+	g.rw.RLock()
+	defer g.rw.RUnlock()
+	if g.err != nil {
+		return nil, g.epoch, g.err
+	}
+	result, err := g.client.TestEndpoint(path)
+	return result, g.epoch, err
+}
+
+// observe counts 401s across workers and, after sessionExpiryRun of them,
+// checks the session the last one was sent with.
+func (g *sessionGuard) observe(result *unifi.EndpointResult, epoch int) {
+	g.mu.Lock()
+	if result.StatusCode != http.StatusUnauthorized {
+		g.run = 0
+		g.mu.Unlock()
+		return
+	}
+	g.run++
+	check := g.run >= sessionExpiryRun
+	if check {
+		g.run = 0
+	}
+	g.mu.Unlock()
+
+	if check {
+		g.renew(epoch)
+	}
+}
+
+// renew logs in again if the session from epoch has expired and no other
+// worker has renewed it already.
+func (g *sessionGuard) renew(epoch int) {
+	g.rw.Lock()
+	defer g.rw.Unlock()
+	if g.epoch != epoch || g.err != nil {
+		return
+	}
+
+	// A controller that can't be asked can't be judged either; carry on
+	if valid, err := g.client.SessionValid(); err != nil || valid {
+		return
+	}
+
+	console.Println("Session expired during discovery; logging in again")
+	if err := g.client.Relogin(); err != nil {
+		g.err = err
+		return
+	}
+	g.epoch++
+}
+
+// failed returns the relogin error that stopped testing, if any.
+func (g *sessionGuard) failed() error {
+	g.rw.RLock()
+	defer g.rw.RUnlock()
+	return g.err
+}
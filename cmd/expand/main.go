@@ -0,0 +1,130 @@
+// Command expand converts the aggregated country list into CIDR ranges using
+// a GeoIP database, for firewalls that can't filter by country code.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/exitcode"
+	"github.com/mattsblocklist/tae/internal/geoip"
+
+	"github.com/mattsblocklist/tae/internal/console"
+)
+
+func main() {
+	inputFile := flag.String("input", "data/blocked_countries.txt", "Input file with country codes")
+	output := flag.String("output", "data/blocked_cidrs.txt", "Output file (one CIDR per line; - = stdout)")
+	dbipCSV := flag.String("dbip-csv", "", "Path to a db-ip.com country CSV (start_ip,end_ip,country)")
+	geoliteBlocks := flag.String("geolite2-blocks", "", "Comma-separated MaxMind GeoLite2 Country Blocks CSVs (IPv4 and/or IPv6)")
+	geoliteLocations := flag.String("geolite2-locations", "", "MaxMind GeoLite2 Country Locations CSV")
+	family := flag.String("family", "all", "Address family to output: all, ipv4, or ipv6")
+	verbose := flag.Bool("verbose", false, "Enable verbose output")
+	quiet := flag.Bool("quiet", false, "Only print errors and requested output; progress and summaries are suppressed")
+
+	flag.Parse()
+	console.SetQuiet(*quiet)
+
+	var (
+		src *geoip.Table
+		err error
+	)
+	switch {
+	case *dbipCSV != "":
+		src, err = geoip.LoadDBIPCSV(*dbipCSV)
+	case *geoliteBlocks != "" && *geoliteLocations != "":
+		src, err = geoip.LoadGeoLite2CSV(strings.Split(*geoliteBlocks, ","), *geoliteLocations)
+	default:
+		fmt.Fprintln(os.Stderr, "Error: a GeoIP database is required (-dbip-csv, or -geolite2-blocks with -geolite2-locations)")
+		flag.Usage()
+		os.Exit(exitcode.Usage)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading GeoIP database: %v\n", err)
+		os.Exit(exitcode.Usage)
+	}
+
+	codes, err := readCodes(*inputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading country codes: %v\n", err)
+		os.Exit(exitcode.Usage)
+	}
+
+	console.Printf("Loaded %d country codes, GeoIP database covers %d countries\n", len(codes), src.Countries())
+
+	prefixes, missing := geoip.Expand(src, codes)
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "  [WARN] No ranges found for: %s\n", strings.Join(missing, ", "))
+	}
+
+	var v4, v6 int
+	var lines []string
+	for _, p := range prefixes {
+		if !includeFamily(p, *family) {
+			continue
+		}
+		if p.Addr().Is4() {
+			v4++
+		} else {
+			v6++
+		}
+		lines = append(lines, p.String())
+	}
+
+	if *verbose {
+		console.Printf("IPv4 prefixes: %d, IPv6 prefixes: %d\n", v4, v6)
+	}
+
+	var b strings.Builder
+	b.WriteString("# CIDR ranges for blocked countries\n")
+	b.WriteString("# Generated: " + time.Now().Format("2006-01-02 15:04:05 MST") + "\n")
+	b.WriteString("# Countries: " + strings.Join(codes, ",") + "\n")
+	b.WriteString("#\n")
+	b.WriteString(strings.Join(lines, "\n"))
+	b.WriteString("\n")
+
+	if err := console.WriteFile(*output, []byte(b.String()), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(exitcode.Failure)
+	}
+
+	if !console.IsStdout(*output) {
+		console.Printf("Wrote %d prefixes to %s\n", len(lines), *output)
+	}
+}
+
+func includeFamily(p netip.Prefix, family string) bool {
+	switch family {
+	case "ipv4":
+		return p.Addr().Is4()
+	case "ipv6":
+		return p.Addr().Is6()
+	default:
+		return true
+	}
+}
+
+// readCodes reads alpha-2 codes from a text list, skipping comments and blanks.
+func readCodes(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer f.Close()
+
+	var codes []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") && len(line) == 2 {
+			codes = append(codes, strings.ToUpper(line))
+		}
+	}
+
+	return codes, scanner.Err()
+}
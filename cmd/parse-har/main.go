@@ -2,12 +2,18 @@
 package main
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/exitcode"
 )
 
 type HAR struct {
@@ -15,6 +21,7 @@ type HAR struct {
 }
 
 type Log struct {
+	Version string  `json:"version"`
 	Entries []Entry `json:"entries"`
 }
 
@@ -31,7 +38,7 @@ type Request struct {
 }
 
 type Response struct {
-	Status  int      `json:"status"`
+	Status  harInt   `json:"status"`
 	Headers []Header `json:"headers"`
 	Content Content  `json:"content"`
 }
@@ -42,20 +49,50 @@ type Header struct {
 }
 
 type PostData struct {
-	MimeType string `json:"mimeType"`
-	Text     string `json:"text"`
+	MimeType string  `json:"mimeType"`
+	Text     string  `json:"text"`
+	Params   []Param `json:"params,omitempty"`
+}
+
+// Param is a posted form field. HAR 1.1 writers may give only these for a
+// form post, without text.
+type Param struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
+// Content is a response body. HAR 1.2 added encoding: Chrome and Safari
+// save binary (and sometimes JSON) bodies base64-encoded.
 type Content struct {
-	Size     int    `json:"size"`
+	Size     harInt `json:"size"`
 	MimeType string `json:"mimeType"`
 	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// harInt is a HAR number written by any browser: an integer, a float
+// (e.g. 1234.0), a numeric string, or null.
+type harInt int
+
+func (n *harInt) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		*n = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %s", data)
+	}
+	*n = harInt(f)
+	return nil
 }
 
 type APIEndpoint struct {
 	URL          string            `json:"url"`
 	Method       string            `json:"method"`
 	Status       int               `json:"status"`
+	Count        int               `json:"count"`
 	Headers      map[string]string `json:"headers"`
 	RequestBody  string            `json:"request_body,omitempty"`
 	ResponseBody string            `json:"response_body,omitempty"`
@@ -72,34 +109,45 @@ type AnalysisResult struct {
 
 func main() {
 	harFile := flag.String("har", "", "Path to HAR file")
-	output := flag.String("output", "api-endpoints.json", "Output file")
+	output := flag.String("output", "api-endpoints.json", "Output file (- = stdout)")
 	verbose := flag.Bool("verbose", false, "Verbose output")
+	quiet := flag.Bool("quiet", false, "Only print errors and requested output; progress and summaries are suppressed")
 	flag.Parse()
+	console.SetQuiet(*quiet)
 
 	if *harFile == "" {
 		fmt.Fprintln(os.Stderr, "Usage: parse-har -har <file.har> [-output <out.json>]")
-		os.Exit(1)
+		os.Exit(exitcode.Usage)
 	}
 
 	data, err := os.ReadFile(*harFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitcode.Usage)
 	}
 
 	var har HAR
 	if err := json.Unmarshal(data, &har); err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing HAR: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitcode.Usage)
+	}
+
+	if v := har.Log.Version; v != "" && v != "1.1" && v != "1.2" {
+		fmt.Fprintf(os.Stderr, "[WARN] Unknown HAR version %s; reading it as 1.2\n", v)
 	}
 
 	result := analyzeHAR(har, *verbose)
 	
 	outputData, _ := json.MarshalIndent(result, "", "  ")
-	os.WriteFile(*output, outputData, 0644)
+	if err := console.WriteFile(*output, outputData, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(exitcode.Failure)
+	}
 	
-	fmt.Printf("Analyzed %d entries, found %d relevant APIs\n", result.TotalEntries, len(result.RelevantAPIs))
-	fmt.Printf("Results saved to: %s\n", *output)
+	console.Printf("Analyzed %d entries, found %d relevant APIs\n", result.TotalEntries, len(result.RelevantAPIs))
+	if !console.IsStdout(*output) {
+		console.Printf("Results saved to: %s\n", *output)
+	}
 	printSummary(result)
 }
 
@@ -110,11 +158,22 @@ func analyzeHAR(har HAR, verbose bool) *AnalysisResult {
 		RegionBlocking: make(map[string]interface{}),
 	}
 
+	// A request repeated with the same method and URL, query string
+	// included, is one endpoint; the last capture of it is kept
+	seen := make(map[string]int)
 	for _, entry := range har.Log.Entries {
-		ep := parseEntry(entry)
+		ep := parseEntry(entry, verbose)
 		if isRelevantAPI(ep) {
 			ep.IsRelevant = true
-			result.RelevantAPIs = append(result.RelevantAPIs, ep)
+			key := ep.Method + " " + endpointKey(ep.URL)
+			if i, ok := seen[key]; ok {
+				ep.Count = result.RelevantAPIs[i].Count + 1
+				result.RelevantAPIs[i] = ep
+			} else {
+				ep.Count = 1
+				seen[key] = len(result.RelevantAPIs)
+				result.RelevantAPIs = append(result.RelevantAPIs, ep)
+			}
 		}
 		if token := ep.Headers["x-csrf-token"]; token != "" {
 			result.CSRFToken = token
@@ -128,25 +187,60 @@ func analyzeHAR(har HAR, verbose bool) *AnalysisResult {
 	return result
 }
 
-func parseEntry(entry Entry) APIEndpoint {
+func parseEntry(entry Entry, verbose bool) APIEndpoint {
 	ep := APIEndpoint{
 		URL:     entry.Request.URL,
-		Method:  entry.Request.Method,
-		Status:  entry.Response.Status,
+		Method:  strings.ToUpper(entry.Request.Method),
+		Status:  int(entry.Response.Status),
 		Headers: make(map[string]string),
 	}
 	for _, h := range entry.Request.Headers {
 		ep.Headers[strings.ToLower(h.Name)] = h.Value
 	}
-	if entry.Request.PostData != nil {
-		ep.RequestBody = entry.Request.PostData.Text
-	}
-	if entry.Response.Content.Text != "" {
-		ep.ResponseBody = entry.Response.Content.Text
+	if pd := entry.Request.PostData; pd != nil {
+		ep.RequestBody = pd.Text
+		if ep.RequestBody == "" && len(pd.Params) > 0 {
+			form := url.Values{}
+			for _, p := range pd.Params {
+				form.Add(p.Name, p.Value)
+			}
+			ep.RequestBody = form.Encode()
+		}
 	}
+	ep.ResponseBody = contentText(entry.Response.Content, ep.URL, verbose)
 	return ep
 }
 
+// contentText returns a response body, decoding it if the HAR stored it
+// base64-encoded. A body that doesn't decode is kept as written.
+func contentText(c Content, entryURL string, verbose bool) string {
+	if c.Text == "" || !strings.EqualFold(c.Encoding, "base64") {
+		return c.Text
+	}
+	decoded, err := base64.StdEncoding.DecodeString(c.Text)
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "[WARN] %s: response body isn't valid base64, keeping it as is: %v\n", entryURL, err)
+		}
+		return c.Text
+	}
+	return string(decoded)
+}
+
+// endpointKey normalizes a URL for deduplication: the query string is kept,
+// since it selects what the endpoint returns, but its parameters are sorted
+// and the fragment dropped, so browsers that order or encode them
+// differently still match.
+func endpointKey(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.Fragment = ""
+	u.RawQuery = u.Query().Encode()
+	return u.String()
+}
+
 func isRelevantAPI(ep APIEndpoint) bool {
 	url := strings.ToLower(ep.URL)
 	if !strings.Contains(url, "/proxy/network/") && !strings.Contains(url, "/api/") {
@@ -162,11 +256,14 @@ func isRelevantAPI(ep APIEndpoint) bool {
 }
 
 func printSummary(result *AnalysisResult) {
-	fmt.Println("\nRelevant API Endpoints:")
+	console.Println("\nRelevant API Endpoints:")
 	for i, ep := range result.RelevantAPIs {
-		fmt.Printf("\n%d. %s %s (Status: %d)\n", i+1, ep.Method, ep.URL, ep.Status)
+		console.Printf("\n%d. %s %s (Status: %d)\n", i+1, ep.Method, ep.URL, ep.Status)
+		if ep.Count > 1 {
+			console.Printf("   Seen: %d times\n", ep.Count)
+		}
 		if ep.RequestBody != "" {
-			fmt.Printf("   Request: %s\n", truncate(ep.RequestBody, 150))
+			console.Printf("   Request: %s\n", truncate(ep.RequestBody, 150))
 		}
 	}
 }
@@ -9,6 +9,9 @@ import (
 	"os"
 	"strings"
 
+	"github.com/mattsblocklist/tae/internal/config"
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/exitcode"
 	"github.com/mattsblocklist/tae/internal/unifi"
 )
 
@@ -18,9 +21,18 @@ func main() {
 	password := flag.String("password", "", "UniFi password")
 	site := flag.String("site", "default", "UniFi site name")
 	insecure := flag.Bool("insecure", false, "Skip TLS certificate verification")
-	output := flag.String("output", "api-discovery.json", "Output file for discovered API structure")
+	caCert := flag.String("ca-cert", "", "PEM file of CA certificates to trust for the controller (or UNIFI_CA_CERT_FILE env)")
+	output := flag.String("output", "api-discovery.json", "Output file for discovered API structure (- = stdout)")
+	quiet := flag.Bool("quiet", false, "Only print errors and requested output; progress and summaries are suppressed")
+	envFile := flag.String("env-file", config.DefaultEnvFile, "Read KEY=VALUE settings from this file (ignored if missing; real env vars take precedence)")
 
 	flag.Parse()
+	console.SetQuiet(*quiet)
+
+	if err := config.LoadDotEnv(*envFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading env file: %v\n", err)
+		os.Exit(exitcode.Usage)
+	}
 
 	if *host == "" {
 		*host = os.Getenv("UNIFI_HOST")
@@ -31,13 +43,16 @@ func main() {
 	if *password == "" {
 		*password = os.Getenv("UNIFI_PASSWORD")
 	}
+	if *caCert == "" {
+		*caCert = os.Getenv("UNIFI_CA_CERT_FILE")
+	}
 
 	if *host == "" || *username == "" || *password == "" {
 		fmt.Fprintln(os.Stderr, "Error: host, username, and password are required")
-		os.Exit(1)
+		os.Exit(exitcode.Usage)
 	}
 
-	fmt.Printf("Connecting to %s...\n", *host)
+	console.Printf("Connecting to %s...\n", *host)
 
 	client, err := unifi.NewClient(unifi.ClientConfig{
 		Host:          *host,
@@ -45,32 +60,23 @@ func main() {
 		Password:      *password,
 		Site:          *site,
 		SkipTLSVerify: *insecure,
+		CACertFile:    *caCert,
 		Verbose:       true,
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to connect: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitcode.FromError(err))
 	}
 	defer client.Logout()
 
-	fmt.Println("Connected! Probing endpoints...\n")
+	console.Printf("Connected! Probing endpoints...\n\n")
 
 	results := make(map[string]interface{})
 
 	// 1. Get all settings to find geo-related keys
-	fmt.Println("1. Fetching all settings...")
-	body, status, err := client.Get("rest/setting")
-	if err == nil && status == 200 {
-		var settings []map[string]interface{}
-		if err := json.Unmarshal(body, &settings); err != nil {
-			var wrapper struct {
-				Data []map[string]interface{} `json:"data"`
-			}
-			if err := json.Unmarshal(body, &wrapper); err == nil {
-				settings = wrapper.Data
-			}
-		}
-
+	console.Println("1. Fetching all settings...")
+	settings, err := client.GetAllSettings()
+	if err == nil {
 		// Find geo-related settings
 		var geoSettings []map[string]interface{}
 		for _, s := range settings {
@@ -90,71 +96,75 @@ func main() {
 		results["all_settings"] = settings
 		results["geo_related_settings"] = geoSettings
 
-		fmt.Printf("   Found %d total settings, %d geo-related\n", len(settings), len(geoSettings))
+		console.Printf("   Found %d total settings, %d geo-related\n", len(settings), len(geoSettings))
 
 		// Try to find the region blocking setting specifically
 		for _, s := range geoSettings {
-			fmt.Printf("\n   Setting: %v\n", s["key"])
+			console.Printf("\n   Setting: %v\n", s["key"])
 			pretty, _ := json.MarshalIndent(s, "     ", "  ")
-			fmt.Printf("     %s\n", string(pretty))
+			console.Printf("     %s\n", string(pretty))
 		}
 	}
 
 	// 2. Get country codes
-	fmt.Println("\n2. Fetching country codes...")
-	body, status, err = client.Get("stat/ccode")
+	console.Println("\n2. Fetching country codes...")
+	console.Printf("   URL: %s\n", client.BuildURL("stat/ccode"))
+	body, status, err := client.Get("stat/ccode")
 	if err == nil && status == 200 {
 		var ccodeData interface{}
 		json.Unmarshal(body, &ccodeData)
 		results["country_codes"] = ccodeData
-		fmt.Printf("   Status: %d\n", status)
-		fmt.Printf("   Response size: %d bytes\n", len(body))
+		console.Printf("   Status: %d\n", status)
+		console.Printf("   Response size: %d bytes\n", len(body))
 		if len(body) < 1000 {
-			fmt.Printf("   Response: %s\n", string(body))
+			console.Printf("   Response: %s\n", string(body))
 		}
 	}
 
 	// 3. Try v2 API endpoints
-	fmt.Println("\n3. Trying v2 API endpoints...")
+	console.Println("\n3. Trying v2 API endpoints...")
 	v2Endpoints := []string{
-		"v2/api/site/" + *site + "/trafficrules",
-		"v2/api/site/" + *site + "/security",
-		"v2/api/site/" + *site + "/threat-management",
+		"v2/api/site/" + client.Site() + "/trafficrules",
+		"v2/api/site/" + client.Site() + "/security",
+		"v2/api/site/" + client.Site() + "/threat-management",
 	}
 
 	for _, ep := range v2Endpoints {
-		fmt.Printf("\n   Trying: %s\n", ep)
-		body, status, err := client.Get(ep)
+		console.Printf("\n   Trying: %s\n", ep)
+		console.Printf("     URL: %s\n", client.BuildURL("proxy/network/"+ep))
+		body, status, header, err := client.RawRequestFull("GET", "proxy/network/"+ep, nil)
 		if err == nil {
-			fmt.Printf("     Status: %d\n", status)
+			console.Printf("     Status: %d\n", status)
+			results[ep+"_headers"] = unifi.RedactHeaders(header)
 			if status == 200 {
 				var data interface{}
 				if err := json.Unmarshal(body, &data); err == nil {
 					results[ep] = data
 					pretty, _ := json.MarshalIndent(data, "     ", "  ")
 					if len(pretty) < 2000 {
-						fmt.Printf("     Response:\n%s\n", string(pretty))
+						console.Printf("     Response:\n%s\n", string(pretty))
 					} else {
-						fmt.Printf("     Response: %d bytes (truncated)\n", len(body))
+						console.Printf("     Response: %d bytes (truncated)\n", len(body))
 					}
 				} else {
 					results[ep+"_raw"] = string(body)
-					fmt.Printf("     Response: %s\n", string(body[:min(200, len(body))]))
+					console.Printf("     Response: %s\n", string(body[:min(200, len(body))]))
 				}
 			}
 		} else {
-			fmt.Printf("     Error: %v\n", err)
+			console.Printf("     Error: %v\n", err)
 		}
 	}
 
 	// 4. Try to GET specific setting keys if we found them
 	if geoSettingsRaw, ok := results["geo_related_settings"].([]map[string]interface{}); ok {
-		fmt.Println("\n4. Fetching detailed setting data...")
+		console.Println("\n4. Fetching detailed setting data...")
 		for _, s := range geoSettingsRaw {
 			if key, ok := s["key"].(string); ok {
 				if id, ok := s["_id"].(string); ok {
 					settingPath := fmt.Sprintf("rest/setting/%s/%s", key, id)
-					fmt.Printf("\n   Fetching: %s\n", settingPath)
+					console.Printf("\n   Fetching: %s\n", settingPath)
+					console.Printf("     URL: %s\n", client.BuildURL(settingPath))
 					body, status, err := client.Get(settingPath)
 					if err == nil && status == 200 {
 						var data interface{}
@@ -162,9 +172,9 @@ func main() {
 							results["setting_"+key] = data
 							pretty, _ := json.MarshalIndent(data, "     ", "  ")
 							if len(pretty) < 2000 {
-								fmt.Printf("     %s\n", string(pretty))
+								console.Printf("     %s\n", string(pretty))
 							} else {
-								fmt.Printf("     Response: %d bytes\n", len(body))
+								console.Printf("     Response: %d bytes\n", len(body))
 							}
 						}
 					}
@@ -176,27 +186,29 @@ func main() {
 	// Save results
 	outputData := map[string]interface{}{
 		"controller_url": *host,
-		"site":          *site,
+		"site":          client.Site(),
 		"discovered":    results,
 	}
 
 	jsonData, err := json.MarshalIndent(outputData, "", "  ")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error marshaling results: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitcode.Failure)
 	}
 
-	if err := os.WriteFile(*output, jsonData, 0644); err != nil {
+	if err := console.WriteFile(*output, jsonData, 0644); err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitcode.Failure)
 	}
 
-	fmt.Printf("\n\nResults saved to %s\n", *output)
-	fmt.Println("\nNext steps:")
-	fmt.Println("1. Review the discovered settings in the output file")
-	fmt.Println("2. Look for setting keys containing 'geo', 'region', 'country', or 'block'")
-	fmt.Println("3. Note the structure of the setting data (especially country code format)")
-	fmt.Println("4. Use this information to update cmd/configure/main.go")
+	if !console.IsStdout(*output) {
+		console.Printf("\n\nResults saved to %s\n", *output)
+	}
+	console.Println("\nNext steps:")
+	console.Println("1. Review the discovered settings in the output file")
+	console.Println("2. Look for setting keys containing 'geo', 'region', 'country', or 'block'")
+	console.Println("3. Note the structure of the setting data (especially country code format)")
+	console.Println("4. Use this information to update cmd/configure/main.go")
 }
 
 func min(a, b int) int {
@@ -0,0 +1,107 @@
+// Command selftest runs the aggregation pipeline offline: every scraper is
+// forced onto its fallback path, and the combined result is checked for
+// valid, non-empty country codes. It needs no network or controller.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/mattsblocklist/tae/internal/aggregate"
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/countries"
+	"github.com/mattsblocklist/tae/internal/exitcode"
+	"github.com/mattsblocklist/tae/internal/fetch"
+	"github.com/mattsblocklist/tae/internal/scrapers"
+)
+
+// offlineClient fails every request so scrapers use their fallback data.
+type offlineClient struct{}
+
+func (offlineClient) Do(req *http.Request) (*http.Response, error) {
+	return nil, fetch.Permanent(errors.New("selftest: network disabled"))
+}
+
+func main() {
+	verbose := flag.Bool("verbose", false, "Enable verbose output")
+	quiet := flag.Bool("quiet", false, "Only print errors and requested output; progress and summaries are suppressed")
+
+	flag.Parse()
+	console.SetQuiet(*quiet)
+
+	console.Println("Offline Pipeline Self-Test")
+	console.Println(strings.Repeat("=", 40))
+
+	registry := scrapers.DefaultRegistry(offlineClient{})
+	normalizer := countries.NewNormalizer()
+
+	results := aggregate.RunScrapers(context.Background(), registry, registry.Names(), 1, *verbose)
+	agg := aggregate.Aggregate(results, normalizer, *verbose)
+
+	var failures []string
+	fail := func(format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		failures = append(failures, msg)
+		fmt.Fprintf(os.Stderr, "  [FAIL] %s\n", msg)
+	}
+
+	console.Println("\nBuilt-in country names:")
+	unresolved := aggregate.UnresolvedBuiltinNames(normalizer)
+	for _, msg := range unresolved {
+		fail("%s", msg)
+	}
+	if len(unresolved) == 0 {
+		console.Println("  [PASS] all names normalize")
+	}
+
+	console.Println("\nSources:")
+	for _, name := range aggregate.SourceNames(agg) {
+		stats := agg.SourceStats[name]
+		status := stats.ParseStatus
+		if scrapers.UsedFallback(status, stats.RawCount) {
+			// Whatever the failure mode, or an open circuit, the source used its fallback list
+			status = "fallback"
+		}
+		switch status {
+		case "fallback":
+			if stats.RawCount == 0 {
+				fail("%s: fallback list is empty", name)
+			} else if stats.MatchedCount != stats.RawCount {
+				fail("%s: only %d of %d fallback names normalized", name, stats.MatchedCount, stats.RawCount)
+			} else {
+				console.Printf("  [PASS] %s: %d fallback countries\n", name, stats.MatchedCount)
+			}
+		case "error", "no_data", scrapers.StatusCircuitOpen, scrapers.StatusErrorPage:
+			// Index sources have no built-in list to fall back on
+			console.Printf("  [SKIP] %s: no fallback data (%s)\n", name, stats.ParseStatus)
+		default:
+			fail("%s: unexpected status %q with network disabled", name, stats.ParseStatus)
+		}
+	}
+
+	console.Println("\nAggregated list:")
+	before := len(failures)
+	if agg.TotalCodes == 0 {
+		fail("aggregated list is empty")
+	}
+	for _, c := range agg.Countries {
+		if !normalizer.IsValidCode(c.Alpha2) {
+			fail("invalid country code %q", c.Alpha2)
+		}
+	}
+	if len(failures) == before {
+		console.Printf("  [PASS] %d valid country codes\n", agg.TotalCodes)
+	}
+
+	console.Println("\n" + strings.Repeat("=", 40))
+	if len(failures) > 0 {
+		fmt.Fprintf(os.Stderr, "FAIL: %d problems\n", len(failures))
+		os.Exit(exitcode.Verification)
+	}
+	console.Println("PASS")
+}
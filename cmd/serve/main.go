@@ -0,0 +1,196 @@
+// Command serve runs the country blocklist aggregation on a schedule and
+// serves the latest text and JSON lists over HTTP.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/aggregate"
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/exitcode"
+	"github.com/mattsblocklist/tae/internal/scrapers"
+)
+
+// snapshot holds the rendered output of one aggregation run.
+type snapshot struct {
+	txt        []byte
+	txtETag    string
+	json       []byte
+	jsonETag   string
+	totalCodes int
+	errors     int
+	generated  time.Time
+}
+
+// server serves the most recent snapshot and records run metadata.
+type server struct {
+	mu      sync.RWMutex
+	current *snapshot
+	lastRun time.Time
+	lastErr string
+	runs    int
+	maxAge  time.Duration
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "HTTP listen address")
+	interval := flag.Duration("interval", 6*time.Hour, "How often to re-run the aggregation")
+	sources := flag.String("sources", "", "Comma-separated list of sources to use (empty = all)")
+	verbose := flag.Bool("verbose", false, "Enable verbose output")
+	quiet := flag.Bool("quiet", false, "Only print errors and requested output; progress and summaries are suppressed")
+	timeout := flag.Duration("timeout", 60*time.Second, "HTTP request timeout")
+	workers := flag.Int("workers", 4, "Number of concurrent workers (0 = auto)")
+
+	flag.Parse()
+	console.SetQuiet(*quiet)
+
+	opts := aggregate.Options{
+		Sources: aggregate.ParseSources(*sources),
+		Workers: *workers,
+		Timeout: *timeout,
+		Verbose: *verbose,
+	}
+
+	srv := &server{maxAge: *interval}
+
+	// Populate the first snapshot before accepting traffic
+	srv.refresh(opts)
+	go func() {
+		ticker := time.NewTicker(*interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			srv.refresh(opts)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blocked_countries.txt", srv.handleTxt)
+	mux.HandleFunc("/blocked_countries.json", srv.handleJSON)
+	mux.HandleFunc("/healthz", srv.handleHealth)
+	mux.HandleFunc("/metrics", srv.handleMetrics)
+
+	console.Printf("Serving blocklist on %s (refresh every %s)\n", *addr, *interval)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+		os.Exit(exitcode.Failure)
+	}
+}
+
+// refresh runs a full aggregation and swaps in the new snapshot.
+// A failed render keeps the previous snapshot in place.
+func (s *server) refresh(opts aggregate.Options) {
+	console.Printf("[%s] Running aggregation...\n", time.Now().Format(time.RFC3339))
+
+	agg := aggregate.Run(context.Background(), opts)
+
+	jsonContent, err := aggregate.FormatJSON(agg)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastRun = time.Now()
+	s.runs++
+
+	if err != nil {
+		s.lastErr = err.Error()
+		fmt.Fprintf(os.Stderr, "Aggregation failed: %v\n", err)
+		return
+	}
+
+	txt := aggregate.FormatText(agg)
+	s.current = &snapshot{
+		txt:        txt,
+		txtETag:    `"` + scrapers.HashContent(txt) + `"`,
+		json:       jsonContent,
+		jsonETag:   `"` + scrapers.HashContent(jsonContent) + `"`,
+		totalCodes: agg.TotalCodes,
+		errors:     len(agg.Errors),
+		generated:  agg.LastModified,
+	}
+	s.lastErr = ""
+
+	console.Printf("Aggregation complete: %d codes\n", agg.TotalCodes)
+}
+
+func (s *server) latest() *snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+func (s *server) handleTxt(w http.ResponseWriter, r *http.Request) {
+	snap := s.latest()
+	if snap == nil {
+		http.Error(w, "blocklist not available yet", http.StatusServiceUnavailable)
+		return
+	}
+	s.serve(w, r, "blocked_countries.txt", "text/plain; charset=utf-8", snap.txtETag, snap.generated, snap.txt)
+}
+
+func (s *server) handleJSON(w http.ResponseWriter, r *http.Request) {
+	snap := s.latest()
+	if snap == nil {
+		http.Error(w, "blocklist not available yet", http.StatusServiceUnavailable)
+		return
+	}
+	s.serve(w, r, "blocked_countries.json", "application/json", snap.jsonETag, snap.generated, snap.json)
+}
+
+// serve writes content with caching headers. http.ServeContent handles
+// If-None-Match / If-Modified-Since and replies 304 when appropriate.
+func (s *server) serve(w http.ResponseWriter, r *http.Request, name, contentType, etag string, modTime time.Time, content []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.maxAge.Seconds())))
+	http.ServeContent(w, r, name, modTime, bytes.NewReader(content))
+}
+
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if s.latest() == nil {
+		http.Error(w, "no successful aggregation yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleMetrics exposes run metadata in Prometheus text format.
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP tae_aggregate_last_run_timestamp_seconds Unix time of the last aggregation attempt.")
+	fmt.Fprintln(w, "# TYPE tae_aggregate_last_run_timestamp_seconds gauge")
+	fmt.Fprintf(w, "tae_aggregate_last_run_timestamp_seconds %d\n", s.lastRun.Unix())
+
+	fmt.Fprintln(w, "# HELP tae_aggregate_runs_total Number of aggregation runs since start.")
+	fmt.Fprintln(w, "# TYPE tae_aggregate_runs_total counter")
+	fmt.Fprintf(w, "tae_aggregate_runs_total %d\n", s.runs)
+
+	failed := 0
+	if s.lastErr != "" {
+		failed = 1
+	}
+	fmt.Fprintln(w, "# HELP tae_aggregate_last_run_failed Whether the last aggregation failed to render.")
+	fmt.Fprintln(w, "# TYPE tae_aggregate_last_run_failed gauge")
+	fmt.Fprintf(w, "tae_aggregate_last_run_failed %d\n", failed)
+
+	if s.current != nil {
+		fmt.Fprintln(w, "# HELP tae_aggregate_total_codes Country codes in the served list.")
+		fmt.Fprintln(w, "# TYPE tae_aggregate_total_codes gauge")
+		fmt.Fprintf(w, "tae_aggregate_total_codes %d\n", s.current.totalCodes)
+
+		fmt.Fprintln(w, "# HELP tae_aggregate_source_errors Sources that reported errors in the served list.")
+		fmt.Fprintln(w, "# TYPE tae_aggregate_source_errors gauge")
+		fmt.Fprintf(w, "tae_aggregate_source_errors %d\n", s.current.errors)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Kinds of change between two snapshots.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Change is one difference between two snapshots. Field is the dotted path
+// within the setting, empty when the whole setting was added or removed.
+type Change struct {
+	Key   string
+	Field string
+	Kind  string
+	Old   interface{}
+	New   interface{}
+}
+
+// Path returns the setting key and field as one dotted path.
+func (c Change) Path() string {
+	if c.Field == "" {
+		return c.Key
+	}
+	return c.Key + "." + c.Field
+}
+
+// diffSnapshots lists the changes from before to after, sorted by setting
+// key and then field.
+func diffSnapshots(before, after *Snapshot) []Change {
+	var changes []Change
+	for _, key := range unionKeys(before.Settings, after.Settings) {
+		o, inOld := before.Settings[key]
+		n, inNew := after.Settings[key]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Key: key, Kind: ChangeAdded, New: n})
+		case !inNew:
+			changes = append(changes, Change{Key: key, Kind: ChangeRemoved, Old: o})
+		default:
+			changes = diffFields(changes, key, "", o, n)
+		}
+	}
+	return changes
+}
+
+// diffFields appends the differences between two objects, descending into
+// nested objects. Arrays and other values are compared whole.
+func diffFields(changes []Change, key, prefix string, before, after map[string]interface{}) []Change {
+	for _, field := range unionKeys(before, after) {
+		path := field
+		if prefix != "" {
+			path = prefix + "." + field
+		}
+
+		o, inOld := before[field]
+		n, inNew := after[field]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Key: key, Field: path, Kind: ChangeAdded, New: n})
+		case !inNew:
+			changes = append(changes, Change{Key: key, Field: path, Kind: ChangeRemoved, Old: o})
+		default:
+			om, oIsMap := o.(map[string]interface{})
+			nm, nIsMap := n.(map[string]interface{})
+			if oIsMap && nIsMap {
+				changes = diffFields(changes, key, path, om, nm)
+			} else if !reflect.DeepEqual(o, n) {
+				changes = append(changes, Change{Key: key, Field: path, Kind: ChangeChanged, Old: o, New: n})
+			}
+		}
+	}
+	return changes
+}
+
+// unionKeys returns the keys present in either map, sorted.
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		seen[k] = true
+	}
+	for k := range b {
+		seen[k] = true
+	}
+
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
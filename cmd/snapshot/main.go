@@ -0,0 +1,258 @@
+// Command snapshot saves every setting object of a UniFi site to a
+// normalized JSON file, and with -diff compares two snapshots to show which
+// settings and fields changed between them.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/config"
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/exitcode"
+	"github.com/mattsblocklist/tae/internal/unifi"
+)
+
+// Snapshot is the saved settings tree of one site. Settings is keyed by the
+// setting's key (e.g. "usg"); encoding/json writes map keys in sorted order,
+// so two snapshots of the same settings are byte-identical.
+type Snapshot struct {
+	Timestamp     time.Time                         `json:"timestamp"`
+	ControllerURL string                            `json:"controller_url"`
+	Site          string                            `json:"site"`
+	Settings      map[string]map[string]interface{} `json:"settings"`
+}
+
+// secretPrefix marks fields UniFi treats as secrets, e.g. x_password.
+const secretPrefix = "x_"
+
+// redacted replaces secret values in a snapshot.
+const redacted = "[redacted]"
+
+func main() {
+	host := flag.String("host", "", "UniFi controller URL")
+	username := flag.String("username", "", "UniFi username")
+	password := flag.String("password", "", "UniFi password")
+	site := flag.String("site", "default", "UniFi site name")
+	insecure := flag.Bool("insecure", false, "Skip TLS certificate verification")
+	caCert := flag.String("ca-cert", "", "PEM file of CA certificates to trust for the controller (or UNIFI_CA_CERT_FILE env)")
+	output := flag.String("output", "settings-snapshot.json", "Write the snapshot to this file (- = stdout)")
+	diff := flag.Bool("diff", false, "Compare two snapshots given as arguments (old.json new.json) instead of taking one")
+	keepSecrets := flag.Bool("keep-secrets", false, "Save x_ secret fields as-is instead of redacting them")
+	verbose := flag.Bool("verbose", false, "Enable verbose output")
+	quiet := flag.Bool("quiet", false, "Only print errors and requested output; progress and summaries are suppressed")
+	envFile := flag.String("env-file", config.DefaultEnvFile, "Read KEY=VALUE settings from this file (ignored if missing; real env vars take precedence)")
+
+	flag.Parse()
+	console.SetQuiet(*quiet)
+
+	if *diff {
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "Error: -diff needs two snapshot files: old.json new.json")
+			os.Exit(exitcode.Usage)
+		}
+		if err := runDiff(flag.Arg(0), flag.Arg(1)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitcode.Usage)
+		}
+		return
+	}
+
+	if err := config.LoadDotEnv(*envFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading env file: %v\n", err)
+		os.Exit(exitcode.Usage)
+	}
+
+	// Load from environment if not provided
+	if *host == "" {
+		*host = os.Getenv("UNIFI_HOST")
+	}
+	if *username == "" {
+		*username = os.Getenv("UNIFI_USERNAME")
+	}
+	if *password == "" {
+		*password = os.Getenv("UNIFI_PASSWORD")
+	}
+	if *caCert == "" {
+		*caCert = os.Getenv("UNIFI_CA_CERT_FILE")
+	}
+
+	if *host == "" || *username == "" || *password == "" {
+		fmt.Fprintln(os.Stderr, "Error: host, username, and password are required")
+		fmt.Fprintln(os.Stderr, "Use flags or environment variables: UNIFI_HOST, UNIFI_USERNAME, UNIFI_PASSWORD")
+		flag.Usage()
+		os.Exit(exitcode.Usage)
+	}
+
+	console.Printf("Connecting to %s...\n", *host)
+	client, err := unifi.NewClient(unifi.ClientConfig{
+		Host:          *host,
+		Username:      *username,
+		Password:      *password,
+		Site:          *site,
+		SkipTLSVerify: *insecure,
+		CACertFile:    *caCert,
+		Verbose:       *verbose,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect: %v\n", err)
+		os.Exit(exitcode.FromError(err))
+	}
+	defer client.Logout()
+
+	settings, err := client.GetAllSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get settings: %v\n", err)
+		os.Exit(exitcode.FromError(err))
+	}
+
+	snap := buildSnapshot(settings, *keepSecrets)
+	snap.ControllerURL = client.BaseURL()
+	snap.Site = client.Site()
+
+	if err := saveSnapshot(*output, snap); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing snapshot: %v\n", err)
+		os.Exit(exitcode.Failure)
+	}
+	if !console.IsStdout(*output) {
+		console.Printf("Saved %d settings to %s\n", len(snap.Settings), *output)
+	}
+}
+
+// buildSnapshot keys the settings by their key. A key that appears more than
+// once is disambiguated with the object's _id. Unless keepSecrets is set,
+// secret fields are redacted, so changes to them don't show up in a diff.
+func buildSnapshot(settings []map[string]interface{}, keepSecrets bool) *Snapshot {
+	snap := &Snapshot{
+		Timestamp: time.Now(),
+		Settings:  make(map[string]map[string]interface{}, len(settings)),
+	}
+
+	count := make(map[string]int)
+	for _, s := range settings {
+		key, _ := s["key"].(string)
+		count[key]++
+	}
+
+	for _, s := range settings {
+		key, _ := s["key"].(string)
+		name := key
+		if count[key] > 1 {
+			id, _ := s["_id"].(string)
+			name = key + "#" + id
+		}
+		if !keepSecrets {
+			s = redactSecrets(s)
+		}
+		snap.Settings[name] = s
+	}
+
+	return snap
+}
+
+// redactSecrets returns a copy of setting with each secret field's value
+// replaced.
+func redactSecrets(setting map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(setting))
+	for field, value := range setting {
+		if strings.HasPrefix(field, secretPrefix) {
+			value = redacted
+		}
+		out[field] = value
+	}
+	return out
+}
+
+func saveSnapshot(path string, snap *Snapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return console.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func loadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+	return &snap, nil
+}
+
+// runDiff prints the changes between two snapshot files.
+func runDiff(oldPath, newPath string) error {
+	oldSnap, err := loadSnapshot(oldPath)
+	if err != nil {
+		return err
+	}
+	newSnap, err := loadSnapshot(newPath)
+	if err != nil {
+		return err
+	}
+
+	console.Printf("Comparing %s (%s) with %s (%s)\n",
+		oldPath, oldSnap.Timestamp.Format(time.RFC3339), newPath, newSnap.Timestamp.Format(time.RFC3339))
+	if oldSnap.Site != newSnap.Site || oldSnap.ControllerURL != newSnap.ControllerURL {
+		fmt.Fprintf(os.Stderr, "Warning: snapshots are of different sites (%s %s vs %s %s)\n",
+			oldSnap.ControllerURL, oldSnap.Site, newSnap.ControllerURL, newSnap.Site)
+	}
+
+	changes := diffSnapshots(oldSnap, newSnap)
+	printChanges(changes)
+	return nil
+}
+
+func printChanges(changes []Change) {
+	if len(changes) == 0 {
+		console.Println("\nNo changes")
+		return
+	}
+
+	console.Println()
+	keys := make(map[string]bool)
+	for _, c := range changes {
+		keys[c.Key] = true
+		switch c.Kind {
+		case ChangeAdded:
+			if c.Field == "" {
+				fmt.Printf("+ %s\n", c.Path())
+			} else {
+				fmt.Printf("+ %s: %s\n", c.Path(), formatValue(c.New))
+			}
+		case ChangeRemoved:
+			if c.Field == "" {
+				fmt.Printf("- %s\n", c.Path())
+			} else {
+				fmt.Printf("- %s: %s\n", c.Path(), formatValue(c.Old))
+			}
+		default:
+			fmt.Printf("~ %s: %s -> %s\n", c.Path(), formatValue(c.Old), formatValue(c.New))
+		}
+	}
+
+	names := make([]string, 0, len(keys))
+	for k := range keys {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	console.Printf("\n%d changes in %d settings: %s\n", len(changes), len(names), strings.Join(names, ", "))
+}
+
+// formatValue renders a value as compact JSON.
+func formatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
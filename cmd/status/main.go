@@ -0,0 +1,235 @@
+// Command status shows a UniFi site's current region blocking state: whether
+// it is enabled, the block mode and traffic direction, and the blocked
+// countries. It only reads from the controller and never changes anything.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/config"
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/countries"
+	"github.com/mattsblocklist/tae/internal/exitcode"
+	"github.com/mattsblocklist/tae/internal/unifi"
+)
+
+// Status is the region blocking state of one site. Inbound and Outbound are
+// set only on firmware with a separate country list per direction.
+type Status struct {
+	Timestamp        time.Time `json:"timestamp"`
+	ControllerURL    string    `json:"controller_url"`
+	Site             string    `json:"site"`
+	Enabled          bool      `json:"enabled"`
+	Block            string    `json:"block"`
+	TrafficDirection string    `json:"traffic_direction"`
+	Count            int       `json:"count"`
+	Countries        []Country `json:"countries"`
+	Inbound          []string  `json:"inbound,omitempty"`
+	Outbound         []string  `json:"outbound,omitempty"`
+}
+
+// Country is one blocked country.
+type Country struct {
+	Alpha2 string `json:"alpha2"`
+	Name   string `json:"name"`
+	Flag   string `json:"flag,omitempty"`
+}
+
+func main() {
+	host := flag.String("host", "", "UniFi controller URL")
+	username := flag.String("username", "", "UniFi username")
+	password := flag.String("password", "", "UniFi password")
+	site := flag.String("site", "default", "UniFi site name")
+	insecure := flag.Bool("insecure", false, "Skip TLS certificate verification")
+	caCert := flag.String("ca-cert", "", "PEM file of CA certificates to trust for the controller (or UNIFI_CA_CERT_FILE env)")
+	endpoint := flag.String("endpoint", "", "Override the region blocking settings path, e.g. set/setting/usg")
+	output := flag.String("output", "", "Also write the status as JSON to this file (- = stdout, instead of the listing)")
+	noFlags := flag.Bool("no-flags", false, "Leave out the flag emoji in the listing")
+	verbose := flag.Bool("verbose", false, "Enable verbose output")
+	quiet := flag.Bool("quiet", false, "Only print errors and requested output; progress and summaries are suppressed")
+	envFile := flag.String("env-file", config.DefaultEnvFile, "Read KEY=VALUE settings from this file (ignored if missing; real env vars take precedence)")
+
+	flag.Parse()
+	console.SetQuiet(*quiet)
+
+	if err := config.LoadDotEnv(*envFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading env file: %v\n", err)
+		os.Exit(exitcode.Usage)
+	}
+
+	// Load from environment if not provided
+	if *host == "" {
+		*host = os.Getenv("UNIFI_HOST")
+	}
+	if *username == "" {
+		*username = os.Getenv("UNIFI_USERNAME")
+	}
+	if *password == "" {
+		*password = os.Getenv("UNIFI_PASSWORD")
+	}
+	if *caCert == "" {
+		*caCert = os.Getenv("UNIFI_CA_CERT_FILE")
+	}
+
+	if *host == "" || *username == "" || *password == "" {
+		fmt.Fprintln(os.Stderr, "Error: host, username, and password are required")
+		fmt.Fprintln(os.Stderr, "Use flags or environment variables: UNIFI_HOST, UNIFI_USERNAME, UNIFI_PASSWORD")
+		flag.Usage()
+		os.Exit(exitcode.Usage)
+	}
+
+	if *endpoint != "" {
+		if _, err := unifi.ParseSettingPath(*endpoint); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -endpoint: %v\n", err)
+			os.Exit(exitcode.Usage)
+		}
+	}
+
+	console.Printf("Connecting to %s...\n", *host)
+	client, err := unifi.NewClient(unifi.ClientConfig{
+		Host:               *host,
+		Username:           *username,
+		Password:           *password,
+		Site:               *site,
+		SkipTLSVerify:      *insecure,
+		CACertFile:         *caCert,
+		Verbose:            *verbose,
+		RegionBlockingPath: *endpoint,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect: %v\n", err)
+		os.Exit(exitcode.FromError(err))
+	}
+	defer client.Logout()
+
+	setting, err := client.GetRegionBlockingSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get region blocking settings: %v\n", err)
+		os.Exit(exitcode.FromError(err))
+	}
+
+	status := buildStatus(setting, countries.NewNormalizer())
+	status.ControllerURL = client.BaseURL()
+	status.Site = client.Site()
+
+	if *output != "" {
+		if err := saveStatus(*output, status); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing status: %v\n", err)
+			os.Exit(exitcode.Failure)
+		}
+		if console.IsStdout(*output) {
+			return
+		}
+		console.Printf("Status saved to %s\n", *output)
+	}
+
+	printStatus(status, !*noFlags)
+}
+
+// buildStatus reads the region blocking fields of a USG setting. The country
+// list is reported even while blocking is disabled, since it is what
+// enabling it would enforce.
+func buildStatus(setting map[string]interface{}, normalizer *countries.Normalizer) *Status {
+	enabled, _ := setting["geo_ip_filtering_enabled"].(bool)
+	block, _ := setting["geo_ip_filtering_block"].(string)
+	direction, _ := setting["geo_ip_filtering_traffic_direction"].(string)
+
+	status := &Status{
+		Timestamp:        time.Now(),
+		Enabled:          enabled,
+		Block:            block,
+		TrafficDirection: direction,
+		Countries:        []Country{},
+	}
+
+	codes := unifi.CountryCodesFromSetting(setting)
+	for _, code := range codes {
+		status.Countries = append(status.Countries, Country{
+			Alpha2: code,
+			Name:   normalizer.GetName(code),
+			Flag:   flagEmoji(code),
+		})
+	}
+	status.Count = len(status.Countries)
+
+	if dc := unifi.DirectionalCountriesFromSetting(setting); dc.Directional {
+		status.Inbound = dc.Inbound
+		status.Outbound = dc.Outbound
+	}
+
+	return status
+}
+
+// flagEmoji returns the flag emoji of an alpha-2 code, built from the two
+// regional indicator symbols, or "" if the code isn't two letters.
+func flagEmoji(code string) string {
+	if len(code) != 2 {
+		return ""
+	}
+	var b strings.Builder
+	for _, c := range strings.ToUpper(code) {
+		if c < 'A' || c > 'Z' {
+			return ""
+		}
+		b.WriteRune(0x1F1E6 + c - 'A')
+	}
+	return b.String()
+}
+
+// printStatus writes the listing to stdout; it is the output that was asked
+// for, so -quiet doesn't suppress it.
+func printStatus(s *Status, flags bool) {
+	state := "disabled"
+	if s.Enabled {
+		state = "enabled"
+	}
+
+	fmt.Printf("Controller: %s\n", s.ControllerURL)
+	fmt.Printf("Site: %s\n", s.Site)
+	fmt.Printf("Region blocking: %s\n", state)
+	fmt.Printf("Mode: %s\n", orUnset(s.Block))
+	fmt.Printf("Direction: %s\n", orUnset(s.TrafficDirection))
+
+	fmt.Printf("\nBlocked countries (%d):\n", s.Count)
+	if s.Count == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, c := range s.Countries {
+		if flags && c.Flag != "" {
+			fmt.Printf("  %s %s  %s\n", c.Flag, c.Alpha2, c.Name)
+		} else {
+			fmt.Printf("  %s  %s\n", c.Alpha2, c.Name)
+		}
+	}
+
+	if s.Inbound != nil || s.Outbound != nil {
+		fmt.Printf("\nInbound (%d): %s\n", len(s.Inbound), strings.Join(s.Inbound, ", "))
+		fmt.Printf("Outbound (%d): %s\n", len(s.Outbound), strings.Join(s.Outbound, ", "))
+	}
+
+	if !s.Enabled && s.Count > 0 {
+		fmt.Println("\nNote: region blocking is disabled, so these countries are not blocked right now.")
+	}
+}
+
+// orUnset shows an empty setting field as "(not set)".
+func orUnset(s string) string {
+	if s == "" {
+		return "(not set)"
+	}
+	return s
+}
+
+// saveStatus writes the status as JSON to path, or stdout for "-".
+func saveStatus(path string, s *Status) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return console.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,776 @@
+// Package aggregate runs the country list scrapers and combines their results
+// into a single normalized list with per-source provenance.
+package aggregate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/clock"
+	"github.com/mattsblocklist/tae/internal/concurrency"
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/countries"
+	"github.com/mattsblocklist/tae/internal/scrapers"
+)
+
+// Default metadata written into every aggregation result.
+const (
+	DefaultName        = "UniFi Region Blocking Country List"
+	DefaultVersion     = "1.0.0"
+	DefaultDescription = "Aggregated list of countries subject to sanctions, export controls, or other restrictions from multiple authoritative sources. This list is intended for use with UniFi Network's Region Blocking (GeoIP Filtering) feature to block traffic from these countries."
+)
+
+// AggregationResult contains the final output. SourceStats is keyed by
+// source name; encoding/json writes map keys in sorted order so the JSON is
+// stable, and SourceNames gives the same order for other output.
+type AggregationResult struct {
+	// Metadata header
+	SchemaVersion int       `json:"schema_version"`
+	Name          string    `json:"name"`
+	Version       string    `json:"version"`
+	Description   string    `json:"description"`
+	LastModified  time.Time `json:"last_modified"`
+	// HeaderLines are extra comment lines for the text output's header,
+	// e.g. a contact or policy reference. They aren't part of the JSON.
+	HeaderLines []string `json:"-"`
+
+	// Data
+	Timestamp   time.Time               `json:"timestamp"`
+	TotalCodes  int                     `json:"total_codes"`
+	Countries   []CountryWithProvenance `json:"countries"`
+	SourceStats map[string]SourceStats  `json:"source_stats"`
+	Errors      []string                `json:"errors,omitempty"`
+
+	// Partial reports that the run was interrupted before every source
+	// finished; the sources that didn't have parse status "cancelled".
+	Partial bool `json:"partial,omitempty"`
+}
+
+// CountryWithProvenance includes source information.
+type CountryWithProvenance struct {
+	Alpha2    string            `json:"alpha2"`
+	Name      string            `json:"name"`
+	Sources   []string          `json:"sources"`
+	RawTokens []string          `json:"raw_tokens,omitempty"`
+	Rationale []SourceRationale `json:"rationale,omitempty"`
+	// MatchTypes records how each of RawTokens matched the country, one of
+	// the countries.Match* values.
+	MatchTypes map[string]string `json:"match_types,omitempty"`
+	// MatchScores records the similarity score of each token accepted as a
+	// fuzzy match, for review.
+	MatchScores map[string]float64 `json:"match_scores,omitempty"`
+	// TokensBySource records, per source, the distinct raw strings that
+	// resolved to this country.
+	TokensBySource map[string]TokenMatches `json:"tokens_by_source,omitempty"`
+	// BaselineOnly reports that only the previously published list still
+	// lists the country; see Options.Baseline.
+	BaselineOnly bool `json:"baseline_only,omitempty"`
+}
+
+// TokenMatches lists the raw strings one source used for a country.
+type TokenMatches struct {
+	Tokens []string `json:"tokens"`
+	// Count includes repeats, so it can exceed len(Tokens).
+	Count int `json:"count"`
+}
+
+// addToken records that source listed the country as match.Input, which
+// matched as match.MatchType. Tokens are recorded in their tokenDisplay
+// form.
+func (c *CountryWithProvenance) addToken(source string, match countries.Match) {
+	raw := tokenDisplay(match)
+	if !containsString(c.RawTokens, raw) {
+		c.RawTokens = append(c.RawTokens, raw)
+	}
+	if c.MatchTypes == nil {
+		c.MatchTypes = make(map[string]string)
+	}
+	c.MatchTypes[raw] = match.MatchType
+	if match.MatchType == countries.MatchFuzzy {
+		if c.MatchScores == nil {
+			c.MatchScores = make(map[string]float64)
+		}
+		c.MatchScores[raw] = match.Score
+	}
+
+	if c.TokensBySource == nil {
+		c.TokensBySource = make(map[string]TokenMatches)
+	}
+	m := c.TokensBySource[source]
+	if !containsString(m.Tokens, raw) {
+		m.Tokens = append(m.Tokens, raw)
+	}
+	m.Count++
+	c.TokensBySource[source] = m
+}
+
+// tokenDisplay is how a matched token appears in provenance. A token that is
+// the code itself, in any case or spacing, appears as the canonical code, so
+// a source listing "RU" and one listing "ru " show it once beside the names
+// other sources used; names are kept as written, minus surrounding space.
+func tokenDisplay(match countries.Match) string {
+	if match.MatchType == countries.MatchCode {
+		return match.Code
+	}
+	return strings.TrimSpace(match.Input)
+}
+
+// SourceRationale explains why a single source listed a country.
+type SourceRationale struct {
+	Source   string `json:"source"`
+	Category string `json:"category"`
+	Token    string `json:"token"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// SourceStats contains statistics for each source.
+type SourceStats struct {
+	Category     string    `json:"category"`
+	URL          string    `json:"url"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	ParseStatus  string    `json:"parse_status"`
+	RawCount     int       `json:"raw_count"`
+	MatchedCount int       `json:"matched_count"`
+	Error        string    `json:"error,omitempty"`
+	// FallbackReason and FallbackDetail say why the source fell back to
+	// built-in data; see scrapers.ScrapeResult.
+	FallbackReason string `json:"fallback_reason,omitempty"`
+	FallbackDetail string `json:"fallback_detail,omitempty"`
+	// ContentHash is the hash of the fetched content, empty if nothing was
+	// fetched; ContentChanged reports that it differs from the previous run.
+	ContentHash    string `json:"content_hash,omitempty"`
+	ContentChanged bool   `json:"content_changed,omitempty"`
+	// UnmatchedTokens lists the distinct raw tokens that didn't normalize to
+	// a country code, and FuzzyTokens the ones only accepted as fuzzy
+	// matches.
+	UnmatchedTokens []string `json:"unmatched_tokens,omitempty"`
+	FuzzyTokens     []string `json:"fuzzy_tokens,omitempty"`
+	// Cached reports that the result was reused from an earlier run's
+	// result cache; FetchedAt is then when it was originally fetched.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// Options controls a full aggregation run.
+type Options struct {
+	// Sources lists the scraper names to run (empty = all registered).
+	Sources []string
+	// Workers is the scraper concurrency (0 = pick automatically).
+	Workers int
+	Timeout time.Duration
+	Verbose bool
+	// PreferSources lists sources, most authoritative first, to order each
+	// country's Sources and Rationale by.
+	PreferSources []string
+	// OONILookback limits OONI measurements to this recent window
+	// (0 = the scraper's default start date).
+	OONILookback time.Duration
+	// AddContinents lists continents whose countries are added to the result
+	// regardless of what the sources report.
+	AddContinents []string
+	// Registry supplies the scrapers to run. Nil uses scrapers.DefaultRegistry
+	// with HTTPClient.
+	Registry *scrapers.Registry
+	// HTTPClient sends the default registry's requests, e.g. a
+	// scrapers.Cassette. Nil uses an http.Client honoring Timeout.
+	HTTPClient scrapers.HTTPClient
+	// ContentState holds the previous run's content hashes; results whose
+	// content differs are marked Changed. Nil skips the comparison.
+	ContentState *ContentState
+	// HeaderLines are added as comments to the text output's header.
+	HeaderLines []string
+	// Name, Version, and Description override the result's metadata;
+	// empty keeps DefaultName, DefaultVersion, and DefaultDescription.
+	// Version is used as given, so callers should check it with
+	// ValidateVersion.
+	Name        string
+	Version     string
+	Description string
+	// Normalizer resolves source tokens to codes. Nil uses
+	// countries.NewNormalizer.
+	Normalizer *countries.Normalizer
+	// SourceTimeouts overrides Timeout for the named sources. Sources not
+	// listed keep Timeout.
+	SourceTimeouts map[string]time.Duration
+	// ResultCache, with MaxAge > 0, supplies results for sources fetched
+	// less than MaxAge ago; only the rest are scraped, and their live
+	// results are stored back. Nil always fetches every source.
+	ResultCache *ResultCache
+	MaxAge      time.Duration
+	// Clock stamps the result and is passed to the scrapers and
+	// ResultCache. Nil uses the wall clock.
+	Clock clock.Clock
+	// Baseline is the path or URL of a previously published text or JSON
+	// list. If set, a scrapers.BaselineScraper reading it is registered and
+	// always run, so its countries stay listed until removed from it.
+	Baseline string
+}
+
+// Run scrapes the selected sources and returns the aggregated result with
+// default metadata populated.
+func Run(ctx context.Context, opts Options) *AggregationResult {
+	if opts.Timeout == 0 {
+		opts.Timeout = 60 * time.Second
+	}
+
+	registry := opts.Registry
+	if registry == nil {
+		// The shared client must allow the longest override; each scraper
+		// then enforces its own limit per request
+		clientTimeout := opts.Timeout
+		for _, d := range opts.SourceTimeouts {
+			if d > clientTimeout {
+				clientTimeout = d
+			}
+		}
+		var httpClient scrapers.HTTPClient = &http.Client{
+			Timeout: clientTimeout,
+		}
+		if opts.HTTPClient != nil {
+			httpClient = opts.HTTPClient
+		}
+		registry = scrapers.DefaultRegistry(httpClient)
+		if opts.Baseline != "" {
+			registry.Register(scrapers.NewBaselineScraper(opts.Baseline, httpClient))
+		}
+	} else if opts.Baseline != "" {
+		registry.Register(scrapers.NewBaselineScraper(opts.Baseline, nil))
+	}
+	var timeoutProblems []string
+	if len(opts.SourceTimeouts) > 0 {
+		timeoutProblems = applySourceTimeouts(registry, opts.Timeout, opts.SourceTimeouts)
+	}
+	if opts.Clock != nil {
+		applyClock(registry, opts.Clock)
+		if opts.ResultCache != nil {
+			opts.ResultCache.SetClock(opts.Clock)
+		}
+	}
+	if opts.OONILookback > 0 {
+		for _, s := range registry.All() {
+			if ooni, ok := s.(*scrapers.OONIScraper); ok {
+				ooni.SetLookback(opts.OONILookback)
+			}
+		}
+	}
+
+	sources := opts.Sources
+	if len(sources) == 0 {
+		sources = registry.Names()
+	}
+	// The baseline is read fresh every run rather than from the cache
+	sources = removeString(sources, scrapers.BaselineSource)
+
+	normalizer := opts.Normalizer
+	if normalizer == nil {
+		normalizer = countries.NewNormalizer()
+	}
+	unresolved := UnresolvedBuiltinNames(normalizer)
+
+	var results []*scrapers.ScrapeResult
+	if opts.ResultCache != nil && opts.MaxAge > 0 {
+		results, sources = splitCached(opts.ResultCache, sources, opts.MaxAge)
+	}
+	if opts.Baseline != "" {
+		sources = append(sources, scrapers.BaselineSource)
+	}
+	if len(sources) > 0 {
+		results = append(results, RunScrapers(ctx, registry, sources, opts.Workers, opts.Verbose)...)
+	}
+	if opts.ResultCache != nil {
+		opts.ResultCache.Store(results)
+	}
+	if opts.ContentState != nil {
+		MarkChanged(results, opts.ContentState)
+	}
+	agg := Aggregate(results, normalizer, opts.Verbose)
+	agg.Errors = append(agg.Errors, unresolved...)
+	agg.Errors = append(agg.Errors, timeoutProblems...)
+	if err := AddContinents(agg, opts.AddContinents, normalizer); err != nil {
+		agg.Errors = append(agg.Errors, err.Error())
+	}
+	if len(opts.PreferSources) > 0 {
+		OrderSources(agg, opts.PreferSources)
+	}
+	MarkBaselineOnly(agg)
+	agg.Partial = CancelledCount(agg) > 0
+
+	agg.SchemaVersion = SchemaVersion
+	agg.Name = orDefault(opts.Name, DefaultName)
+	agg.Version = orDefault(opts.Version, DefaultVersion)
+	agg.Description = orDefault(opts.Description, DefaultDescription)
+	agg.LastModified = clock.Or(opts.Clock).Now()
+	agg.Timestamp = agg.LastModified
+	agg.HeaderLines = opts.HeaderLines
+
+	return agg
+}
+
+// orDefault returns s, or def if s is empty.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// applySourceTimeouts gives every scraper that supports it a per-request
+// timeout: its override if it has one, otherwise def. It describes each
+// override given for a scraper that doesn't support one.
+func applySourceTimeouts(registry *scrapers.Registry, def time.Duration, overrides map[string]time.Duration) []string {
+	var problems []string
+	for _, s := range registry.All() {
+		t, ok := s.(interface{ SetTimeout(time.Duration) })
+		if !ok {
+			if _, listed := overrides[s.Name()]; listed {
+				problems = append(problems, fmt.Sprintf("%s doesn't support a timeout override", s.Name()))
+			}
+			continue
+		}
+		if d, ok := overrides[s.Name()]; ok {
+			t.SetTimeout(d)
+		} else {
+			t.SetTimeout(def)
+		}
+	}
+	return problems
+}
+
+// applyClock gives every scraper that supports it clk to stamp results with.
+func applyClock(registry *scrapers.Registry, clk clock.Clock) {
+	for _, s := range registry.All() {
+		if c, ok := s.(interface{ SetClock(clock.Clock) }); ok {
+			c.SetClock(clk)
+		}
+	}
+}
+
+// CancelledCount returns how many sources didn't finish because the run was
+// cancelled.
+func CancelledCount(agg *AggregationResult) int {
+	n := 0
+	for _, stats := range agg.SourceStats {
+		if stats.ParseStatus == scrapers.StatusCancelled {
+			n++
+		}
+	}
+	return n
+}
+
+// LiveCounts reports how many sources were fetched live and how many fell
+// back to built-in data.
+func LiveCounts(agg *AggregationResult) (live, fallback int) {
+	for _, stats := range agg.SourceStats {
+		switch {
+		case stats.ParseStatus == "success":
+			live++
+		case scrapers.UsedFallback(stats.ParseStatus, stats.RawCount):
+			fallback++
+		}
+	}
+	return live, fallback
+}
+
+// SourceNames returns the keys of agg.SourceStats in sorted order.
+func SourceNames(agg *AggregationResult) []string {
+	names := make([]string, 0, len(agg.SourceStats))
+	for name := range agg.SourceStats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnresolvedBuiltinNames checks every hardcoded country name the scrapers use
+// and describes each one the normalizer can't resolve. Such a name would
+// silently contribute nothing, so callers should surface these loudly.
+func UnresolvedBuiltinNames(normalizer *countries.Normalizer) []string {
+	lists := scrapers.BuiltinCountryNames()
+
+	listNames := make([]string, 0, len(lists))
+	for name := range lists {
+		listNames = append(listNames, name)
+	}
+	sort.Strings(listNames)
+
+	var problems []string
+	for _, list := range listNames {
+		for _, name := range lists[list] {
+			if _, ok := normalizer.Normalize(name); !ok {
+				problems = append(problems, fmt.Sprintf("built-in %s: %q does not normalize to a country code", list, name))
+			}
+		}
+	}
+	return problems
+}
+
+// AllSources returns the names of every registered scraper.
+func AllSources() []string {
+	return scrapers.DefaultRegistry(nil).Names()
+}
+
+// ParseSources splits a comma-separated source list, trimming whitespace.
+func ParseSources(s string) []string {
+	if s == "" {
+		return nil
+	}
+	sources := strings.Split(s, ",")
+	for i := range sources {
+		sources[i] = strings.TrimSpace(sources[i])
+	}
+	return sources
+}
+
+// Worker sizing for scrapers: up to scraperWorkersPerCPU per CPU, at most
+// maxScraperWorkers, and never more than the number of distinct hosts being
+// scraped so sources that share a site aren't fetched in parallel needlessly.
+const (
+	scraperWorkersPerCPU = 2
+	maxScraperWorkers    = 8
+)
+
+// RunScrapers runs the named scrapers concurrently and collects their results.
+// A workers value of 0 or less picks a count automatically; any count is
+// capped at the number of distinct hosts among the sources.
+func RunScrapers(ctx context.Context, registry *scrapers.Registry, sources []string, workers int, verbose bool) []*scrapers.ScrapeResult {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results []*scrapers.ScrapeResult
+	)
+
+	work := make(chan scrapers.Scraper, len(sources))
+	hosts := make(map[string]bool)
+
+	// Queue work
+	for _, name := range sources {
+		if s, ok := registry.Get(name); ok {
+			work <- s
+			hosts[scrapers.Host(s.URL())] = true
+		} else if verbose {
+			console.Printf("  [WARN] Unknown source: %s\n", name)
+		}
+	}
+	close(work)
+
+	if workers <= 0 {
+		workers = concurrency.AutoWorkers(len(work), scraperWorkersPerCPU, maxScraperWorkers)
+	}
+	if len(hosts) > 0 && workers > len(hosts) {
+		workers = len(hosts)
+	}
+	if verbose {
+		console.Printf("  Using %d workers for %d sources\n", workers, len(work))
+	}
+
+	// Start workers
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range work {
+				// Don't start new scrapes once the run is cancelled
+				if err := ctx.Err(); err != nil {
+					mu.Lock()
+					results = append(results, cancelledResult(s, err))
+					mu.Unlock()
+					continue
+				}
+
+				console.Printf("  Fetching: %s...\n", s.Name())
+
+				result, err := s.Scrape(ctx)
+				if err != nil {
+					if ctxErr := ctx.Err(); ctxErr != nil {
+						// Still counted, so the run shows as partial
+						mu.Lock()
+						results = append(results, cancelledResult(s, ctxErr))
+						mu.Unlock()
+						continue
+					}
+					fmt.Fprintf(os.Stderr, "    [ERROR] %s: %v\n", s.Name(), err)
+					continue
+				}
+
+				// A scrape cut short by cancellation can't be trusted
+				if ctxErr := ctx.Err(); ctxErr != nil && result.ParseStatus != "success" {
+					result = cancelledResult(s, ctxErr)
+				}
+
+				if verbose {
+					console.Printf("    Status: %s, Raw countries: %d\n", result.ParseStatus, len(result.RawCountries))
+				}
+
+				mu.Lock()
+				results = append(results, result)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+// cancelledResult records a source that didn't finish before cancellation.
+// Scrapers built on BaseScraper stamp it with their own clock.
+func cancelledResult(s scrapers.Scraper, err error) *scrapers.ScrapeResult {
+	var result *scrapers.ScrapeResult
+	if b, ok := s.(interface{ NewResult() *scrapers.ScrapeResult }); ok {
+		result = b.NewResult()
+	} else {
+		result = &scrapers.ScrapeResult{
+			Source:    s.Name(),
+			URL:       s.URL(),
+			FetchedAt: time.Now(),
+		}
+		if c, ok := s.(interface{ Category() string }); ok {
+			result.Category = c.Category()
+		}
+	}
+	result.ParseStatus = scrapers.StatusCancelled
+	result.Error = fmt.Sprintf("cancelled: %v", err)
+	return result
+}
+
+// Aggregate normalizes scrape results and merges them by country code.
+// Results are processed in source name order, not completion order, so the
+// per-country Sources, RawTokens, and Rationale and the Errors list come out
+// the same on every run.
+func Aggregate(results []*scrapers.ScrapeResult, normalizer *countries.Normalizer, verbose bool) *AggregationResult {
+	agg := &AggregationResult{
+		Timestamp:   time.Now(),
+		SourceStats: make(map[string]SourceStats),
+	}
+
+	results = append([]*scrapers.ScrapeResult(nil), results...)
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Source < results[j].Source
+	})
+
+	// Map from country code to provenance
+	countryMap := make(map[string]*CountryWithProvenance)
+
+	for _, result := range results {
+		stats := SourceStats{
+			Category:    result.Category,
+			URL:         result.URL,
+			FetchedAt:   result.FetchedAt,
+			ParseStatus: result.ParseStatus,
+			RawCount:    len(result.RawCountries),
+			Error:       result.Error,
+
+			FallbackReason: result.FallbackReason,
+			FallbackDetail: result.FallbackDetail,
+			ContentHash:    result.ContentHash,
+			ContentChanged: result.Changed,
+			Cached:         result.Cached,
+		}
+
+		entries := result.Entries()
+		tokens := make([]string, len(entries))
+		for i, entry := range entries {
+			tokens[i] = entry.Token
+		}
+		matches := normalizer.NormalizeBatch(tokens)
+
+		matched := 0
+		for i, entry := range entries {
+			raw := entry.Token
+			code, ok := matches[i].Code, matches[i].OK
+			if !ok {
+				if verbose {
+					console.Printf("    [SKIP] Could not normalize: %q\n", raw)
+				}
+				if !containsString(stats.UnmatchedTokens, raw) {
+					stats.UnmatchedTokens = append(stats.UnmatchedTokens, raw)
+				}
+				continue
+			}
+
+			matched++
+			if matches[i].MatchType == countries.MatchFuzzy {
+				if verbose {
+					console.Printf("    [FUZZY] %q -> %s (score %.2f)\n", raw, code, matches[i].Score)
+				}
+				if !containsString(stats.FuzzyTokens, raw) {
+					stats.FuzzyTokens = append(stats.FuzzyTokens, raw)
+				}
+			}
+
+			rationale := SourceRationale{
+				Source:   result.Source,
+				Category: result.Category,
+				Token:    tokenDisplay(matches[i]),
+				Reason:   entry.Reason,
+			}
+
+			existing, ok := countryMap[code]
+			if !ok {
+				existing = &CountryWithProvenance{
+					Alpha2: code,
+					Name:   normalizer.GetName(code),
+				}
+				countryMap[code] = existing
+			}
+			// A source counts once however many of its tokens matched, e.g.
+			// both a code and a name; Rationale keeps the first of them
+			if !containsString(existing.Sources, result.Source) {
+				existing.Sources = append(existing.Sources, result.Source)
+				existing.Rationale = append(existing.Rationale, rationale)
+			}
+			existing.addToken(result.Source, matches[i])
+		}
+
+		stats.MatchedCount = matched
+		agg.SourceStats[result.Source] = stats
+
+		if result.Error != "" {
+			agg.Errors = append(agg.Errors, fmt.Sprintf("%s: %s", result.Source, result.Error))
+		}
+	}
+
+	// Convert map to sorted slice
+	for _, c := range countryMap {
+		agg.Countries = append(agg.Countries, *c)
+	}
+
+	sort.Slice(agg.Countries, func(i, j int) bool {
+		return agg.Countries[i].Alpha2 < agg.Countries[j].Alpha2
+	})
+
+	agg.TotalCodes = len(agg.Countries)
+
+	return agg
+}
+
+// ContinentSourcePrefix starts the synthetic source name recorded for codes
+// added by AddContinents, e.g. "policy:continent:Asia".
+const ContinentSourcePrefix = "policy:continent:"
+
+// CategoryPolicy is the rationale category for codes added by policy rather
+// than by a scraped source.
+const CategoryPolicy = "policy"
+
+// AddContinents adds every country on the named continents to agg, recording
+// a policy:continent:<Name> source on each. Countries already listed keep
+// their existing sources. Unknown continent names are an error and nothing
+// is added.
+func AddContinents(agg *AggregationResult, names []string, normalizer *countries.Normalizer) error {
+	type continent struct {
+		name  string
+		codes []string
+	}
+	var selected []continent
+	for _, name := range names {
+		codes, canonical, ok := countries.ContinentCodes(name)
+		if !ok {
+			return fmt.Errorf("unknown continent %q (known: %s)", name, strings.Join(countries.Continents(), ", "))
+		}
+		selected = append(selected, continent{canonical, codes})
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(agg.Countries))
+	for i, c := range agg.Countries {
+		index[c.Alpha2] = i
+	}
+
+	for _, cont := range selected {
+		source := ContinentSourcePrefix + cont.name
+		rationale := SourceRationale{
+			Source:   source,
+			Category: CategoryPolicy,
+			Token:    cont.name,
+			Reason:   fmt.Sprintf("on continent %s", cont.name),
+		}
+
+		for _, code := range cont.codes {
+			if i, ok := index[code]; ok {
+				c := &agg.Countries[i]
+				if !containsString(c.Sources, source) {
+					c.Sources = append(c.Sources, source)
+					c.Rationale = append(c.Rationale, rationale)
+				}
+				continue
+			}
+
+			index[code] = len(agg.Countries)
+			agg.Countries = append(agg.Countries, CountryWithProvenance{
+				Alpha2:    code,
+				Name:      normalizer.GetName(code),
+				Sources:   []string{source},
+				Rationale: []SourceRationale{rationale},
+			})
+		}
+	}
+
+	sort.Slice(agg.Countries, func(i, j int) bool {
+		return agg.Countries[i].Alpha2 < agg.Countries[j].Alpha2
+	})
+	agg.TotalCodes = len(agg.Countries)
+
+	return nil
+}
+
+// MarkBaselineOnly sets BaselineOnly on each country the baseline source is
+// the only source for.
+func MarkBaselineOnly(agg *AggregationResult) {
+	for i := range agg.Countries {
+		c := &agg.Countries[i]
+		c.BaselineOnly = len(c.Sources) == 1 && c.Sources[0] == scrapers.BaselineSource
+	}
+}
+
+// OrderSources reorders each country's Sources and Rationale so that the
+// preferred sources come first, in the given order. Other sources keep their
+// relative order after them.
+func OrderSources(agg *AggregationResult, prefer []string) {
+	rank := make(map[string]int, len(prefer))
+	for i, name := range prefer {
+		rank[name] = i
+	}
+	rankOf := func(name string) int {
+		if r, ok := rank[name]; ok {
+			return r
+		}
+		return len(prefer)
+	}
+
+	for i := range agg.Countries {
+		c := &agg.Countries[i]
+		sort.SliceStable(c.Sources, func(a, b int) bool {
+			return rankOf(c.Sources[a]) < rankOf(c.Sources[b])
+		})
+		sort.SliceStable(c.Rationale, func(a, b int) bool {
+			return rankOf(c.Rationale[a].Source) < rankOf(c.Rationale[b].Source)
+		})
+	}
+}
+
+// removeString returns list without any occurrence of s.
+func removeString(list []string, s string) []string {
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
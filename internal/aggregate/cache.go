@@ -0,0 +1,105 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/clock"
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/scrapers"
+)
+
+// ResultCache keeps each source's last successful scrape result, so a run
+// can reuse the ones that are still recent instead of fetching them again.
+type ResultCache struct {
+	UpdatedAt time.Time                         `json:"updated_at"`
+	Results   map[string]*scrapers.ScrapeResult `json:"results"`
+
+	clock clock.Clock
+}
+
+// LoadResultCache reads a result cache file, returning an empty cache if it
+// doesn't exist.
+func LoadResultCache(path string) (*ResultCache, error) {
+	cache := &ResultCache{Results: make(map[string]*scrapers.ScrapeResult)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result cache: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, fmt.Errorf("failed to parse result cache: %w", err)
+	}
+	if cache.Results == nil {
+		cache.Results = make(map[string]*scrapers.ScrapeResult)
+	}
+
+	return cache, nil
+}
+
+// SetClock replaces the clock result ages are measured with. Nil means the
+// wall clock.
+func (c *ResultCache) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+// Save writes the cache to path.
+func (c *ResultCache) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Fresh returns a copy of the cached result for name, marked Cached, if it
+// was fetched less than maxAge ago.
+func (c *ResultCache) Fresh(name string, maxAge time.Duration) (*scrapers.ScrapeResult, bool) {
+	cached, ok := c.Results[name]
+	if !ok || clock.Since(clock.Or(c.clock), cached.FetchedAt) >= maxAge {
+		return nil, false
+	}
+
+	result := *cached
+	result.Cached = true
+	return &result, true
+}
+
+// Store records the live results among results. Fallback data, errors, and
+// results that were themselves served from the cache are skipped, so a
+// source is retried on the next run and its cache age is never extended.
+func (c *ResultCache) Store(results []*scrapers.ScrapeResult) {
+	for _, r := range results {
+		if r.Cached || r.ParseStatus != "success" {
+			continue
+		}
+		c.Results[r.Source] = r
+	}
+	c.UpdatedAt = clock.Or(c.clock).Now()
+}
+
+// splitCached returns the cached results for sources still younger than
+// maxAge, and the names of the sources that need fetching.
+func splitCached(cache *ResultCache, sources []string, maxAge time.Duration) ([]*scrapers.ScrapeResult, []string) {
+	var (
+		cached []*scrapers.ScrapeResult
+		stale  []string
+	)
+	for _, name := range sources {
+		if result, ok := cache.Fresh(name, maxAge); ok {
+			age := clock.Since(clock.Or(cache.clock), result.FetchedAt)
+			console.Printf("  Cached: %s (fetched %s ago)\n", name, age.Round(time.Second))
+			cached = append(cached, result)
+		} else {
+			stale = append(stale, name)
+		}
+	}
+	return cached, stale
+}
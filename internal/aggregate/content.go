@@ -0,0 +1,107 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/scrapers"
+)
+
+// Content change states reported by ContentStatus.
+const (
+	ContentNew        = "new"
+	ContentChanged    = "changed"
+	ContentUnchanged  = "unchanged"
+	ContentNotFetched = "not_fetched"
+)
+
+// ContentState records the last content hash fetched from each source, so a
+// run can tell whether an upstream list actually changed.
+type ContentState struct {
+	UpdatedAt time.Time         `json:"updated_at"`
+	Hashes    map[string]string `json:"hashes"`
+}
+
+// LoadContentState reads a content state file, returning empty state if it
+// doesn't exist.
+func LoadContentState(path string) (*ContentState, error) {
+	state := &ContentState{Hashes: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse content state: %w", err)
+	}
+	if state.Hashes == nil {
+		state.Hashes = make(map[string]string)
+	}
+
+	return state, nil
+}
+
+// Save writes the state to path.
+func (s *ContentState) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Record stores the content hash of every source fetched in agg. Sources
+// without a hash this run keep their previous one, so an outage isn't
+// mistaken for a change once the source is back.
+func (s *ContentState) Record(agg *AggregationResult) {
+	for name, stats := range agg.SourceStats {
+		if stats.ContentHash != "" {
+			s.Hashes[name] = stats.ContentHash
+		}
+	}
+	s.UpdatedAt = time.Now()
+}
+
+// ContentStatus compares a source's stats from this run with the state from
+// the previous one.
+func (s *ContentState) ContentStatus(name string, stats SourceStats) string {
+	previous, ok := s.Hashes[name]
+	switch {
+	case stats.ContentHash == "":
+		return ContentNotFetched
+	case !ok:
+		return ContentNew
+	case previous != stats.ContentHash:
+		return ContentChanged
+	default:
+		return ContentUnchanged
+	}
+}
+
+// Unchanged reports whether every source in agg was fetched with the same
+// content as in the previous run.
+func (s *ContentState) Unchanged(agg *AggregationResult) bool {
+	for name, stats := range agg.SourceStats {
+		if s.ContentStatus(name, stats) != ContentUnchanged {
+			return false
+		}
+	}
+	return true
+}
+
+// MarkChanged sets Changed on each result whose content hash differs from
+// the one recorded in state. Sources seen for the first time, or not fetched
+// this run, are left unchanged.
+func MarkChanged(results []*scrapers.ScrapeResult, state *ContentState) {
+	for _, r := range results {
+		previous, ok := state.Hashes[r.Source]
+		r.Changed = ok && r.ContentHash != "" && r.ContentHash != previous
+	}
+}
@@ -0,0 +1,250 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/console"
+)
+
+// Artifact file names used inside a run directory.
+const (
+	TextFile = "blocked_countries.txt"
+	JSONFile = "blocked_countries.json"
+	RunFile  = "run.json"
+)
+
+// RunInfo records when a run happened alongside its per-source stats.
+type RunInfo struct {
+	StartedAt       time.Time              `json:"started_at"`
+	FinishedAt      time.Time              `json:"finished_at"`
+	DurationSeconds float64                `json:"duration_seconds"`
+	Cancelled       bool                   `json:"cancelled,omitempty"`
+	Changed         bool                   `json:"changed"`
+	TotalCodes      int                    `json:"total_codes"`
+	SourceStats     map[string]SourceStats `json:"source_stats"`
+	Errors          []string               `json:"errors,omitempty"`
+}
+
+// NewRunInfo builds the run record for an aggregation that began at started.
+// Changed is left for the caller, which knows the previous output.
+func NewRunInfo(agg *AggregationResult, started time.Time, cancelled bool) *RunInfo {
+	finished := time.Now()
+	return &RunInfo{
+		StartedAt:       started,
+		FinishedAt:      finished,
+		DurationSeconds: finished.Sub(started).Seconds(),
+		Cancelled:       cancelled,
+		TotalCodes:      agg.TotalCodes,
+		SourceStats:     agg.SourceStats,
+		Errors:          agg.Errors,
+	}
+}
+
+// FormatText renders the result as a commented text file, one code per line.
+// HeaderLines follow the description in the header.
+func FormatText(agg *AggregationResult) []byte {
+	var txtBuilder strings.Builder
+	txtBuilder.WriteString("# " + agg.Name + "\n")
+	txtBuilder.WriteString("# Version: " + agg.Version + "\n")
+	txtBuilder.WriteString("# Last Modified: " + agg.LastModified.Format("2006-01-02 15:04:05 MST") + "\n")
+	txtBuilder.WriteString("#\n")
+	txtBuilder.WriteString("# " + strings.ReplaceAll(agg.Description, "\n", "\n# ") + "\n")
+	txtBuilder.WriteString("#\n")
+	if len(agg.HeaderLines) > 0 {
+		for _, line := range agg.HeaderLines {
+			txtBuilder.WriteString(commentLine(line))
+		}
+		txtBuilder.WriteString("#\n")
+	}
+	txtBuilder.WriteString("# Country codes (ISO 3166-1 alpha-2)\n")
+	txtBuilder.WriteString("#\n")
+
+	var codes []string
+	for _, c := range agg.Countries {
+		codes = append(codes, c.Alpha2)
+	}
+	txtBuilder.WriteString(strings.Join(codes, "\n"))
+	txtBuilder.WriteString("\n")
+
+	return []byte(txtBuilder.String())
+}
+
+// commentLine renders a header line as one or more comment lines, without
+// doubling a leading "#" the caller already wrote.
+func commentLine(line string) string {
+	var b strings.Builder
+	for _, l := range strings.Split(line, "\n") {
+		l = strings.TrimPrefix(strings.TrimPrefix(l, "#"), " ")
+		if l == "" {
+			b.WriteString("#\n")
+			continue
+		}
+		b.WriteString("# " + l + "\n")
+	}
+	return b.String()
+}
+
+// FormatJSON renders the result as indented JSON with full provenance.
+func FormatJSON(agg *AggregationResult) ([]byte, error) {
+	jsonContent, err := json.MarshalIndent(agg, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	return jsonContent, nil
+}
+
+// ReadJSON loads a result previously written by FormatJSON.
+func ReadJSON(path string) (*AggregationResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var agg AggregationResult
+	if err := json.Unmarshal(data, &agg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &agg, nil
+}
+
+// WriteOutputs writes the text and JSON renderings to disk. Either path may
+// be console.Stdout to write that rendering to standard output.
+func WriteOutputs(agg *AggregationResult, txtPath, jsonPath string) error {
+	// Ensure output directories exist
+	for _, path := range []string{txtPath, jsonPath} {
+		if console.IsStdout(path) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	if err := console.WriteFile(txtPath, FormatText(agg), 0644); err != nil {
+		return fmt.Errorf("failed to write txt file: %w", err)
+	}
+
+	jsonContent, err := FormatJSON(agg)
+	if err != nil {
+		return err
+	}
+
+	if err := console.WriteFile(jsonPath, jsonContent, 0644); err != nil {
+		return fmt.Errorf("failed to write JSON file: %w", err)
+	}
+
+	return nil
+}
+
+// SourceList is one source's contribution to a result after normalization.
+type SourceList struct {
+	Source      string   `json:"source"`
+	Category    string   `json:"category"`
+	ParseStatus string   `json:"parse_status"`
+	RawCount    int      `json:"raw_count"`
+	Codes       []string `json:"codes"`
+	// Tokens maps each code to the raw tokens that normalized to it.
+	Tokens          map[string][]string `json:"tokens"`
+	UnmatchedTokens []string            `json:"unmatched_tokens,omitempty"`
+	FuzzyTokens     []string            `json:"fuzzy_tokens,omitempty"`
+}
+
+// SourceLists splits a result back into per-source lists, in SourceNames
+// order. Codes added by policy rather than a source aren't included.
+func SourceLists(agg *AggregationResult) []*SourceList {
+	lists := make(map[string]*SourceList, len(agg.SourceStats))
+	for name, stats := range agg.SourceStats {
+		lists[name] = &SourceList{
+			Source:          name,
+			Category:        stats.Category,
+			ParseStatus:     stats.ParseStatus,
+			RawCount:        stats.RawCount,
+			Codes:           []string{},
+			Tokens:          make(map[string][]string),
+			UnmatchedTokens: stats.UnmatchedTokens,
+			FuzzyTokens:     stats.FuzzyTokens,
+		}
+	}
+
+	// Countries are sorted by code, so each list's Codes are too
+	for _, c := range agg.Countries {
+		for source, m := range c.TokensBySource {
+			l, ok := lists[source]
+			if !ok {
+				continue
+			}
+			l.Codes = append(l.Codes, c.Alpha2)
+			l.Tokens[c.Alpha2] = m.Tokens
+		}
+	}
+
+	out := make([]*SourceList, 0, len(lists))
+	for _, name := range SourceNames(agg) {
+		out = append(out, lists[name])
+	}
+	return out
+}
+
+// WritePerSource writes each source's list to <dir>/<slug>.json, where slug
+// is the lowercased source name with runs of other characters replaced by
+// hyphens, e.g. "eu-sanctions-list.json".
+func WritePerSource(agg *AggregationResult, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create per-source directory: %w", err)
+	}
+
+	for _, l := range SourceLists(agg) {
+		data, err := json.MarshalIndent(l, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", l.Source, err)
+		}
+		path := filepath.Join(dir, sourceSlug(l.Source)+".json")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s list: %w", l.Source, err)
+		}
+	}
+
+	return nil
+}
+
+// sourceSlug turns a source name into a file name stem.
+func sourceSlug(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return b.String()
+}
+
+// WriteRunDir writes the text, JSON, and run record files into dir.
+func WriteRunDir(agg *AggregationResult, info *RunInfo, dir string) error {
+	if err := WriteOutputs(agg, filepath.Join(dir, TextFile), filepath.Join(dir, JSONFile)); err != nil {
+		return err
+	}
+
+	runContent, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run record: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, RunFile), runContent, 0644); err != nil {
+		return fmt.Errorf("failed to write run record: %w", err)
+	}
+
+	return nil
+}
+
@@ -0,0 +1,104 @@
+package aggregate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/scrapers"
+)
+
+// FormatReport renders the result as a Markdown document for reviewers who
+// need to justify the list: every country with the authorities that list it
+// and why, followed by where each authority's data came from.
+func FormatReport(agg *AggregationResult) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", agg.Name)
+	fmt.Fprintf(&b, "Generated %s. %d countries from %d sources.\n\n",
+		agg.LastModified.Format("2006-01-02 15:04 MST"), agg.TotalCodes, len(agg.SourceStats))
+	if agg.Partial {
+		fmt.Fprintf(&b, "**Partial result:** the run was interrupted and %d sources didn't finish, so countries only they list are missing.\n\n", CancelledCount(agg))
+	}
+
+	b.WriteString("## Countries\n\n")
+	b.WriteString("| Code | Country | Listed by |\n")
+	b.WriteString("|------|---------|-----------|\n")
+	for _, c := range agg.Countries {
+		authorities := make([]string, len(c.Sources))
+		for i, source := range c.Sources {
+			authorities[i] = reportAuthority(source)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", c.Alpha2, markdownCell(c.Name), markdownCell(strings.Join(authorities, ", ")))
+	}
+
+	for _, c := range agg.Countries {
+		fmt.Fprintf(&b, "\n### %s (%s)\n\n", c.Name, c.Alpha2)
+		for _, r := range c.Rationale {
+			fmt.Fprintf(&b, "- **%s**: %s\n", reportAuthority(r.Source), reportReason(r))
+		}
+		if c.BaselineOnly {
+			b.WriteString("\nNo current source lists this country; it is kept because the previously published list still does.\n")
+		}
+	}
+
+	b.WriteString("\n## Sources\n\n")
+	for _, name := range SourceNames(agg) {
+		stats := agg.SourceStats[name]
+		fmt.Fprintf(&b, "- **%s**", name)
+		if stats.URL != "" {
+			fmt.Fprintf(&b, " (%s)", stats.URL)
+		}
+		fmt.Fprintf(&b, ": %s\n", reportSourceStatus(stats))
+	}
+
+	return []byte(b.String())
+}
+
+// WriteReport writes FormatReport's document to path, or to stdout if path
+// is console.Stdout.
+func WriteReport(agg *AggregationResult, path string) error {
+	if err := console.WriteFile(path, FormatReport(agg), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// reportAuthority names a source for the report; policy sources read as the
+// rule that added the country.
+func reportAuthority(source string) string {
+	if continent, ok := strings.CutPrefix(source, ContinentSourcePrefix); ok {
+		return "Policy: all of " + continent
+	}
+	return source
+}
+
+// reportReason explains a rationale, falling back to the token the source
+// used when it gave no reason.
+func reportReason(r SourceRationale) string {
+	if r.Reason != "" {
+		return r.Reason
+	}
+	return fmt.Sprintf("listed as %q", r.Token)
+}
+
+// reportSourceStatus says how a source's data was obtained on this run.
+func reportSourceStatus(stats SourceStats) string {
+	switch {
+	case stats.ParseStatus == scrapers.StatusCancelled:
+		return "didn't finish (cancelled)"
+	case stats.FallbackReason != "":
+		return fmt.Sprintf("built-in list used (%s: %s)", stats.FallbackReason, stats.FallbackDetail)
+	case stats.Cached:
+		return "cached result fetched " + stats.FetchedAt.Format("2006-01-02 15:04 MST")
+	case stats.Error != "":
+		return "error: " + stats.Error
+	default:
+		return "fetched " + stats.FetchedAt.Format("2006-01-02 15:04 MST")
+	}
+}
+
+// markdownCell escapes the characters that would break a table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,171 @@
+package aggregate
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// SchemaVersion identifies the shape of the JSON output. Bump it whenever a
+// field is removed, renamed, or changes type so consumers can detect it.
+const SchemaVersion = 1
+
+// Schema is the JSON Schema for blocked_countries.json.
+const Schema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://github.com/mattsblocklist/tae/schema/blocked_countries.json",
+  "title": "Aggregated country blocklist",
+  "type": "object",
+  "required": ["schema_version", "name", "version", "last_modified", "timestamp", "total_codes", "countries", "source_stats"],
+  "properties": {
+    "schema_version": {"type": "integer", "const": 1},
+    "name": {"type": "string", "minLength": 1},
+    "version": {"type": "string", "minLength": 1},
+    "description": {"type": "string"},
+    "last_modified": {"type": "string", "format": "date-time"},
+    "timestamp": {"type": "string", "format": "date-time"},
+    "total_codes": {"type": "integer", "minimum": 0},
+    "partial": {"type": "boolean"},
+    "countries": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["alpha2", "name", "sources"],
+        "properties": {
+          "alpha2": {"type": "string", "pattern": "^[A-Z]{2}$"},
+          "name": {"type": "string", "minLength": 1},
+          "sources": {"type": "array", "minItems": 1, "items": {"type": "string"}},
+          "raw_tokens": {"type": "array", "items": {"type": "string"}},
+          "rationale": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["source", "category", "token"],
+              "properties": {
+                "source": {"type": "string"},
+                "category": {"type": "string"},
+                "token": {"type": "string"},
+                "reason": {"type": "string"}
+              }
+            }
+          },
+          "match_types": {
+            "type": "object",
+            "additionalProperties": {"enum": ["code", "exact_name", "alias", "normalized", "fuzzy"]}
+          },
+          "match_scores": {
+            "type": "object",
+            "additionalProperties": {"type": "number", "minimum": 0, "maximum": 1}
+          },
+          "tokens_by_source": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "object",
+              "required": ["tokens", "count"],
+              "properties": {
+                "tokens": {"type": "array", "items": {"type": "string"}},
+                "count": {"type": "integer", "minimum": 1}
+              }
+            }
+          },
+          "baseline_only": {"type": "boolean"}
+        }
+      }
+    },
+    "source_stats": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "object",
+        "required": ["category", "url", "fetched_at", "parse_status", "raw_count", "matched_count"],
+        "properties": {
+          "category": {"type": "string"},
+          "url": {"type": "string"},
+          "fetched_at": {"type": "string", "format": "date-time"},
+          "parse_status": {"type": "string", "minLength": 1},
+          "raw_count": {"type": "integer", "minimum": 0},
+          "matched_count": {"type": "integer", "minimum": 0},
+          "error": {"type": "string"},
+          "content_hash": {"type": "string"},
+          "content_changed": {"type": "boolean"},
+          "cached": {"type": "boolean"},
+          "fallback_reason": {"enum": ["fetch_error", "parse_empty", "not_json", "error_page"]},
+          "fallback_detail": {"type": "string"},
+          "unmatched_tokens": {"type": "array", "items": {"type": "string"}},
+          "fuzzy_tokens": {"type": "array", "items": {"type": "string"}}
+        }
+      }
+    },
+    "errors": {"type": "array", "items": {"type": "string"}}
+  }
+}
+`
+
+var alpha2Pattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// Validate checks agg against the rules in Schema before it is serialized
+// and returns a description of each violation.
+func Validate(agg *AggregationResult) []string {
+	var problems []string
+
+	if agg.SchemaVersion != SchemaVersion {
+		problems = append(problems, fmt.Sprintf("schema_version is %d, want %d", agg.SchemaVersion, SchemaVersion))
+	}
+	if agg.Name == "" {
+		problems = append(problems, "name is empty")
+	}
+	if agg.Version == "" {
+		problems = append(problems, "version is empty")
+	}
+	if agg.LastModified.IsZero() {
+		problems = append(problems, "last_modified is not set")
+	}
+	if agg.Timestamp.IsZero() {
+		problems = append(problems, "timestamp is not set")
+	}
+	if agg.TotalCodes != len(agg.Countries) {
+		problems = append(problems, fmt.Sprintf("total_codes is %d but there are %d countries", agg.TotalCodes, len(agg.Countries)))
+	}
+
+	for i, c := range agg.Countries {
+		if !alpha2Pattern.MatchString(c.Alpha2) {
+			problems = append(problems, fmt.Sprintf("countries[%d].alpha2 %q is not two uppercase letters", i, c.Alpha2))
+		}
+		if c.Name == "" {
+			problems = append(problems, fmt.Sprintf("countries[%d] (%s) has no name", i, c.Alpha2))
+		}
+		if len(c.Sources) == 0 {
+			problems = append(problems, fmt.Sprintf("countries[%d] (%s) has no sources", i, c.Alpha2))
+		}
+		if dup := firstDuplicate(c.Sources); dup != "" {
+			problems = append(problems, fmt.Sprintf("countries[%d] (%s) lists source %q more than once", i, c.Alpha2, dup))
+		}
+	}
+
+	if agg.SourceStats == nil {
+		problems = append(problems, "source_stats is missing")
+	}
+	for _, name := range SourceNames(agg) {
+		stats := agg.SourceStats[name]
+		if stats.ParseStatus == "" {
+			problems = append(problems, fmt.Sprintf("source_stats[%s].parse_status is empty", name))
+		}
+		if stats.RawCount < 0 || stats.MatchedCount < 0 {
+			problems = append(problems, fmt.Sprintf("source_stats[%s] has negative counts", name))
+		}
+	}
+
+	return problems
+}
+
+// firstDuplicate returns the first string that appears twice in list, or ""
+// if none does.
+func firstDuplicate(list []string) string {
+	seen := make(map[string]bool, len(list))
+	for _, s := range list {
+		if seen[s] {
+			return s
+		}
+		seen[s] = true
+	}
+	return ""
+}
+
@@ -0,0 +1,60 @@
+package aggregate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// VersionAuto is the version value that derives the version from the
+// previously published result; see NextVersion.
+const VersionAuto = "auto"
+
+var semverPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// ValidateVersion checks that v is a semantic version such as 1.4.0 or
+// 2.0.0-rc.1.
+func ValidateVersion(v string) error {
+	if !semverPattern.MatchString(v) {
+		return fmt.Errorf("version %q is not a semantic version (MAJOR.MINOR.PATCH)", v)
+	}
+	return nil
+}
+
+// NextVersion returns the version to publish agg as, given the previously
+// published result: the previous version if the country codes are the
+// same, otherwise the previous version with its patch number bumped and
+// any pre-release or build suffix dropped. With no previous result, or one
+// whose version isn't a semantic version, it is DefaultVersion.
+func NextVersion(previous, agg *AggregationResult) string {
+	if previous == nil {
+		return DefaultVersion
+	}
+	m := semverPattern.FindStringSubmatch(previous.Version)
+	if m == nil {
+		return DefaultVersion
+	}
+	if SameCodes(previous, agg) {
+		return previous.Version
+	}
+
+	patch, err := strconv.Atoi(m[3])
+	if err != nil {
+		return DefaultVersion
+	}
+	return fmt.Sprintf("%s.%s.%d", m[1], m[2], patch+1)
+}
+
+// SameCodes reports whether a and b list the same countries; both are
+// sorted by code.
+func SameCodes(a, b *AggregationResult) bool {
+	if len(a.Countries) != len(b.Countries) {
+		return false
+	}
+	for i := range a.Countries {
+		if a.Countries[i].Alpha2 != b.Countries[i].Alpha2 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,23 @@
+// Package concurrency holds helpers for sizing worker pools.
+package concurrency
+
+import "runtime"
+
+// AutoWorkers picks a worker count for items units of I/O-bound work.
+// It allows perCPU workers for each usable CPU (GOMAXPROCS), never more than
+// there are items, and never more than limit so a single remote server isn't
+// flooded. The result is at least 1.
+func AutoWorkers(items, perCPU, limit int) int {
+	n := runtime.GOMAXPROCS(0) * perCPU
+	if n > items {
+		n = items
+	}
+	if limit > 0 && n > limit {
+		n = limit
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
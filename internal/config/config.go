@@ -4,6 +4,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -11,26 +12,41 @@ import (
 
 // Config represents the application configuration.
 type Config struct {
-	UniFi  UniFiConfig  `yaml:"unifi"`
-	GitHub GitHubConfig `yaml:"github"`
+	UniFi  UniFiConfig  `yaml:"unifi" toml:"unifi"`
+	GitHub GitHubConfig `yaml:"github" toml:"github"`
+	// Controllers lists independent controllers to manage together.
+	Controllers []ControllerProfile `yaml:"controllers" toml:"controllers"`
 }
 
 // UniFiConfig holds UniFi controller connection settings.
 type UniFiConfig struct {
-	Host          string `yaml:"host"`
-	Username      string `yaml:"username"`
-	Password      string `yaml:"password"`
-	Site          string `yaml:"site"`
-	SkipTLSVerify bool   `yaml:"skip_tls_verify"`
+	Host          string            `yaml:"host" toml:"host"`
+	Username      string            `yaml:"username" toml:"username"`
+	Password      string            `yaml:"password" toml:"password"`
+	Site          string            `yaml:"site" toml:"site"`
+	SkipTLSVerify bool              `yaml:"skip_tls_verify" toml:"skip_tls_verify"`
+	CACertFile    string            `yaml:"ca_cert_file" toml:"ca_cert_file"`
+	UserAgent     string            `yaml:"user_agent" toml:"user_agent"`
+	Headers       map[string]string `yaml:"headers" toml:"headers"`
+	// DisableCSRF stops the client sending X-Csrf-Token, for reverse
+	// proxies that handle CSRF themselves.
+	DisableCSRF bool `yaml:"disable_csrf" toml:"disable_csrf"`
+}
+
+// ControllerProfile is one named controller in a multi-controller config.
+type ControllerProfile struct {
+	Name        string `yaml:"name" toml:"name"`
+	UniFiConfig `yaml:",inline" toml:",inline"`
 }
 
 // GitHubConfig holds GitHub integration settings.
 type GitHubConfig struct {
-	Repo  string `yaml:"repo"`
-	Token string `yaml:"token"`
+	Repo  string `yaml:"repo" toml:"repo"`
+	Token string `yaml:"token" toml:"token"`
 }
 
-// Load reads configuration from a YAML file and expands environment variables.
+// Load reads configuration from a YAML file, or a TOML file if path ends in
+// .toml, and expands environment variables.
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -41,7 +57,12 @@ func Load(path string) (*Config, error) {
 	expanded := os.ExpandEnv(string(data))
 
 	var cfg Config
-	if err := yaml.Unmarshal([]byte(expanded), &cfg); err != nil {
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = unmarshalTOML([]byte(expanded), &cfg)
+	} else {
+		err = yaml.Unmarshal([]byte(expanded), &cfg)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
@@ -50,6 +71,24 @@ func Load(path string) (*Config, error) {
 		cfg.UniFi.Site = "default"
 	}
 
+	seen := make(map[string]bool)
+	for i := range cfg.Controllers {
+		c := &cfg.Controllers[i]
+		if c.Host == "" {
+			return nil, fmt.Errorf("controllers[%d]: host is required", i)
+		}
+		if c.Name == "" {
+			c.Name = c.Host
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("controllers[%d]: duplicate name %q", i, c.Name)
+		}
+		seen[c.Name] = true
+		if c.Site == "" {
+			c.Site = "default"
+		}
+	}
+
 	return &cfg, nil
 }
 
@@ -62,6 +101,8 @@ func LoadFromEnv() (*Config, error) {
 			Password:      getEnv("UNIFI_PASSWORD", ""),
 			Site:          getEnv("UNIFI_SITE", "default"),
 			SkipTLSVerify: getEnvBool("UNIFI_SKIP_TLS_VERIFY", false),
+			CACertFile:    getEnv("UNIFI_CA_CERT_FILE", ""),
+			UserAgent:     getEnv("UNIFI_USER_AGENT", ""),
 		},
 		GitHub: GitHubConfig{
 			Repo:  getEnv("GITHUB_REPO", ""),
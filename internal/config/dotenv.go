@@ -0,0 +1,111 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultEnvFile is the .env file read when no other path is given.
+const DefaultEnvFile = ".env"
+
+// LoadDotEnv reads KEY=VALUE lines from path into the process environment so
+// that env-based configuration picks them up. Variables already set in the
+// environment are left alone. A missing file is not an error.
+//
+// Blank lines and lines starting with # are skipped, an optional "export "
+// prefix is allowed, double-quoted values support Go escapes (\n, \"), and
+// single-quoted values are taken literally. A # comment may follow any value.
+func LoadDotEnv(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open env file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNum)
+		}
+
+		value, err := parseEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("%s:%d: failed to set %s: %w", path, lineNum, key, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	return nil
+}
+
+// parseEnvValue unquotes a .env value. A quoted value ends at its closing
+// quote and may be followed by a comment; unquoted values end at a " #"
+// comment.
+func parseEnvValue(value string) (string, error) {
+	if value != "" && (value[0] == '"' || value[0] == '\'') {
+		end := closingQuote(value)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+		if rest := strings.TrimSpace(value[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected %q after quoted value", rest)
+		}
+
+		quoted := value[:end+1]
+		if quoted[0] == '\'' {
+			return quoted[1 : len(quoted)-1], nil
+		}
+		unquoted, err := strconv.Unquote(quoted)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted value: %w", err)
+		}
+		return unquoted, nil
+	}
+
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value, nil
+}
+
+// closingQuote returns the index of the quote closing the one value starts
+// with, or -1. Double-quoted values may escape a quote with a backslash.
+func closingQuote(value string) int {
+	quote := value[0]
+	for i := 1; i < len(value); i++ {
+		switch {
+		case quote == '"' && value[i] == '\\':
+			i++
+		case value[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
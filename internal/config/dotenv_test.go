@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadDotEnv(t *testing.T) {
+	content := `# controller credentials
+TAE_TEST_PLAIN=admin
+TAE_TEST_COMMENT=admin # the default user
+export TAE_TEST_EXPORT=exported
+TAE_TEST_DOUBLE="pass word"
+TAE_TEST_DOUBLE_COMMENT="secret" # rotate monthly
+TAE_TEST_ESCAPES="line\nnext \"quoted\""
+TAE_TEST_SINGLE='$literal\n'
+TAE_TEST_SINGLE_COMMENT='value' # note
+TAE_TEST_HASH="a # not a comment"
+TAE_TEST_EMPTY=
+TAE_TEST_SET=from-file
+
+TAE_TEST_SPACED = spaced
+`
+	path := writeConfig(t, ".env", content)
+
+	keys := []string{
+		"TAE_TEST_PLAIN", "TAE_TEST_COMMENT", "TAE_TEST_EXPORT", "TAE_TEST_DOUBLE",
+		"TAE_TEST_DOUBLE_COMMENT", "TAE_TEST_ESCAPES", "TAE_TEST_SINGLE",
+		"TAE_TEST_SINGLE_COMMENT", "TAE_TEST_HASH", "TAE_TEST_EMPTY", "TAE_TEST_SPACED",
+	}
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	t.Setenv("TAE_TEST_SET", "from-env")
+
+	if err := LoadDotEnv(path); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"TAE_TEST_PLAIN":          "admin",
+		"TAE_TEST_COMMENT":        "admin",
+		"TAE_TEST_EXPORT":         "exported",
+		"TAE_TEST_DOUBLE":         "pass word",
+		"TAE_TEST_DOUBLE_COMMENT": "secret",
+		"TAE_TEST_ESCAPES":        "line\nnext \"quoted\"",
+		"TAE_TEST_SINGLE":         `$literal\n`,
+		"TAE_TEST_SINGLE_COMMENT": "value",
+		"TAE_TEST_HASH":           "a # not a comment",
+		"TAE_TEST_EMPTY":          "",
+		"TAE_TEST_SPACED":         "spaced",
+		// Already set in the environment, so the file doesn't override it
+		"TAE_TEST_SET": "from-env",
+	}
+	for key, value := range want {
+		got, ok := os.LookupEnv(key)
+		if !ok || got != value {
+			t.Errorf("%s = %q (set %v), want %q", key, got, ok, value)
+		}
+	}
+}
+
+func TestLoadDotEnvMissingFile(t *testing.T) {
+	if err := LoadDotEnv(t.TempDir() + "/missing.env"); err != nil {
+		t.Errorf("missing file: %v, want nil", err)
+	}
+}
+
+func TestLoadDotEnvErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "no equals", content: "TAE_TEST_X", want: ":1: expected KEY=VALUE"},
+		{name: "no key", content: "=value", want: ":1: expected KEY=VALUE"},
+		{name: "unterminated double", content: "TAE_TEST_X=\"secret", want: ":1: unterminated quoted value"},
+		{name: "unterminated single", content: "\nTAE_TEST_X='secret", want: ":2: unterminated quoted value"},
+		{name: "text after quote", content: "TAE_TEST_X=\"a\" b", want: `:1: unexpected "b" after quoted value`},
+		{name: "bad escape", content: `TAE_TEST_X="\q"`, want: ":1: invalid quoted value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := LoadDotEnv(writeConfig(t, ".env", tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
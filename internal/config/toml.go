@@ -0,0 +1,393 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// unmarshalTOML decodes a TOML document into v, a pointer to a struct whose
+// fields carry toml tags. It supports the subset config files need: bare and
+// quoted keys, dotted keys, [tables], [[arrays of tables]], inline tables,
+// strings, booleans, and integers. Multi-line strings, arrays of values,
+// floats, and dates are rejected rather than misread.
+func unmarshalTOML(data []byte, v interface{}) error {
+	doc, err := parseTOML(string(data))
+	if err != nil {
+		return err
+	}
+	return decodeTOML(doc, reflect.ValueOf(v).Elem(), "")
+}
+
+// parseTOML parses a document into nested maps; arrays of tables become
+// []map[string]interface{}.
+func parseTOML(data string) (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	current := root
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		p := &tomlLine{s: scanner.Text()}
+		p.skipSpace()
+		if p.done() {
+			continue
+		}
+
+		var err error
+		switch {
+		case strings.HasPrefix(p.rest(), "[["):
+			p.pos += 2
+			current, err = p.header(root, "]]", true)
+		case strings.HasPrefix(p.rest(), "["):
+			p.pos++
+			current, err = p.header(root, "]", false)
+		default:
+			err = p.keyValue(current)
+		}
+		if err == nil {
+			err = p.end()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// tomlLine is a cursor over one line of a TOML document.
+type tomlLine struct {
+	s   string
+	pos int
+}
+
+func (p *tomlLine) rest() string { return p.s[p.pos:] }
+
+// done reports whether only a comment or nothing is left.
+func (p *tomlLine) done() bool {
+	return p.pos >= len(p.s) || p.s[p.pos] == '#'
+}
+
+func (p *tomlLine) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// end fails if anything but a comment follows.
+func (p *tomlLine) end() error {
+	p.skipSpace()
+	if !p.done() {
+		return fmt.Errorf("unexpected %q", p.rest())
+	}
+	return nil
+}
+
+// expect consumes c after optional space.
+func (p *tomlLine) expect(c string) error {
+	p.skipSpace()
+	if !strings.HasPrefix(p.rest(), c) {
+		return fmt.Errorf("expected %q at %q", c, p.rest())
+	}
+	p.pos += len(c)
+	return nil
+}
+
+// header parses a [table] or [[array]] header up to closer and returns the
+// table that following keys belong to.
+func (p *tomlLine) header(root map[string]interface{}, closer string, array bool) (map[string]interface{}, error) {
+	keys, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(closer); err != nil {
+		return nil, err
+	}
+
+	parent, err := descend(root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+
+	if array {
+		table := make(map[string]interface{})
+		switch existing := parent[last].(type) {
+		case nil:
+			parent[last] = []map[string]interface{}{table}
+		case []map[string]interface{}:
+			parent[last] = append(existing, table)
+		default:
+			return nil, fmt.Errorf("%s is not an array of tables", strings.Join(keys, "."))
+		}
+		return table, nil
+	}
+	return descend(root, keys)
+}
+
+// descend walks keys from table, creating missing tables. A key holding an
+// array of tables resolves to its last element.
+func descend(table map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for _, k := range keys {
+		switch next := table[k].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			table[k] = child
+			table = child
+		case map[string]interface{}:
+			table = next
+		case []map[string]interface{}:
+			table = next[len(next)-1]
+		default:
+			return nil, fmt.Errorf("%s is not a table", k)
+		}
+	}
+	return table, nil
+}
+
+// key parses a possibly dotted key.
+func (p *tomlLine) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		var k string
+		var err error
+		switch {
+		case strings.HasPrefix(p.rest(), `"`), strings.HasPrefix(p.rest(), "'"):
+			k, err = p.str()
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && isBareKeyChar(p.s[p.pos]) {
+				p.pos++
+			}
+			k = p.s[start:p.pos]
+			if k == "" {
+				err = fmt.Errorf("expected a key at %q", p.rest())
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+
+		p.skipSpace()
+		if !strings.HasPrefix(p.rest(), ".") {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// keyValue parses "key = value" into table.
+func (p *tomlLine) keyValue(table map[string]interface{}) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+
+	parent, err := descend(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, exists := parent[last]; exists {
+		return fmt.Errorf("duplicate key %s", strings.Join(keys, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// value parses a string, boolean, integer, or inline table.
+func (p *tomlLine) value() (interface{}, error) {
+	p.skipSpace()
+	rest := p.rest()
+	switch {
+	case strings.HasPrefix(rest, `"""`), strings.HasPrefix(rest, "'''"):
+		return nil, fmt.Errorf("multi-line strings aren't supported")
+	case strings.HasPrefix(rest, `"`), strings.HasPrefix(rest, "'"):
+		return p.str()
+	case strings.HasPrefix(rest, "{"):
+		return p.inlineTable()
+	case strings.HasPrefix(rest, "["):
+		return nil, fmt.Errorf("arrays aren't supported")
+	}
+
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(" \t,}#", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	token := p.s[start:p.pos]
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	n, err := strconv.ParseInt(token, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported value %q", token)
+	}
+	return n, nil
+}
+
+// str parses a basic ("...") or literal ('...') string.
+func (p *tomlLine) str() (string, error) {
+	quote := p.s[p.pos]
+	for i := p.pos + 1; i < len(p.s); i++ {
+		switch {
+		case quote == '"' && p.s[i] == '\\':
+			i++
+		case p.s[i] == quote:
+			raw := p.s[p.pos : i+1]
+			p.pos = i + 1
+			if quote == '\'' {
+				return raw[1 : len(raw)-1], nil
+			}
+			s, err := strconv.Unquote(raw)
+			if err != nil {
+				return "", fmt.Errorf("invalid string %s", raw)
+			}
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unterminated string %s", p.rest())
+}
+
+// inlineTable parses "{ k = v, ... }".
+func (p *tomlLine) inlineTable() (map[string]interface{}, error) {
+	p.pos++
+	table := make(map[string]interface{})
+	p.skipSpace()
+	if strings.HasPrefix(p.rest(), "}") {
+		p.pos++
+		return table, nil
+	}
+	for {
+		if err := p.keyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch {
+		case strings.HasPrefix(p.rest(), ","):
+			p.pos++
+		case strings.HasPrefix(p.rest(), "}"):
+			p.pos++
+			return table, nil
+		default:
+			return nil, fmt.Errorf("expected \",\" or \"}\" at %q", p.rest())
+		}
+	}
+}
+
+// decodeTOML stores a parsed table into the struct v, matching keys to toml
+// tags. Embedded structs and ",inline" fields take keys from the same table;
+// unknown keys are ignored, as with YAML.
+func decodeTOML(table map[string]interface{}, v reflect.Value, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if field.Anonymous || opts == "inline" {
+			if err := decodeTOML(table, v.Field(i), path); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		raw, ok := table[name]
+		if !ok {
+			continue
+		}
+		if err := decodeTOMLValue(raw, v.Field(i), path+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeTOMLValue stores one parsed value into the field v, reporting
+// mismatches by path.
+func decodeTOMLValue(raw interface{}, v reflect.Value, path string) error {
+	mismatch := func(want string) error {
+		return fmt.Errorf("%s: expected %s, got %T", path, want, raw)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			return mismatch("a string")
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if !ok {
+			return mismatch("a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := raw.(int64)
+		if !ok {
+			return mismatch("an integer")
+		}
+		v.SetInt(n)
+	case reflect.Map:
+		table, ok := raw.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return mismatch("a table")
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(table))
+		for k, elem := range table {
+			ev := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeTOMLValue(elem, ev, path+"."+k); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(k), ev)
+		}
+		v.Set(m)
+	case reflect.Struct:
+		table, ok := raw.(map[string]interface{})
+		if !ok {
+			return mismatch("a table")
+		}
+		return decodeTOML(table, v, path+".")
+	case reflect.Slice:
+		tables, ok := raw.([]map[string]interface{})
+		if !ok || v.Type().Elem().Kind() != reflect.Struct {
+			return mismatch("an array of tables")
+		}
+		s := reflect.MakeSlice(v.Type(), len(tables), len(tables))
+		for i, table := range tables {
+			if err := decodeTOML(table, s.Index(i), fmt.Sprintf("%s[%d].", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	default:
+		return fmt.Errorf("%s: unsupported field type %s", path, v.Type())
+	}
+	return nil
+}
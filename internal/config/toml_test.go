@@ -0,0 +1,153 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const tomlConfig = `# Region blocking controllers
+[unifi]
+host = "https://unifi.example.com"
+username = "admin"
+password = "${TAE_TEST_PASSWORD}"
+skip_tls_verify = true
+
+[github]
+repo = 'owner/repo'
+
+[[controllers]]
+name = "home"
+host = "https://10.0.0.1"
+username = "admin"
+password = "secret"
+headers = { "X-Proxy-Auth" = "token" }
+
+[[controllers]]
+host = "https://10.0.0.2"
+username = "admin"
+password = "secret"
+site = "branch"
+disable_csrf = true
+`
+
+const yamlConfig = `unifi:
+  host: https://unifi.example.com
+  username: admin
+  password: ${TAE_TEST_PASSWORD}
+  skip_tls_verify: true
+github:
+  repo: owner/repo
+controllers:
+  - name: home
+    host: https://10.0.0.1
+    username: admin
+    password: secret
+    headers:
+      X-Proxy-Auth: token
+  - host: https://10.0.0.2
+    username: admin
+    password: secret
+    site: branch
+    disable_csrf: true
+`
+
+func TestLoadTOMLMatchesYAML(t *testing.T) {
+	t.Setenv("TAE_TEST_PASSWORD", "from-env")
+
+	fromTOML, err := Load(writeConfig(t, "config.toml", tomlConfig))
+	if err != nil {
+		t.Fatalf("TOML: %v", err)
+	}
+	fromYAML, err := Load(writeConfig(t, "config.yaml", yamlConfig))
+	if err != nil {
+		t.Fatalf("YAML: %v", err)
+	}
+	if !reflect.DeepEqual(fromTOML, fromYAML) {
+		t.Errorf("TOML and YAML configs differ:\n toml: %+v\n yaml: %+v", fromTOML, fromYAML)
+	}
+
+	if fromTOML.UniFi.Password != "from-env" {
+		t.Errorf("password = %q, want the expanded environment variable", fromTOML.UniFi.Password)
+	}
+	if fromTOML.UniFi.Site != "default" {
+		t.Errorf("unifi site = %q, want the default", fromTOML.UniFi.Site)
+	}
+	if len(fromTOML.Controllers) != 2 {
+		t.Fatalf("controllers = %d, want 2", len(fromTOML.Controllers))
+	}
+	home, branch := fromTOML.Controllers[0], fromTOML.Controllers[1]
+	if home.Site != "default" || home.Headers["X-Proxy-Auth"] != "token" {
+		t.Errorf("home = %+v", home)
+	}
+	if branch.Name != branch.Host || branch.Site != "branch" || !branch.DisableCSRF {
+		t.Errorf("branch = %+v", branch)
+	}
+}
+
+func TestLoadTOMLUppercaseExtension(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "CONFIG.TOML", "[unifi]\nhost = \"h\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.UniFi.Host != "h" {
+		t.Errorf("host = %q, want h", cfg.UniFi.Host)
+	}
+}
+
+func TestLoadTOMLIgnoresUnknownKeys(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "config.toml", `
+future_option = 3
+[unifi]
+host = "h"
+retries = 5
+[metrics]
+enabled = true
+`))
+	if err != nil {
+		t.Fatalf("unknown keys should be ignored, as with YAML: %v", err)
+	}
+	if cfg.UniFi.Host != "h" {
+		t.Errorf("host = %q, want h", cfg.UniFi.Host)
+	}
+}
+
+func TestLoadTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "string for bool", content: "[unifi]\nskip_tls_verify = \"yes\"", want: "unifi.skip_tls_verify: expected a boolean"},
+		{name: "integer for string", content: "[unifi]\nhost = 8443", want: "unifi.host: expected a string"},
+		{name: "value for table", content: "unifi = \"h\"", want: "unifi: expected a table"},
+		{name: "table for array", content: "[controllers]\nhost = \"h\"", want: "controllers: expected an array of tables"},
+		{name: "wrong type in array", content: "[[controllers]]\nhost = true", want: "controllers[0].host: expected a string"},
+		{name: "duplicate key", content: "[unifi]\nhost = \"a\"\nhost = \"b\"", want: "line 3: duplicate key host"},
+		{name: "unterminated string", content: "[unifi]\nhost = \"h", want: "line 2: unterminated string"},
+		{name: "missing value", content: "[unifi]\nhost =", want: "line 2: unsupported value"},
+		{name: "arrays", content: "[unifi]\nhost = [\"a\"]", want: "line 2: arrays aren't supported"},
+		{name: "trailing garbage", content: "[unifi] x", want: "line 1: unexpected"},
+		{name: "controller without host", content: "[[controllers]]\nname = \"a\"", want: "controllers[0]: host is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, "config.toml", tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Package console keeps the commands' stdout a data channel. Progress and
+// summary messages go to stderr, and -quiet silences them; errors are
+// written to stderr directly and are never silenced.
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Stdout is the output path that writes to standard output instead of a
+// file, e.g. -output -.
+const Stdout = "-"
+
+// quiet is set once from a command's -quiet flag, before any goroutines
+// start.
+var quiet bool
+
+// SetQuiet silences (or restores) progress and summary messages.
+func SetQuiet(q bool) {
+	quiet = q
+}
+
+// Quiet reports whether progress and summary messages are silenced.
+func Quiet() bool {
+	return quiet
+}
+
+// Writer returns where messages go: stderr, or io.Discard when quiet.
+func Writer() io.Writer {
+	if quiet {
+		return io.Discard
+	}
+	return os.Stderr
+}
+
+// Printf writes a message to stderr unless quiet.
+func Printf(format string, a ...interface{}) {
+	fmt.Fprintf(Writer(), format, a...)
+}
+
+// Println writes a message line to stderr unless quiet.
+func Println(a ...interface{}) {
+	fmt.Fprintln(Writer(), a...)
+}
+
+// WriteFile writes data to path, or to stdout if path is Stdout.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	if path == Stdout {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+// IsStdout reports whether path names standard output.
+func IsStdout(path string) bool {
+	return path == Stdout
+}
@@ -0,0 +1,103 @@
+package countries
+
+// DefaultFuzzyThreshold is a conservative SetFuzzyThreshold value: a name
+// of ten or more letters may be off by one letter, and shorter names must
+// match exactly.
+const DefaultFuzzyThreshold = 0.9
+
+// minFuzzyLength is the shortest normalized input NormalizeFuzzy tries;
+// shorter tokens are too often codes or abbreviations to guess at.
+const minFuzzyLength = 4
+
+// fuzzyMatch is a memoized NormalizeFuzzy result.
+type fuzzyMatch struct {
+	code  string
+	score float64
+	ok    bool
+}
+
+// SetFuzzyThreshold makes NormalizeDetailed and NormalizeBatch fall back to
+// NormalizeFuzzy for inputs that match no name or code, accepting results
+// that score at least threshold as MatchFuzzy. 0, the default, turns the
+// fallback off. It must be called before n is shared.
+func (n *Normalizer) SetFuzzyThreshold(threshold float64) {
+	n.fuzzyThreshold = threshold
+}
+
+// NormalizeFuzzy finds the known name closest to input by edit distance,
+// after the same normalization Normalize applies. score runs from 0 to 1,
+// where 1 means identical; it is 1 minus the distance over the longer
+// name's length. ok is false for inputs under four letters and when two
+// countries tie for the best score.
+func (n *Normalizer) NormalizeFuzzy(input string) (code string, score float64, ok bool) {
+	normalized := n.normalizeCached(input)
+
+	n.mu.Lock()
+	cached, seen := n.fuzzyCache[normalized]
+	n.mu.Unlock()
+	if seen {
+		return cached.code, cached.score, cached.ok
+	}
+
+	m := n.closestName(normalized)
+
+	n.mu.Lock()
+	n.fuzzyCache[normalized] = m
+	n.mu.Unlock()
+
+	return m.code, m.score, m.ok
+}
+
+// closestName scores normalized against every name in the table.
+func (n *Normalizer) closestName(normalized string) fuzzyMatch {
+	a := []rune(normalized)
+	if len(a) < minFuzzyLength {
+		return fuzzyMatch{}
+	}
+
+	var best fuzzyMatch
+	tied := false
+	for name, code := range n.nameToCode {
+		b := []rune(name)
+		longer := len(a)
+		if len(b) > longer {
+			longer = len(b)
+		}
+		score := 1 - float64(editDistance(a, b))/float64(longer)
+
+		switch {
+		case score > best.score:
+			best = fuzzyMatch{code: code, score: score, ok: true}
+			tied = false
+		case score == best.score && code != best.code:
+			tied = true
+		}
+	}
+
+	if tied || best.score <= 0 {
+		return fuzzyMatch{}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
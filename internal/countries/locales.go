@@ -0,0 +1,691 @@
+package countries
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultLanguage is the language of the built-in name table and of GetName.
+const DefaultLanguage = "en"
+
+// Languages returns the languages GetNameLocalized knows, DefaultLanguage
+// first and the rest in sorted order.
+func Languages() []string {
+	langs := make([]string, 0, len(localizedNames))
+	for lang := range localizedNames {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return append([]string{DefaultLanguage}, langs...)
+}
+
+// GetNameLocalized returns the name of a country code in lang, an ISO 639-1
+// code optionally followed by a region ("de", "es-MX", "fr_CA"). It falls
+// back to GetName for DefaultLanguage, unknown languages, and codes with no
+// localized name, such as territories.
+func (n *Normalizer) GetNameLocalized(code, lang string) string {
+	if names, ok := localizedNames[baseLanguage(lang)][strings.ToUpper(code)]; ok {
+		return names[0]
+	}
+	return n.GetName(code)
+}
+
+// baseLanguage strips the region from a language tag and lowercases it.
+func baseLanguage(lang string) string {
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(lang))
+}
+
+// addLocalizedNames makes the names in localizedNames resolve too, so
+// sources that publish in another language match. English names take
+// precedence, then languages and codes in sorted order, so a name shared by
+// two countries always resolves the same way.
+func (n *Normalizer) addLocalizedNames() {
+	for _, lang := range Languages()[1:] {
+		byCode := localizedNames[lang]
+		codes := make([]string, 0, len(byCode))
+		for code := range byCode {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+
+		for _, code := range codes {
+			for _, name := range byCode[code] {
+				key := normalizeString(name)
+				if _, taken := n.nameToCode[key]; !taken {
+					n.nameToCode[key] = code
+					n.exact[foldName(name)] = code
+				}
+			}
+		}
+	}
+}
+
+// localizedNames maps a language to each country's name in it, keyed like
+// countryNames. The first name is the one GetNameLocalized returns; the rest
+// are common variants that only help matching.
+var localizedNames = map[string]map[string][]string{
+	"de": {
+		"AF": {"Afghanistan"},
+		"AL": {"Albanien"},
+		"DZ": {"Algerien"},
+		"AS": {"Amerikanisch-Samoa"},
+		"AD": {"Andorra"},
+		"AO": {"Angola"},
+		"AI": {"Anguilla"},
+		"AQ": {"Antarktis"},
+		"AG": {"Antigua und Barbuda"},
+		"AR": {"Argentinien"},
+		"AM": {"Armenien"},
+		"AW": {"Aruba"},
+		"AU": {"Australien"},
+		"AT": {"Österreich"},
+		"AZ": {"Aserbaidschan"},
+		"BS": {"Bahamas"},
+		"BH": {"Bahrain"},
+		"BD": {"Bangladesch"},
+		"BB": {"Barbados"},
+		"BY": {"Belarus", "Weißrussland"},
+		"BE": {"Belgien"},
+		"BZ": {"Belize"},
+		"BJ": {"Benin"},
+		"BM": {"Bermuda"},
+		"BT": {"Bhutan"},
+		"BO": {"Bolivien"},
+		"BA": {"Bosnien und Herzegowina"},
+		"BW": {"Botsuana"},
+		"BR": {"Brasilien"},
+		"BN": {"Brunei"},
+		"BG": {"Bulgarien"},
+		"BF": {"Burkina Faso"},
+		"BI": {"Burundi"},
+		"CV": {"Cabo Verde", "Kap Verde"},
+		"KH": {"Kambodscha"},
+		"CM": {"Kamerun"},
+		"CA": {"Kanada"},
+		"KY": {"Kaimaninseln"},
+		"CF": {"Zentralafrikanische Republik"},
+		"TD": {"Tschad"},
+		"CL": {"Chile"},
+		"CN": {"China", "Volksrepublik China"},
+		"CO": {"Kolumbien"},
+		"KM": {"Komoren"},
+		"CG": {"Republik Kongo"},
+		"CD": {"Demokratische Republik Kongo"},
+		"CR": {"Costa Rica"},
+		"CI": {"Elfenbeinküste"},
+		"HR": {"Kroatien"},
+		"CU": {"Kuba"},
+		"CY": {"Zypern"},
+		"CZ": {"Tschechien", "Tschechische Republik"},
+		"DK": {"Dänemark"},
+		"DJ": {"Dschibuti"},
+		"DM": {"Dominica"},
+		"DO": {"Dominikanische Republik"},
+		"EC": {"Ecuador"},
+		"EG": {"Ägypten"},
+		"SV": {"El Salvador"},
+		"GQ": {"Äquatorialguinea"},
+		"ER": {"Eritrea"},
+		"EE": {"Estland"},
+		"SZ": {"Eswatini"},
+		"ET": {"Äthiopien"},
+		"FJ": {"Fidschi"},
+		"FI": {"Finnland"},
+		"FR": {"Frankreich"},
+		"GA": {"Gabun"},
+		"GM": {"Gambia"},
+		"GE": {"Georgien"},
+		"DE": {"Deutschland"},
+		"GH": {"Ghana"},
+		"GR": {"Griechenland"},
+		"GD": {"Grenada"},
+		"GT": {"Guatemala"},
+		"GN": {"Guinea"},
+		"GW": {"Guinea-Bissau"},
+		"GY": {"Guyana"},
+		"HT": {"Haiti"},
+		"HN": {"Honduras"},
+		"HK": {"Hongkong"},
+		"HU": {"Ungarn"},
+		"IS": {"Island"},
+		"IN": {"Indien"},
+		"ID": {"Indonesien"},
+		"IR": {"Iran"},
+		"IQ": {"Irak"},
+		"IE": {"Irland"},
+		"IL": {"Israel"},
+		"IT": {"Italien"},
+		"JM": {"Jamaika"},
+		"JP": {"Japan"},
+		"JO": {"Jordanien"},
+		"KZ": {"Kasachstan"},
+		"KE": {"Kenia"},
+		"KI": {"Kiribati"},
+		"KP": {"Nordkorea"},
+		"KR": {"Südkorea"},
+		"KW": {"Kuwait"},
+		"KG": {"Kirgisistan"},
+		"LA": {"Laos"},
+		"LV": {"Lettland"},
+		"LB": {"Libanon"},
+		"LS": {"Lesotho"},
+		"LR": {"Liberia"},
+		"LY": {"Libyen"},
+		"LI": {"Liechtenstein"},
+		"LT": {"Litauen"},
+		"LU": {"Luxemburg"},
+		"MO": {"Macau"},
+		"MG": {"Madagaskar"},
+		"MW": {"Malawi"},
+		"MY": {"Malaysia"},
+		"MV": {"Malediven"},
+		"ML": {"Mali"},
+		"MT": {"Malta"},
+		"MH": {"Marshallinseln"},
+		"MR": {"Mauretanien"},
+		"MU": {"Mauritius"},
+		"MX": {"Mexiko"},
+		"FM": {"Mikronesien"},
+		"MD": {"Moldau", "Moldawien"},
+		"MC": {"Monaco"},
+		"MN": {"Mongolei"},
+		"ME": {"Montenegro"},
+		"MA": {"Marokko"},
+		"MZ": {"Mosambik"},
+		"MM": {"Myanmar"},
+		"NA": {"Namibia"},
+		"NR": {"Nauru"},
+		"NP": {"Nepal"},
+		"NL": {"Niederlande"},
+		"NZ": {"Neuseeland"},
+		"NI": {"Nicaragua"},
+		"NE": {"Niger"},
+		"NG": {"Nigeria"},
+		"MK": {"Nordmazedonien"},
+		"NO": {"Norwegen"},
+		"OM": {"Oman"},
+		"PK": {"Pakistan"},
+		"PW": {"Palau"},
+		"PS": {"Palästina"},
+		"PA": {"Panama"},
+		"PG": {"Papua-Neuguinea"},
+		"PY": {"Paraguay"},
+		"PE": {"Peru"},
+		"PH": {"Philippinen"},
+		"PL": {"Polen"},
+		"PT": {"Portugal"},
+		"PR": {"Puerto Rico"},
+		"QA": {"Katar"},
+		"RO": {"Rumänien"},
+		"RU": {"Russland", "Russische Föderation"},
+		"RW": {"Ruanda"},
+		"KN": {"St. Kitts und Nevis"},
+		"LC": {"St. Lucia"},
+		"VC": {"St. Vincent und die Grenadinen"},
+		"WS": {"Samoa"},
+		"SM": {"San Marino"},
+		"ST": {"São Tomé und Príncipe"},
+		"SA": {"Saudi-Arabien"},
+		"SN": {"Senegal"},
+		"RS": {"Serbien"},
+		"SC": {"Seychellen"},
+		"SL": {"Sierra Leone"},
+		"SG": {"Singapur"},
+		"SK": {"Slowakei"},
+		"SI": {"Slowenien"},
+		"SB": {"Salomonen"},
+		"SO": {"Somalia"},
+		"ZA": {"Südafrika"},
+		"SS": {"Südsudan"},
+		"ES": {"Spanien"},
+		"LK": {"Sri Lanka"},
+		"SD": {"Sudan"},
+		"SR": {"Suriname"},
+		"SE": {"Schweden"},
+		"CH": {"Schweiz"},
+		"SY": {"Syrien"},
+		"TW": {"Taiwan"},
+		"TJ": {"Tadschikistan"},
+		"TZ": {"Tansania"},
+		"TH": {"Thailand"},
+		"TL": {"Osttimor", "Timor-Leste"},
+		"TG": {"Togo"},
+		"TO": {"Tonga"},
+		"TT": {"Trinidad und Tobago"},
+		"TN": {"Tunesien"},
+		"TR": {"Türkei"},
+		"TM": {"Turkmenistan"},
+		"TV": {"Tuvalu"},
+		"UG": {"Uganda"},
+		"UA": {"Ukraine"},
+		"AE": {"Vereinigte Arabische Emirate"},
+		"GB": {"Vereinigtes Königreich", "Großbritannien"},
+		"US": {"Vereinigte Staaten", "Vereinigte Staaten von Amerika"},
+		"UY": {"Uruguay"},
+		"UZ": {"Usbekistan"},
+		"VU": {"Vanuatu"},
+		"VA": {"Vatikanstadt"},
+		"VE": {"Venezuela"},
+		"VN": {"Vietnam"},
+		"YE": {"Jemen"},
+		"ZM": {"Sambia"},
+		"ZW": {"Simbabwe"},
+	},
+	"es": {
+		"AF": {"Afganistán"},
+		"AL": {"Albania"},
+		"DZ": {"Argelia"},
+		"AS": {"Samoa Americana"},
+		"AD": {"Andorra"},
+		"AO": {"Angola"},
+		"AI": {"Anguila"},
+		"AQ": {"Antártida"},
+		"AG": {"Antigua y Barbuda"},
+		"AR": {"Argentina"},
+		"AM": {"Armenia"},
+		"AW": {"Aruba"},
+		"AU": {"Australia"},
+		"AT": {"Austria"},
+		"AZ": {"Azerbaiyán"},
+		"BS": {"Bahamas"},
+		"BH": {"Baréin", "Bahréin"},
+		"BD": {"Bangladés"},
+		"BB": {"Barbados"},
+		"BY": {"Bielorrusia"},
+		"BE": {"Bélgica"},
+		"BZ": {"Belice"},
+		"BJ": {"Benín"},
+		"BM": {"Bermudas"},
+		"BT": {"Bután"},
+		"BO": {"Bolivia"},
+		"BA": {"Bosnia y Herzegovina"},
+		"BW": {"Botsuana"},
+		"BR": {"Brasil"},
+		"BN": {"Brunéi"},
+		"BG": {"Bulgaria"},
+		"BF": {"Burkina Faso"},
+		"BI": {"Burundi"},
+		"CV": {"Cabo Verde"},
+		"KH": {"Camboya"},
+		"CM": {"Camerún"},
+		"CA": {"Canadá"},
+		"KY": {"Islas Caimán"},
+		"CF": {"República Centroafricana"},
+		"TD": {"Chad"},
+		"CL": {"Chile"},
+		"CN": {"China", "República Popular China"},
+		"CO": {"Colombia"},
+		"KM": {"Comoras"},
+		"CG": {"República del Congo"},
+		"CD": {"República Democrática del Congo"},
+		"CR": {"Costa Rica"},
+		"CI": {"Costa de Marfil"},
+		"HR": {"Croacia"},
+		"CU": {"Cuba"},
+		"CY": {"Chipre"},
+		"CZ": {"Chequia", "República Checa"},
+		"DK": {"Dinamarca"},
+		"DJ": {"Yibuti"},
+		"DM": {"Dominica"},
+		"DO": {"República Dominicana"},
+		"EC": {"Ecuador"},
+		"EG": {"Egipto"},
+		"SV": {"El Salvador"},
+		"GQ": {"Guinea Ecuatorial"},
+		"ER": {"Eritrea"},
+		"EE": {"Estonia"},
+		"SZ": {"Esuatini"},
+		"ET": {"Etiopía"},
+		"FJ": {"Fiyi"},
+		"FI": {"Finlandia"},
+		"FR": {"Francia"},
+		"GA": {"Gabón"},
+		"GM": {"Gambia"},
+		"GE": {"Georgia"},
+		"DE": {"Alemania"},
+		"GH": {"Ghana"},
+		"GR": {"Grecia"},
+		"GD": {"Granada"},
+		"GT": {"Guatemala"},
+		"GN": {"Guinea"},
+		"GW": {"Guinea-Bisáu"},
+		"GY": {"Guyana"},
+		"HT": {"Haití"},
+		"HN": {"Honduras"},
+		"HK": {"Hong Kong"},
+		"HU": {"Hungría"},
+		"IS": {"Islandia"},
+		"IN": {"India"},
+		"ID": {"Indonesia"},
+		"IR": {"Irán"},
+		"IQ": {"Irak"},
+		"IE": {"Irlanda"},
+		"IL": {"Israel"},
+		"IT": {"Italia"},
+		"JM": {"Jamaica"},
+		"JP": {"Japón"},
+		"JO": {"Jordania"},
+		"KZ": {"Kazajistán"},
+		"KE": {"Kenia"},
+		"KI": {"Kiribati"},
+		"KP": {"Corea del Norte"},
+		"KR": {"Corea del Sur"},
+		"KW": {"Kuwait"},
+		"KG": {"Kirguistán"},
+		"LA": {"Laos"},
+		"LV": {"Letonia"},
+		"LB": {"Líbano"},
+		"LS": {"Lesoto"},
+		"LR": {"Liberia"},
+		"LY": {"Libia"},
+		"LI": {"Liechtenstein"},
+		"LT": {"Lituania"},
+		"LU": {"Luxemburgo"},
+		"MO": {"Macao"},
+		"MG": {"Madagascar"},
+		"MW": {"Malaui"},
+		"MY": {"Malasia"},
+		"MV": {"Maldivas"},
+		"ML": {"Malí"},
+		"MT": {"Malta"},
+		"MH": {"Islas Marshall"},
+		"MR": {"Mauritania"},
+		"MU": {"Mauricio"},
+		"MX": {"México"},
+		"FM": {"Micronesia"},
+		"MD": {"Moldavia"},
+		"MC": {"Mónaco"},
+		"MN": {"Mongolia"},
+		"ME": {"Montenegro"},
+		"MA": {"Marruecos"},
+		"MZ": {"Mozambique"},
+		"MM": {"Birmania", "Myanmar"},
+		"NA": {"Namibia"},
+		"NR": {"Nauru"},
+		"NP": {"Nepal"},
+		"NL": {"Países Bajos", "Holanda"},
+		"NZ": {"Nueva Zelanda"},
+		"NI": {"Nicaragua"},
+		"NE": {"Níger"},
+		"NG": {"Nigeria"},
+		"MK": {"Macedonia del Norte"},
+		"NO": {"Noruega"},
+		"OM": {"Omán"},
+		"PK": {"Pakistán"},
+		"PW": {"Palaos"},
+		"PS": {"Palestina"},
+		"PA": {"Panamá"},
+		"PG": {"Papúa Nueva Guinea"},
+		"PY": {"Paraguay"},
+		"PE": {"Perú"},
+		"PH": {"Filipinas"},
+		"PL": {"Polonia"},
+		"PT": {"Portugal"},
+		"PR": {"Puerto Rico"},
+		"QA": {"Catar"},
+		"RO": {"Rumania", "Rumanía"},
+		"RU": {"Rusia", "Federación de Rusia"},
+		"RW": {"Ruanda"},
+		"KN": {"San Cristóbal y Nieves"},
+		"LC": {"Santa Lucía"},
+		"VC": {"San Vicente y las Granadinas"},
+		"WS": {"Samoa"},
+		"SM": {"San Marino"},
+		"ST": {"Santo Tomé y Príncipe"},
+		"SA": {"Arabia Saudita", "Arabia Saudí"},
+		"SN": {"Senegal"},
+		"RS": {"Serbia"},
+		"SC": {"Seychelles"},
+		"SL": {"Sierra Leona"},
+		"SG": {"Singapur"},
+		"SK": {"Eslovaquia"},
+		"SI": {"Eslovenia"},
+		"SB": {"Islas Salomón"},
+		"SO": {"Somalia"},
+		"ZA": {"Sudáfrica"},
+		"SS": {"Sudán del Sur"},
+		"ES": {"España"},
+		"LK": {"Sri Lanka"},
+		"SD": {"Sudán"},
+		"SR": {"Surinam"},
+		"SE": {"Suecia"},
+		"CH": {"Suiza"},
+		"SY": {"Siria"},
+		"TW": {"Taiwán"},
+		"TJ": {"Tayikistán"},
+		"TZ": {"Tanzania"},
+		"TH": {"Tailandia"},
+		"TL": {"Timor Oriental"},
+		"TG": {"Togo"},
+		"TO": {"Tonga"},
+		"TT": {"Trinidad y Tobago"},
+		"TN": {"Túnez"},
+		"TR": {"Turquía"},
+		"TM": {"Turkmenistán"},
+		"TV": {"Tuvalu"},
+		"UG": {"Uganda"},
+		"UA": {"Ucrania"},
+		"AE": {"Emiratos Árabes Unidos"},
+		"GB": {"Reino Unido"},
+		"US": {"Estados Unidos", "Estados Unidos de América"},
+		"UY": {"Uruguay"},
+		"UZ": {"Uzbekistán"},
+		"VU": {"Vanuatu"},
+		"VA": {"Ciudad del Vaticano"},
+		"VE": {"Venezuela"},
+		"VN": {"Vietnam"},
+		"YE": {"Yemen"},
+		"ZM": {"Zambia"},
+		"ZW": {"Zimbabue"},
+	},
+	"fr": {
+		"AF": {"Afghanistan"},
+		"AL": {"Albanie"},
+		"DZ": {"Algérie"},
+		"AS": {"Samoa américaines"},
+		"AD": {"Andorre"},
+		"AO": {"Angola"},
+		"AI": {"Anguilla"},
+		"AQ": {"Antarctique"},
+		"AG": {"Antigua-et-Barbuda"},
+		"AR": {"Argentine"},
+		"AM": {"Arménie"},
+		"AW": {"Aruba"},
+		"AU": {"Australie"},
+		"AT": {"Autriche"},
+		"AZ": {"Azerbaïdjan"},
+		"BS": {"Bahamas"},
+		"BH": {"Bahreïn"},
+		"BD": {"Bangladesh"},
+		"BB": {"Barbade"},
+		"BY": {"Biélorussie", "Bélarus"},
+		"BE": {"Belgique"},
+		"BZ": {"Belize"},
+		"BJ": {"Bénin"},
+		"BM": {"Bermudes"},
+		"BT": {"Bhoutan"},
+		"BO": {"Bolivie"},
+		"BA": {"Bosnie-Herzégovine"},
+		"BW": {"Botswana"},
+		"BR": {"Brésil"},
+		"BN": {"Brunei"},
+		"BG": {"Bulgarie"},
+		"BF": {"Burkina Faso"},
+		"BI": {"Burundi"},
+		"CV": {"Cap-Vert"},
+		"KH": {"Cambodge"},
+		"CM": {"Cameroun"},
+		"CA": {"Canada"},
+		"KY": {"Îles Caïmans"},
+		"CF": {"République centrafricaine"},
+		"TD": {"Tchad"},
+		"CL": {"Chili"},
+		"CN": {"Chine", "République populaire de Chine"},
+		"CO": {"Colombie"},
+		"KM": {"Comores"},
+		"CG": {"République du Congo"},
+		"CD": {"République démocratique du Congo"},
+		"CR": {"Costa Rica"},
+		"CI": {"Côte d'Ivoire"},
+		"HR": {"Croatie"},
+		"CU": {"Cuba"},
+		"CY": {"Chypre"},
+		"CZ": {"Tchéquie", "République tchèque"},
+		"DK": {"Danemark"},
+		"DJ": {"Djibouti"},
+		"DM": {"Dominique"},
+		"DO": {"République dominicaine"},
+		"EC": {"Équateur"},
+		"EG": {"Égypte"},
+		"SV": {"Salvador"},
+		"GQ": {"Guinée équatoriale"},
+		"ER": {"Érythrée"},
+		"EE": {"Estonie"},
+		"SZ": {"Eswatini"},
+		"ET": {"Éthiopie"},
+		"FJ": {"Fidji"},
+		"FI": {"Finlande"},
+		"FR": {"France"},
+		"GA": {"Gabon"},
+		"GM": {"Gambie"},
+		"GE": {"Géorgie"},
+		"DE": {"Allemagne"},
+		"GH": {"Ghana"},
+		"GR": {"Grèce"},
+		"GD": {"Grenade"},
+		"GT": {"Guatemala"},
+		"GN": {"Guinée"},
+		"GW": {"Guinée-Bissau"},
+		"GY": {"Guyana"},
+		"HT": {"Haïti"},
+		"HN": {"Honduras"},
+		"HK": {"Hong Kong"},
+		"HU": {"Hongrie"},
+		"IS": {"Islande"},
+		"IN": {"Inde"},
+		"ID": {"Indonésie"},
+		"IR": {"Iran"},
+		"IQ": {"Irak"},
+		"IE": {"Irlande"},
+		"IL": {"Israël"},
+		"IT": {"Italie"},
+		"JM": {"Jamaïque"},
+		"JP": {"Japon"},
+		"JO": {"Jordanie"},
+		"KZ": {"Kazakhstan"},
+		"KE": {"Kenya"},
+		"KI": {"Kiribati"},
+		"KP": {"Corée du Nord"},
+		"KR": {"Corée du Sud"},
+		"KW": {"Koweït"},
+		"KG": {"Kirghizistan"},
+		"LA": {"Laos"},
+		"LV": {"Lettonie"},
+		"LB": {"Liban"},
+		"LS": {"Lesotho"},
+		"LR": {"Liberia"},
+		"LY": {"Libye"},
+		"LI": {"Liechtenstein"},
+		"LT": {"Lituanie"},
+		"LU": {"Luxembourg"},
+		"MO": {"Macao"},
+		"MG": {"Madagascar"},
+		"MW": {"Malawi"},
+		"MY": {"Malaisie"},
+		"MV": {"Maldives"},
+		"ML": {"Mali"},
+		"MT": {"Malte"},
+		"MH": {"Îles Marshall"},
+		"MR": {"Mauritanie"},
+		"MU": {"Maurice"},
+		"MX": {"Mexique"},
+		"FM": {"Micronésie"},
+		"MD": {"Moldavie"},
+		"MC": {"Monaco"},
+		"MN": {"Mongolie"},
+		"ME": {"Monténégro"},
+		"MA": {"Maroc"},
+		"MZ": {"Mozambique"},
+		"MM": {"Birmanie", "Myanmar"},
+		"NA": {"Namibie"},
+		"NR": {"Nauru"},
+		"NP": {"Népal"},
+		"NL": {"Pays-Bas"},
+		"NZ": {"Nouvelle-Zélande"},
+		"NI": {"Nicaragua"},
+		"NE": {"Niger"},
+		"NG": {"Nigeria"},
+		"MK": {"Macédoine du Nord"},
+		"NO": {"Norvège"},
+		"OM": {"Oman"},
+		"PK": {"Pakistan"},
+		"PW": {"Palaos"},
+		"PS": {"Palestine"},
+		"PA": {"Panama"},
+		"PG": {"Papouasie-Nouvelle-Guinée"},
+		"PY": {"Paraguay"},
+		"PE": {"Pérou"},
+		"PH": {"Philippines"},
+		"PL": {"Pologne"},
+		"PT": {"Portugal"},
+		"PR": {"Porto Rico"},
+		"QA": {"Qatar"},
+		"RO": {"Roumanie"},
+		"RU": {"Russie", "Fédération de Russie"},
+		"RW": {"Rwanda"},
+		"KN": {"Saint-Christophe-et-Niévès"},
+		"LC": {"Sainte-Lucie"},
+		"VC": {"Saint-Vincent-et-les-Grenadines"},
+		"WS": {"Samoa"},
+		"SM": {"Saint-Marin"},
+		"ST": {"Sao Tomé-et-Principe"},
+		"SA": {"Arabie saoudite"},
+		"SN": {"Sénégal"},
+		"RS": {"Serbie"},
+		"SC": {"Seychelles"},
+		"SL": {"Sierra Leone"},
+		"SG": {"Singapour"},
+		"SK": {"Slovaquie"},
+		"SI": {"Slovénie"},
+		"SB": {"Îles Salomon"},
+		"SO": {"Somalie"},
+		"ZA": {"Afrique du Sud"},
+		"SS": {"Soudan du Sud"},
+		"ES": {"Espagne"},
+		"LK": {"Sri Lanka"},
+		"SD": {"Soudan"},
+		"SR": {"Suriname"},
+		"SE": {"Suède"},
+		"CH": {"Suisse"},
+		"SY": {"Syrie"},
+		"TW": {"Taïwan"},
+		"TJ": {"Tadjikistan"},
+		"TZ": {"Tanzanie"},
+		"TH": {"Thaïlande"},
+		"TL": {"Timor oriental"},
+		"TG": {"Togo"},
+		"TO": {"Tonga"},
+		"TT": {"Trinité-et-Tobago"},
+		"TN": {"Tunisie"},
+		"TR": {"Turquie"},
+		"TM": {"Turkménistan"},
+		"TV": {"Tuvalu"},
+		"UG": {"Ouganda"},
+		"UA": {"Ukraine"},
+		"AE": {"Émirats arabes unis"},
+		"GB": {"Royaume-Uni"},
+		"US": {"États-Unis", "États-Unis d'Amérique"},
+		"UY": {"Uruguay"},
+		"UZ": {"Ouzbékistan"},
+		"VU": {"Vanuatu"},
+		"VA": {"Cité du Vatican", "Vatican"},
+		"VE": {"Venezuela"},
+		"VN": {"Viêt Nam"},
+		"YE": {"Yémen"},
+		"ZM": {"Zambie"},
+		"ZW": {"Zimbabwe"},
+	},
+}
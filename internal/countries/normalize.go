@@ -3,6 +3,7 @@ package countries
 
 import (
 	"strings"
+	"sync"
 	"unicode"
 
 	"golang.org/x/text/unicode/norm"
@@ -27,10 +28,24 @@ type CountryEntry struct {
 	RawTokens []string `json:"raw_tokens,omitempty"`
 }
 
-// Normalizer handles country name normalization.
+// Normalizer handles country name normalization. It is safe for concurrent use.
 type Normalizer struct {
 	nameToCode map[string]string
 	codeToName map[string]string
+	// exact maps each table name, lowercased but otherwise as written, to
+	// its code, to tell exact matches from ones that needed normalizing
+	exact map[string]string
+
+	// fuzzyThreshold is the lowest NormalizeFuzzy score NormalizeDetailed
+	// accepts; 0 turns fuzzy matching off
+	fuzzyThreshold float64
+
+	// cache memoizes normalizeString for inputs seen by Normalize, since
+	// sources repeat the same tokens many times; fuzzyCache does the same
+	// for NormalizeFuzzy
+	mu         sync.Mutex
+	cache      map[string]string
+	fuzzyCache map[string]fuzzyMatch
 }
 
 // NewNormalizer creates a new country normalizer.
@@ -38,6 +53,9 @@ func NewNormalizer() *Normalizer {
 	n := &Normalizer{
 		nameToCode: make(map[string]string),
 		codeToName: make(map[string]string),
+		exact:      make(map[string]string),
+		cache:      make(map[string]string),
+		fuzzyCache: make(map[string]fuzzyMatch),
 	}
 
 	// Build lookup maps
@@ -46,27 +64,25 @@ func NewNormalizer() *Normalizer {
 		for _, name := range names {
 			normalized := normalizeString(name)
 			n.nameToCode[normalized] = code
+			n.exact[foldName(name)] = code
 		}
 	}
-
-	// Also add codes as self-referencing
-	for code := range countryNames {
-		n.nameToCode[strings.ToLower(code)] = code
-	}
+	n.addLocalizedNames()
 
 	return n
 }
 
 // Normalize converts a country name or code to ISO 3166-1 alpha-2.
+// Codes are matched case-insensitively against the code table itself, so
+// nameToCode only ever holds real names.
 func (n *Normalizer) Normalize(input string) (string, bool) {
-	normalized := normalizeString(input)
+	normalized := n.normalizeCached(input)
 	if code, ok := n.nameToCode[normalized]; ok {
 		return code, true
 	}
 
-	// Try uppercase as-is (might be a code already)
-	upper := strings.ToUpper(strings.TrimSpace(input))
-	if len(upper) == 2 {
+	// Might be a code already
+	if upper := strings.ToUpper(normalized); len(upper) == 2 {
 		if _, ok := n.codeToName[upper]; ok {
 			return upper, true
 		}
@@ -75,7 +91,105 @@ func (n *Normalizer) Normalize(input string) (string, bool) {
 	return "", false
 }
 
-// GetName returns the display name for a country code.
+// How NormalizeDetailed matched an input.
+const (
+	// MatchCode: the input is an alpha-2 code.
+	MatchCode = "code"
+	// MatchExactName: the input is the country's display name, ignoring
+	// case.
+	MatchExactName = "exact_name"
+	// MatchAlias: the input is another name in the table, ignoring case.
+	MatchAlias = "alias"
+	// MatchNormalized: the input only matched a name after diacritics,
+	// punctuation, and spacing were ignored, e.g. "Cote dIvoire".
+	MatchNormalized = "normalized"
+	// MatchFuzzy: the input matched no name and was accepted as a
+	// misspelling of the closest one; see SetFuzzyThreshold.
+	MatchFuzzy = "fuzzy"
+)
+
+// NormalizeDetailed is Normalize, also reporting how the input matched.
+// matchType is empty when ok is false.
+func (n *Normalizer) NormalizeDetailed(input string) (code, matchType string, ok bool) {
+	m := n.match(input)
+	return m.Code, m.MatchType, m.OK
+}
+
+// Match is the result of normalizing one input with NormalizeBatch.
+// Score is the NormalizeFuzzy score for a MatchFuzzy match and 1 for any
+// other match.
+type Match struct {
+	Input     string
+	Code      string
+	MatchType string
+	Score     float64
+	OK        bool
+}
+
+// NormalizeBatch normalizes each input with NormalizeDetailed, in order.
+func (n *Normalizer) NormalizeBatch(inputs []string) []Match {
+	matches := make([]Match, len(inputs))
+	for i, input := range inputs {
+		matches[i] = n.match(input)
+	}
+	return matches
+}
+
+// match implements NormalizeDetailed.
+func (n *Normalizer) match(input string) Match {
+	m := Match{Input: input, Score: 1, OK: true}
+
+	normalized := n.normalizeCached(input)
+	if code, ok := n.nameToCode[normalized]; ok {
+		m.Code = code
+		folded := foldName(input)
+		switch {
+		case folded == foldName(n.codeToName[code]):
+			m.MatchType = MatchExactName
+		case n.exact[folded] == code:
+			m.MatchType = MatchAlias
+		default:
+			m.MatchType = MatchNormalized
+		}
+		return m
+	}
+
+	if code, ok := n.Normalize(input); ok {
+		m.Code, m.MatchType = code, MatchCode
+		return m
+	}
+
+	if n.fuzzyThreshold > 0 {
+		if code, score, ok := n.NormalizeFuzzy(input); ok && score >= n.fuzzyThreshold {
+			m.Code, m.MatchType, m.Score = code, MatchFuzzy, score
+			return m
+		}
+	}
+
+	return Match{Input: input}
+}
+
+// foldName lowercases a name and collapses its whitespace, keeping
+// diacritics and punctuation, for comparing names as written.
+func foldName(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// normalizeCached returns normalizeString(input), reusing earlier results.
+func (n *Normalizer) normalizeCached(input string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	normalized, ok := n.cache[input]
+	if !ok {
+		normalized = normalizeString(input)
+		n.cache[input] = normalized
+	}
+	return normalized
+}
+
+// GetName returns the English display name for a country code; see
+// GetNameLocalized for other languages.
 func (n *Normalizer) GetName(code string) string {
 	if name, ok := n.codeToName[strings.ToUpper(code)]; ok {
 		return name
@@ -0,0 +1,110 @@
+package countries
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeCodesIgnoreCase(t *testing.T) {
+	n := NewNormalizer()
+
+	tests := []struct {
+		input     string
+		wantCode  string
+		wantMatch string
+	}{
+		{input: "ru", wantCode: "RU", wantMatch: MatchCode},
+		{input: "RU", wantCode: "RU", wantMatch: MatchCode},
+		{input: " Ru ", wantCode: "RU", wantMatch: MatchCode},
+		{input: "rU", wantCode: "RU", wantMatch: MatchCode},
+		{input: "Russia", wantCode: "RU", wantMatch: MatchExactName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			code, ok := n.Normalize(tt.input)
+			if !ok || code != tt.wantCode {
+				t.Errorf("Normalize(%q) = %q, %v, want %q", tt.input, code, ok, tt.wantCode)
+			}
+			if _, match, _ := n.NormalizeDetailed(tt.input); match != tt.wantMatch {
+				t.Errorf("NormalizeDetailed(%q) match = %q, want %q", tt.input, match, tt.wantMatch)
+			}
+		})
+	}
+
+	for _, input := range []string{"", "r", "zz"} {
+		if code, ok := n.Normalize(input); ok {
+			t.Errorf("Normalize(%q) = %q, want no match", input, code)
+		}
+	}
+}
+
+func TestAllCodesAreUppercase(t *testing.T) {
+	for _, code := range NewNormalizer().AllCodes() {
+		if len(code) != 2 || code != strings.ToUpper(code) {
+			t.Errorf("AllCodes contains %q, want only uppercase alpha-2 codes", code)
+		}
+	}
+}
+
+// BenchmarkNormalizeBatch normalizes a page's worth of repeated tokens. The
+// cold case empties the memoization cache each iteration, as on the first
+// source of a run; the warm case keeps it, as for every later source.
+func BenchmarkNormalizeBatch(b *testing.B) {
+	inputs := benchmarkTokens()
+
+	b.Run("cold", func(b *testing.B) {
+		n := NewNormalizer()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			n.cache = make(map[string]string)
+			n.NormalizeBatch(inputs)
+		}
+	})
+	b.Run("warm", func(b *testing.B) {
+		n := NewNormalizer()
+		n.NormalizeBatch(inputs)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			n.NormalizeBatch(inputs)
+		}
+	})
+}
+
+// BenchmarkNormalizeCached isolates the cache: uncached is what every token
+// cost before memoization, cached is a repeat token's cost now.
+func BenchmarkNormalizeCached(b *testing.B) {
+	inputs := benchmarkTokens()
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, input := range inputs {
+				normalizeString(input)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		n := NewNormalizer()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, input := range inputs {
+				n.normalizeCached(input)
+			}
+		}
+	})
+}
+
+// benchmarkTokens returns country tokens repeated as a large page repeats
+// them.
+func benchmarkTokens() []string {
+	names := []string{
+		"Russia", "Russian Federation", "Iran", "North Korea", "Côte d'Ivoire",
+		"Syria", "Democratic Republic of the Congo", "Myanmar", "Venezuela",
+		"cuba", " Belarus ", "Not a country",
+	}
+	inputs := make([]string, 0, len(names)*200)
+	for i := 0; i < 200; i++ {
+		inputs = append(inputs, names...)
+	}
+	return inputs
+}
@@ -0,0 +1,135 @@
+package countries
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Override actions accepted by NewNormalizerWithOverrides.
+const (
+	OverrideAdd    = "add"
+	OverrideRemove = "remove"
+	OverrideRemap  = "remap"
+)
+
+// NewNormalizerWithOverrides creates a normalizer from the built-in table and
+// then applies the overrides read from r, so each deployment can make its
+// own choices for contested names. Each non-blank line not starting with "#"
+// is
+//
+//	CODE,ACTION,ALIAS
+//
+// where ACTION is one of:
+//
+//   - add: ALIAS now resolves to CODE. It is an error if ALIAS already
+//     resolves to a different code; use remap for that. An unknown CODE is
+//     added as a new country with ALIAS as its display name.
+//   - remove: ALIAS no longer resolves. It must currently resolve to CODE.
+//   - remap: ALIAS, which must already resolve to some code, now resolves
+//     to CODE instead.
+//
+// Lines are applied in order on top of the built-in table, so overrides
+// always win over built-in entries and a later line sees the effect of
+// earlier ones. Aliases are matched the same way as input to Normalize,
+// ignoring case, diacritics, and punctuation. Overrides change name matching
+// only: a two-letter code still resolves to itself.
+func NewNormalizerWithOverrides(r io.Reader) (*Normalizer, error) {
+	n := NewNormalizer()
+	if err := n.ApplyOverrides(r); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// ApplyOverrides applies overrides in the NewNormalizerWithOverrides format
+// on top of n's current table, e.g. after IncludeTerritories. It must be
+// called before n is shared.
+func (n *Normalizer) ApplyOverrides(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, ",", 3)
+		if len(fields) != 3 {
+			return fmt.Errorf("overrides line %d: want CODE,ACTION,ALIAS", lineNum)
+		}
+		code := strings.ToUpper(strings.TrimSpace(fields[0]))
+		action := strings.ToLower(strings.TrimSpace(fields[1]))
+		alias := strings.TrimSpace(fields[2])
+
+		if err := n.applyOverride(code, action, alias); err != nil {
+			return fmt.Errorf("overrides line %d: %w", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read overrides: %w", err)
+	}
+
+	return nil
+}
+
+// remapExact points every exact name that normalizes to key at code, or
+// drops them if code is empty, keeping exact in step with nameToCode.
+func (n *Normalizer) remapExact(key, code string) {
+	for name := range n.exact {
+		if normalizeString(name) != key {
+			continue
+		}
+		if code == "" {
+			delete(n.exact, name)
+		} else {
+			n.exact[name] = code
+		}
+	}
+}
+
+// applyOverride applies a single override entry.
+func (n *Normalizer) applyOverride(code, action, alias string) error {
+	if len(code) != 2 {
+		return fmt.Errorf("invalid code %q", code)
+	}
+	key := normalizeString(alias)
+	if key == "" {
+		return fmt.Errorf("empty alias")
+	}
+	current, exists := n.nameToCode[key]
+
+	switch action {
+	case OverrideAdd:
+		if exists && current != code {
+			return fmt.Errorf("%q already resolves to %s; use remap", alias, current)
+		}
+		if _, known := n.codeToName[code]; !known {
+			n.codeToName[code] = alias
+		}
+		n.nameToCode[key] = code
+		n.exact[foldName(alias)] = code
+	case OverrideRemove:
+		if !exists || current != code {
+			return fmt.Errorf("%q does not resolve to %s", alias, code)
+		}
+		delete(n.nameToCode, key)
+		n.remapExact(key, "")
+	case OverrideRemap:
+		if !exists {
+			return fmt.Errorf("%q does not resolve to any code; use add", alias)
+		}
+		if _, known := n.codeToName[code]; !known {
+			return fmt.Errorf("unknown code %s; add it first", code)
+		}
+		n.nameToCode[key] = code
+		n.remapExact(key, code)
+		n.exact[foldName(alias)] = code
+	default:
+		return fmt.Errorf("unknown action %q (want add, remove, or remap)", action)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package countries
+
+import (
+	"sort"
+	"strings"
+)
+
+// continents assigns every code in countryNames to one continent, following
+// the UN geoscheme: transcontinental countries go where the UN places them
+// (e.g. Russia in Europe; Turkey, Cyprus, and the Caucasus in Asia), and
+// Central America and the Caribbean count as North America.
+var continents = map[string][]string{
+	"Africa": {
+		"AO", "BF", "BI", "BJ", "BW", "CD", "CF", "CG", "CI", "CM", "CV", "DJ",
+		"DZ", "EG", "ER", "ET", "GA", "GH", "GM", "GN", "GQ", "GW", "KE", "KM",
+		"LR", "LS", "LY", "MA", "MG", "ML", "MR", "MU", "MW", "MZ", "NA", "NE",
+		"NG", "RW", "SC", "SD", "SL", "SN", "SO", "SS", "ST", "SZ", "TD", "TG",
+		"TN", "TZ", "UG", "ZA", "ZM", "ZW",
+	},
+	"Antarctica": {
+		"AQ",
+	},
+	"Asia": {
+		"AE", "AF", "AM", "AZ", "BD", "BH", "BN", "BT", "CN", "CY", "GE", "HK",
+		"ID", "IL", "IN", "IQ", "IR", "JO", "JP", "KG", "KH", "KP", "KR", "KW",
+		"KZ", "LA", "LB", "LK", "MM", "MN", "MO", "MV", "MY", "NP", "OM", "PH",
+		"PK", "PS", "QA", "SA", "SG", "SY", "TH", "TJ", "TL", "TM", "TR", "TW",
+		"UZ", "VN", "YE",
+	},
+	"Europe": {
+		"AD", "AL", "AT", "BA", "BE", "BG", "BY", "CH", "CZ", "DE", "DK", "EE",
+		"ES", "FI", "FR", "GB", "GR", "HR", "HU", "IE", "IS", "IT", "LI", "LT",
+		"LU", "LV", "MC", "MD", "ME", "MK", "MT", "NL", "NO", "PL", "PT", "RO",
+		"RS", "RU", "SE", "SI", "SK", "SM", "UA", "VA",
+	},
+	"North America": {
+		"AG", "AI", "AW", "BB", "BM", "BS", "BZ", "CA", "CR", "CU", "DM", "DO",
+		"GD", "GT", "HN", "HT", "JM", "KN", "KY", "LC", "MX", "NI", "PA", "PR",
+		"SV", "TT", "US", "VC",
+	},
+	"Oceania": {
+		"AS", "AU", "FJ", "FM", "KI", "MH", "NR", "NZ", "PG", "PW", "SB", "TO",
+		"TV", "VU", "WS",
+	},
+	"South America": {
+		"AR", "BO", "BR", "CL", "CO", "EC", "GY", "PE", "PY", "SR", "UY", "VE",
+	},
+}
+
+// Continents returns the continent names known to ContinentCodes, sorted.
+func Continents() []string {
+	names := make([]string, 0, len(continents))
+	for name := range continents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ContinentCodes returns the sorted country codes on the named continent and
+// its canonical name. The name is matched case-insensitively.
+func ContinentCodes(name string) ([]string, string, bool) {
+	for canonical, codes := range continents {
+		if strings.EqualFold(canonical, strings.TrimSpace(name)) {
+			sorted := append([]string(nil), codes...)
+			sort.Strings(sorted)
+			return sorted, canonical, true
+		}
+	}
+	return nil, "", false
+}
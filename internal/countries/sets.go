@@ -0,0 +1,75 @@
+package countries
+
+import (
+	"sort"
+	"strings"
+)
+
+// Set helpers over lists of alpha-2 codes. Each trims and upper-cases its
+// inputs, drops empty entries, and returns a sorted list without duplicates,
+// never nil.
+
+// Dedupe returns codes sorted and without duplicates.
+func Dedupe(codes []string) []string {
+	return Union(codes)
+}
+
+// Union returns every code in any of sets.
+func Union(sets ...[]string) []string {
+	set := make(map[string]bool)
+	for _, codes := range sets {
+		for _, code := range codes {
+			if code = cleanSetCode(code); code != "" {
+				set[code] = true
+			}
+		}
+	}
+	return sortedSet(set)
+}
+
+// Intersect returns the codes in both a and b.
+func Intersect(a, b []string) []string {
+	inB := toSet(b)
+	set := make(map[string]bool)
+	for _, code := range a {
+		if code = cleanSetCode(code); inB[code] {
+			set[code] = true
+		}
+	}
+	return sortedSet(set)
+}
+
+// Difference returns the codes in a that aren't in b.
+func Difference(a, b []string) []string {
+	inB := toSet(b)
+	set := make(map[string]bool)
+	for _, code := range a {
+		if code = cleanSetCode(code); code != "" && !inB[code] {
+			set[code] = true
+		}
+	}
+	return sortedSet(set)
+}
+
+func cleanSetCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func toSet(codes []string) map[string]bool {
+	set := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		if code = cleanSetCode(code); code != "" {
+			set[code] = true
+		}
+	}
+	return set
+}
+
+func sortedSet(set map[string]bool) []string {
+	codes := make([]string, 0, len(set))
+	for code := range set {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,76 @@
+package countries
+
+// territoryNames maps dependent territories and other places with their own
+// ISO 3166-1 alpha-2 code, which GeoIP databases and so UniFi's region
+// blocking usually treat like countries, to their common names. XK (Kosovo)
+// is a user-assigned code, but the major GeoIP databases use it.
+//
+// Sub-national regions such as Crimea, Donetsk, Luhansk, Abkhazia, South
+// Ossetia, Transnistria, or Northern Cyprus have no code of their own in
+// GeoIP data, so they can't be blocked separately and are deliberately not
+// mapped: resolving them to the surrounding country would block all of it.
+var territoryNames = map[string][]string{
+	"AX": {"Aland Islands", "Åland"},
+	"BL": {"Saint Barthelemy", "St. Barthelemy"},
+	"BQ": {"Caribbean Netherlands", "Bonaire, Sint Eustatius and Saba", "Bonaire"},
+	"BV": {"Bouvet Island"},
+	"CC": {"Cocos (Keeling) Islands", "Cocos Islands"},
+	"CK": {"Cook Islands"},
+	"CW": {"Curacao"},
+	"CX": {"Christmas Island"},
+	"EH": {"Western Sahara"},
+	"FK": {"Falkland Islands", "Falkland Islands (Malvinas)", "Malvinas"},
+	"FO": {"Faroe Islands", "Faeroe Islands"},
+	"GF": {"French Guiana"},
+	"GG": {"Guernsey"},
+	"GI": {"Gibraltar"},
+	"GL": {"Greenland"},
+	"GP": {"Guadeloupe"},
+	"GS": {"South Georgia and the South Sandwich Islands"},
+	"GU": {"Guam"},
+	"HM": {"Heard Island and McDonald Islands"},
+	"IM": {"Isle of Man"},
+	"IO": {"British Indian Ocean Territory"},
+	"JE": {"Jersey"},
+	"MF": {"Saint Martin", "Saint Martin (French part)"},
+	"MP": {"Northern Mariana Islands"},
+	"MQ": {"Martinique"},
+	"MS": {"Montserrat"},
+	"NC": {"New Caledonia"},
+	"NF": {"Norfolk Island"},
+	"NU": {"Niue"},
+	"PF": {"French Polynesia"},
+	"PM": {"Saint Pierre and Miquelon"},
+	"PN": {"Pitcairn", "Pitcairn Islands"},
+	"RE": {"Reunion"},
+	"SH": {"Saint Helena", "Saint Helena, Ascension and Tristan da Cunha"},
+	"SJ": {"Svalbard and Jan Mayen", "Svalbard"},
+	"SX": {"Sint Maarten", "Sint Maarten (Dutch part)"},
+	"TC": {"Turks and Caicos Islands"},
+	"TF": {"French Southern Territories"},
+	"TK": {"Tokelau"},
+	"UM": {"United States Minor Outlying Islands"},
+	"VG": {"British Virgin Islands", "Virgin Islands, British"},
+	"VI": {"U.S. Virgin Islands", "United States Virgin Islands", "Virgin Islands, U.S."},
+	"WF": {"Wallis and Futuna"},
+	"XK": {"Kosovo"},
+	"YT": {"Mayotte"},
+}
+
+// IncludeTerritories extends n with the territories in territoryNames, so
+// their names and codes resolve too. Built-in country entries take
+// precedence over territory names. It must be called before n is shared.
+func (n *Normalizer) IncludeTerritories() {
+	for code, names := range territoryNames {
+		if _, ok := n.codeToName[code]; !ok {
+			n.codeToName[code] = names[0]
+		}
+		for _, name := range names {
+			key := normalizeString(name)
+			if _, taken := n.nameToCode[key]; !taken {
+				n.nameToCode[key] = code
+				n.exact[foldName(name)] = code
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Package exitcode defines the exit statuses shared by every command, so
+// scripts can tell failure modes apart without parsing output.
+package exitcode
+
+import (
+	"errors"
+
+	"github.com/mattsblocklist/tae/internal/unifi"
+)
+
+// Exit statuses. Failure covers errors that fit no other class, such as
+// failing to write an output file.
+const (
+	OK           = 0
+	Failure      = 1
+	Usage        = 2 // bad flags, arguments, or input files
+	Auth         = 3 // the controller rejected the login
+	Controller   = 4 // the controller or a source couldn't be reached or failed a request
+	Verification = 5 // a result failed verification or drifted from what was wanted
+	Partial      = 6 // some targets of a multi-target run failed and others succeeded
+	Unchanged    = 7 // aggregate -only-changed: the countries are the same as in the previous output
+)
+
+// FromError classifies an error from the unifi package: a rejected login or
+// a request refused for lack of a session or permission is Auth, anything
+// else Controller. A nil error is OK.
+func FromError(err error) int {
+	switch {
+	case err == nil:
+		return OK
+	case errors.Is(err, unifi.ErrAuthFailed), errors.Is(err, unifi.ErrLoginRequired), errors.Is(err, unifi.ErrNoPermission):
+		return Auth
+	default:
+		return Controller
+	}
+}
+
+// Combine reduces the exit statuses of the targets of a multi-target run to
+// one: OK if all succeeded, Partial if only some did, and otherwise the
+// status of the first failure.
+func Combine(codes []int) int {
+	failed := 0
+	first := OK
+	for _, code := range codes {
+		if code != OK {
+			if failed == 0 {
+				first = code
+			}
+			failed++
+		}
+	}
+
+	switch {
+	case failed == 0:
+		return OK
+	case failed < len(codes):
+		return Partial
+	default:
+		return first
+	}
+}
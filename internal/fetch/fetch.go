@@ -0,0 +1,220 @@
+// Package fetch retrieves HTTP resources for the scrapers and for configure's
+// URL inputs, so both get the same robustness: a per-attempt timeout,
+// retries with backoff on transient failures, gzip and deflate decoding, and
+// a size limit that also holds after decompression.
+package fetch
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPClient is an interface for making HTTP requests.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DefaultMaxSize caps how much of a response Get reads, before and after
+// decompression, when Options.MaxSize is 0.
+const DefaultMaxSize = 8 << 20
+
+// ErrTooLarge is returned by Get when a body exceeds the size limit.
+var ErrTooLarge = errors.New("response too large")
+
+// Permanent marks err as a failure that retrying won't fix, such as a
+// request an offline client or a replayed recording can never answer. A
+// client returns it from Do to stop Get retrying; the message is err's.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// StatusError is returned by Get for a response that isn't 2xx.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.Code)
+}
+
+// Options controls how Get fetches a URL. The zero value makes one attempt
+// with a plain http.Client and the default size limit.
+type Options struct {
+	// Client sends the requests; nil uses a plain http.Client.
+	Client HTTPClient
+	// Header is set on every request, e.g. a User-Agent or Accept.
+	Header http.Header
+	// Timeout limits each attempt, including reading the body, on top of
+	// any timeout Client has. 0 leaves attempts to the client's timeout.
+	Timeout time.Duration
+	// Retries is how many times a network error (unless Permanent), 429,
+	// or 5xx is retried, waiting Backoff, then twice that, and so on
+	// between attempts.
+	Retries int
+	// Backoff is the first wait between attempts; 0 is one second.
+	Backoff time.Duration
+	// MaxSize caps the body before and after decompression; 0 uses
+	// DefaultMaxSize.
+	MaxSize int64
+	// OnAttempt, if set, is called after each attempt with the response's
+	// status code, or 0 if none arrived, and the attempt's error.
+	OnAttempt func(status int, err error)
+	// OnRetry, if set, is called before each retry with the error and how
+	// long Get waits before trying again.
+	OnRetry func(err error, wait time.Duration)
+}
+
+// Response is a fetched body, already decompressed, and its headers.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Get retrieves url, retrying transient failures as opts allows. Other
+// statuses, an oversized or undecodable body, and cancellation of ctx fail
+// at once, since retrying won't help.
+func Get(ctx context.Context, url string, opts Options) (*Response, error) {
+	if opts.Client == nil {
+		opts.Client = &http.Client{}
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+
+	delay := opts.Backoff
+	if delay <= 0 {
+		delay = time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		resp, retryable, err := getOnce(ctx, url, opts)
+		if err == nil || !retryable || ctx.Err() != nil || attempt >= opts.Retries {
+			return resp, err
+		}
+		if opts.OnRetry != nil {
+			opts.OnRetry(err, delay)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// getOnce makes one attempt, reporting whether a failure is worth retrying.
+func getOnce(ctx context.Context, url string, opts Options) (*Response, bool, error) {
+	reqCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range opts.Header {
+		req.Header[key] = values
+	}
+	// Setting this ourselves disables the transport's transparent gzip
+	// handling, so decodeBody is responsible for decompression.
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		if opts.OnAttempt != nil {
+			opts.OnAttempt(0, err)
+		}
+		var permanent permanentError
+		return nil, !errors.As(err, &permanent), fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := &StatusError{Code: resp.StatusCode}
+		if opts.OnAttempt != nil {
+			opts.OnAttempt(resp.StatusCode, err)
+		}
+		return nil, resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+	}
+
+	body, retryable, err := readBody(resp, opts.MaxSize)
+	if opts.OnAttempt != nil {
+		opts.OnAttempt(resp.StatusCode, err)
+	}
+	if err != nil {
+		return nil, retryable, err
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}, false, nil
+}
+
+// readBody reads and decodes a response body. A connection dropped mid-body
+// is worth retrying; a body over max or in an unknown encoding isn't.
+func readBody(resp *http.Response, max int64) ([]byte, bool, error) {
+	body, err := readLimited(resp.Body, max)
+	if err != nil {
+		return nil, !errors.Is(err, ErrTooLarge), fmt.Errorf("failed to read response: %w", err)
+	}
+
+	body, err = decodeBody(body, resp.Header.Get("Content-Encoding"), max)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return body, false, nil
+}
+
+// readLimited reads all of r, failing with ErrTooLarge past max bytes.
+func readLimited(r io.Reader, max int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > max {
+		return nil, fmt.Errorf("%w (limit %d bytes)", ErrTooLarge, max)
+	}
+	return body, nil
+}
+
+// decodeBody decompresses a response body according to its Content-Encoding.
+// The decompressed size is limited to max bytes.
+func decodeBody(body []byte, encoding string, max int64) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return readLimited(r, max)
+	case "deflate":
+		// "deflate" should be zlib-wrapped, but some servers send raw DEFLATE
+		if r, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+			defer r.Close()
+			return readLimited(r, max)
+		}
+		r := flate.NewReader(bytes.NewReader(body))
+		defer r.Close()
+		return readLimited(r, max)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+}
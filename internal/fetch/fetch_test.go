@@ -0,0 +1,245 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// clientFunc adapts a function to HTTPClient.
+type clientFunc func(*http.Request) (*http.Response, error)
+
+func (f clientFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+// sequenceServer answers the nth request with statuses[n], repeating the
+// last status once they run out, and counts the requests.
+func sequenceServer(t *testing.T, statuses []int, body string) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&requests, 1)) - 1
+		if n >= len(statuses) {
+			n = len(statuses) - 1
+		}
+		w.WriteHeader(statuses[n])
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestGetRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		body         string
+		retries      int
+		maxSize      int64
+		wantAttempts int32
+		wantStatus   int // StatusError code expected, 0 for none
+		wantTooLarge bool
+	}{
+		{name: "429 then ok", statuses: []int{429, 200}, retries: 1, wantAttempts: 2},
+		{name: "5xx twice then ok", statuses: []int{503, 502, 200}, retries: 2, wantAttempts: 3},
+		{name: "5xx until retries run out", statuses: []int{500}, retries: 2, wantAttempts: 3, wantStatus: 500},
+		{name: "no retries configured", statuses: []int{503, 200}, retries: 0, wantAttempts: 1, wantStatus: 503},
+		{name: "4xx is not retried", statuses: []int{404, 200}, retries: 3, wantAttempts: 1, wantStatus: 404},
+		{name: "403 is not retried", statuses: []int{403, 200}, retries: 3, wantAttempts: 1, wantStatus: 403},
+		{name: "too large is not retried", statuses: []int{200}, body: strings.Repeat("x", 64), retries: 3, maxSize: 16, wantAttempts: 1, wantTooLarge: true},
+		{name: "any 2xx is accepted", statuses: []int{203}, retries: 0, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := sequenceServer(t, tt.statuses, tt.body)
+
+			var retried int
+			_, err := Get(context.Background(), srv.URL, Options{
+				Retries: tt.retries,
+				Backoff: time.Millisecond,
+				MaxSize: tt.maxSize,
+				OnRetry: func(error, time.Duration) { retried++ },
+			})
+
+			if got := atomic.LoadInt32(requests); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+			if retried != int(tt.wantAttempts)-1 {
+				t.Errorf("OnRetry called %d times, want %d", retried, tt.wantAttempts-1)
+			}
+
+			var statusErr *StatusError
+			switch {
+			case tt.wantStatus != 0:
+				if !errors.As(err, &statusErr) || statusErr.Code != tt.wantStatus {
+					t.Errorf("err = %v, want status %d", err, tt.wantStatus)
+				}
+			case tt.wantTooLarge:
+				if !errors.Is(err, ErrTooLarge) {
+					t.Errorf("err = %v, want ErrTooLarge", err)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetRetriesNetworkErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantAttempts int
+	}{
+		{name: "network error is retried", err: errors.New("connection refused"), wantAttempts: 3},
+		{name: "permanent error is not", err: Permanent(errors.New("offline")), wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			client := clientFunc(func(*http.Request) (*http.Response, error) {
+				attempts++
+				return nil, tt.err
+			})
+
+			_, err := Get(context.Background(), "http://example.invalid/", Options{
+				Client:  client,
+				Retries: 2,
+				Backoff: time.Millisecond,
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.err.Error()) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zlibbed(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGetDecodesAndLimitsAfterDecompression(t *testing.T) {
+	plain := []byte(strings.Repeat("Russia\nIran\n", 100))
+
+	tests := []struct {
+		name         string
+		encoding     string
+		body         []byte
+		maxSize      int64
+		wantTooLarge bool
+	}{
+		{name: "identity", body: plain},
+		{name: "gzip", encoding: "gzip", body: gzipped(t, plain)},
+		{name: "deflate", encoding: "deflate", body: zlibbed(t, plain)},
+		// The compressed body fits the limit, but the decoded one doesn't
+		{name: "gzip over limit once decoded", encoding: "gzip", body: gzipped(t, plain), maxSize: int64(len(plain)) - 1, wantTooLarge: true},
+		{name: "deflate over limit once decoded", encoding: "deflate", body: zlibbed(t, plain), maxSize: int64(len(plain)) - 1, wantTooLarge: true},
+		{name: "limit equal to decoded size", encoding: "gzip", body: gzipped(t, plain), maxSize: int64(len(plain))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.maxSize > 0 && int64(len(tt.body)) > tt.maxSize {
+				t.Fatalf("fixture: compressed body (%d bytes) must fit the limit", len(tt.body))
+			}
+			var requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				if got := r.Header.Get("Accept-Encoding"); got != "gzip, deflate" {
+					t.Errorf("Accept-Encoding = %q", got)
+				}
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Write(tt.body)
+			}))
+			defer srv.Close()
+
+			resp, err := Get(context.Background(), srv.URL, Options{MaxSize: tt.maxSize, Retries: 2, Backoff: time.Millisecond})
+			if tt.wantTooLarge {
+				if !errors.Is(err, ErrTooLarge) {
+					t.Fatalf("err = %v, want ErrTooLarge", err)
+				}
+				if requests != 1 {
+					t.Errorf("attempts = %d, want 1", requests)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(resp.Body, plain) {
+				t.Errorf("body differs from the plain content (%d bytes, want %d)", len(resp.Body), len(plain))
+			}
+		})
+	}
+}
+
+func TestGetCancelledDuringBackoff(t *testing.T) {
+	srv, requests := sequenceServer(t, []int{503}, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	_, err := Get(ctx, srv.URL, Options{
+		Retries: 5,
+		Backoff: time.Hour,
+		OnRetry: func(error, time.Duration) { cancel() },
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Get took %s after cancellation, want it to return at once", elapsed)
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
+
+func TestGetSendsHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("User-Agent")))
+	}))
+	defer srv.Close()
+
+	resp, err := Get(context.Background(), srv.URL, Options{Header: http.Header{"User-Agent": {"tae-test"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Body) != "tae-test" {
+		t.Errorf("User-Agent = %q, want tae-test", resp.Body)
+	}
+}
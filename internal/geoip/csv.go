@@ -0,0 +1,154 @@
+package geoip
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// LoadDBIPCSV loads a db-ip.com country CSV ("start_ip,end_ip,country").
+func LoadDBIPCSV(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db-ip file: %w", err)
+	}
+	defer f.Close()
+
+	t := NewTable()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+
+	line := 0
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("failed to read db-ip file: %w", err)
+		}
+		if len(record) < 3 {
+			continue
+		}
+
+		start, end, err := ParseAddrRange(record[0], record[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		code := strings.TrimSpace(record[2])
+		// db-ip uses "ZZ" for unassigned space
+		if len(code) != 2 || code == "ZZ" {
+			continue
+		}
+		t.AddRange(code, start, end)
+	}
+
+	return t, nil
+}
+
+// LoadGeoLite2CSV loads MaxMind GeoLite2/GeoIP2 Country CSVs. blockPaths are
+// the IPv4 and/or IPv6 "Blocks" files and locationsPath is a "Locations" file
+// mapping geoname_id to country_iso_code.
+func LoadGeoLite2CSV(blockPaths []string, locationsPath string) (*Table, error) {
+	locations, err := loadGeoLite2Locations(locationsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	t := NewTable()
+	for _, path := range blockPaths {
+		if err := loadGeoLite2Blocks(t, path, locations); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+func loadGeoLite2Locations(path string) (map[string]string, error) {
+	records, columns, err := readCSVWithHeader(path)
+	if err != nil {
+		return nil, err
+	}
+
+	idCol, ok1 := columns["geoname_id"]
+	codeCol, ok2 := columns["country_iso_code"]
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("%s: missing geoname_id or country_iso_code column", path)
+	}
+
+	locations := make(map[string]string)
+	for _, record := range records {
+		if codeCol < len(record) && record[codeCol] != "" {
+			locations[record[idCol]] = record[codeCol]
+		}
+	}
+
+	return locations, nil
+}
+
+func loadGeoLite2Blocks(t *Table, path string, locations map[string]string) error {
+	records, columns, err := readCSVWithHeader(path)
+	if err != nil {
+		return err
+	}
+
+	netCol, ok := columns["network"]
+	if !ok {
+		return fmt.Errorf("%s: missing network column", path)
+	}
+	// Fall back to the registered country when the block has no geoname
+	idCols := []string{"geoname_id", "registered_country_geoname_id"}
+
+	for i, record := range records {
+		p, err := netip.ParsePrefix(record[netCol])
+		if err != nil {
+			return fmt.Errorf("%s line %d: %w", path, i+2, err)
+		}
+
+		for _, name := range idCols {
+			col, ok := columns[name]
+			if !ok || col >= len(record) {
+				continue
+			}
+			if code, ok := locations[record[col]]; ok {
+				t.Add(code, p)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// readCSVWithHeader reads a CSV file and returns its rows and a column index.
+func readCSVWithHeader(path string) ([][]string, map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("%s is empty", path)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	return records[1:], columns, nil
+}
@@ -0,0 +1,169 @@
+// Package geoip expands ISO 3166-1 alpha-2 country codes into IP ranges using
+// a user-supplied GeoIP database.
+package geoip
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// Source looks up the IP ranges allocated to a country.
+type Source interface {
+	// Prefixes returns the IPv4 and IPv6 ranges for an alpha-2 code.
+	Prefixes(alpha2 string) []netip.Prefix
+}
+
+// Table is an in-memory Source built from a GeoIP database file.
+type Table struct {
+	ranges map[string][]netip.Prefix
+}
+
+// NewTable creates an empty table.
+func NewTable() *Table {
+	return &Table{ranges: make(map[string][]netip.Prefix)}
+}
+
+// Add records a prefix for a country.
+func (t *Table) Add(alpha2 string, p netip.Prefix) {
+	code := strings.ToUpper(strings.TrimSpace(alpha2))
+	t.ranges[code] = append(t.ranges[code], p.Masked())
+}
+
+// AddRange records an inclusive address range for a country.
+func (t *Table) AddRange(alpha2 string, start, end netip.Addr) {
+	for _, p := range rangeToPrefixes(start, end) {
+		t.Add(alpha2, p)
+	}
+}
+
+// Prefixes returns the ranges recorded for a country.
+func (t *Table) Prefixes(alpha2 string) []netip.Prefix {
+	return t.ranges[strings.ToUpper(alpha2)]
+}
+
+// Countries returns the number of countries with at least one range.
+func (t *Table) Countries() int {
+	return len(t.ranges)
+}
+
+// Expand collects the ranges for every code and returns them merged, with
+// overlapping and adjacent ranges combined. Codes with no ranges are returned
+// separately so callers can report them.
+func Expand(src Source, codes []string) (prefixes []netip.Prefix, missing []string) {
+	var all []netip.Prefix
+	for _, code := range codes {
+		p := src.Prefixes(code)
+		if len(p) == 0 {
+			missing = append(missing, code)
+			continue
+		}
+		all = append(all, p...)
+	}
+	return Merge(all), missing
+}
+
+// Merge removes overlaps between prefixes and combines adjacent ranges into
+// the smallest equivalent set of CIDRs. IPv4 sorts before IPv6.
+func Merge(prefixes []netip.Prefix) []netip.Prefix {
+	type span struct{ start, end netip.Addr }
+
+	spans := make([]span, 0, len(prefixes))
+	for _, p := range prefixes {
+		p = p.Masked()
+		spans = append(spans, span{p.Addr(), lastAddr(p)})
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start.Less(spans[j].start)
+	})
+
+	var merged []span
+	for _, s := range spans {
+		if n := len(merged); n > 0 {
+			prev := &merged[n-1]
+			// Same family and overlapping or directly adjacent
+			if prev.end.BitLen() == s.start.BitLen() {
+				next := prev.end.Next()
+				if !next.IsValid() || !next.Less(s.start) {
+					if prev.end.Less(s.end) {
+						prev.end = s.end
+					}
+					continue
+				}
+			}
+		}
+		merged = append(merged, s)
+	}
+
+	var out []netip.Prefix
+	for _, s := range merged {
+		out = append(out, rangeToPrefixes(s.start, s.end)...)
+	}
+	return out
+}
+
+// ParseAddrRange parses an inclusive start/end address pair.
+func ParseAddrRange(start, end string) (netip.Addr, netip.Addr, error) {
+	s, err := netip.ParseAddr(strings.TrimSpace(start))
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, fmt.Errorf("invalid start address: %w", err)
+	}
+	e, err := netip.ParseAddr(strings.TrimSpace(end))
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, fmt.Errorf("invalid end address: %w", err)
+	}
+	s, e = s.Unmap(), e.Unmap()
+	if s.BitLen() != e.BitLen() || e.Less(s) {
+		return netip.Addr{}, netip.Addr{}, fmt.Errorf("invalid range %s - %s", s, e)
+	}
+	return s, e, nil
+}
+
+// rangeToPrefixes converts an inclusive address range into the minimal set of
+// CIDR prefixes that exactly covers it.
+func rangeToPrefixes(start, end netip.Addr) []netip.Prefix {
+	var out []netip.Prefix
+	for start.IsValid() && !end.Less(start) {
+		// Grow the prefix while it stays aligned on start and within end
+		best := netip.PrefixFrom(start, start.BitLen())
+		for bits := start.BitLen() - 1; bits >= 0; bits-- {
+			p := netip.PrefixFrom(start, bits).Masked()
+			if p.Addr() != start || end.Less(lastAddr(p)) {
+				break
+			}
+			best = p
+		}
+		out = append(out, best)
+		start = lastAddr(best).Next()
+	}
+	return out
+}
+
+// lastAddr returns the final address covered by a prefix.
+func lastAddr(p netip.Prefix) netip.Addr {
+	p = p.Masked()
+	if p.Addr().Is4() {
+		b := p.Addr().As4()
+		setHostBits(b[:], p.Bits())
+		return netip.AddrFrom4(b)
+	}
+	b := p.Addr().As16()
+	setHostBits(b[:], p.Bits())
+	return netip.AddrFrom16(b)
+}
+
+func setHostBits(b []byte, bits int) {
+	for i := range b {
+		switch {
+		case bits >= 8:
+			bits -= 8
+		case bits > 0:
+			b[i] |= 0xff >> bits
+			bits = 0
+		default:
+			b[i] = 0xff
+		}
+	}
+}
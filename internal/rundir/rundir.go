@@ -0,0 +1,49 @@
+// Package rundir manages timestamped per-run artifact directories.
+package rundir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LatestLink is the name of the symlink pointing at the most recent run.
+const LatestLink = "latest"
+
+// timeFormat names run directories so they sort chronologically.
+const timeFormat = "20060102-150405"
+
+// Create makes base/YYYYMMDD-HHMMSS for a run started at t and returns its path.
+func Create(base string, t time.Time) (string, error) {
+	dir := filepath.Join(base, t.Format(timeFormat))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create run directory: %w", err)
+	}
+	return dir, nil
+}
+
+// UpdateLatest points base/latest at dir, replacing any previous link.
+// The link target is relative so the tree can be moved or synced elsewhere.
+func UpdateLatest(base, dir string) error {
+	target, err := filepath.Rel(base, dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve run directory: %w", err)
+	}
+
+	// Create the new link beside the old one and rename it into place so
+	// readers never see a missing link.
+	link := filepath.Join(base, LatestLink)
+	tmp := link + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return fmt.Errorf("failed to create latest link: %w", err)
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to update latest link: %w", err)
+	}
+
+	return nil
+}
+
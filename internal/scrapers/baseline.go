@@ -0,0 +1,119 @@
+package scrapers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// BaselineSource is the name of the source that replays a previously
+// published list.
+const BaselineSource = "baseline"
+
+// CategoryBaseline is the category of the baseline source.
+const CategoryBaseline = "baseline"
+
+// BaselineScraper reads a previously published blocked_countries.txt or
+// blocked_countries.json, from a local path or an http(s) URL, and reports
+// its codes as a source. A country then stays listed while upstream sources
+// are temporarily unavailable, until it is removed from the baseline.
+type BaselineScraper struct {
+	*BaseScraper
+}
+
+// NewBaselineScraper creates a baseline scraper reading location.
+func NewBaselineScraper(location string, client HTTPClient) *BaselineScraper {
+	return &BaselineScraper{
+		BaseScraper: NewBaseScraper(BaselineSource, location, CategoryBaseline, client),
+	}
+}
+
+// Scrape loads the baseline list. A missing or unreadable baseline is
+// reported as an error result, contributing no countries.
+func (s *BaselineScraper) Scrape(ctx context.Context) (*ScrapeResult, error) {
+	result := s.NewResult()
+
+	content, err := s.read(ctx)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return cancelled(result, ctxErr)
+		}
+		result.ParseStatus = errorStatus(err)
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	result.ContentHash = HashContent(content)
+
+	codes, err := parseBaseline(content)
+	if err != nil {
+		result.ParseStatus = "error"
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	result.RawCountries = codes
+	if len(codes) > 0 {
+		result.ParseStatus = "success"
+	} else {
+		result.ParseStatus = "no_data"
+	}
+
+	return withReason(result, "in previously published list"), nil
+}
+
+// read fetches the baseline over HTTP or from disk.
+func (s *BaselineScraper) read(ctx context.Context) ([]byte, error) {
+	if strings.HasPrefix(s.url, "http://") || strings.HasPrefix(s.url, "https://") {
+		return s.Fetch(ctx, s.url)
+	}
+
+	content, err := os.ReadFile(s.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	return content, nil
+}
+
+// parseBaseline extracts the codes from either published format: the JSON
+// output's countries[].alpha2, or the text output's one code per line with
+// "#" comments.
+func parseBaseline(content []byte) ([]string, error) {
+	trimmed := bytes.TrimSpace(content)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var published struct {
+			Countries []struct {
+				Alpha2 string `json:"alpha2"`
+			} `json:"countries"`
+		}
+		if err := json.Unmarshal(trimmed, &published); err != nil {
+			return nil, fmt.Errorf("failed to parse baseline JSON: %w", err)
+		}
+
+		codes := make([]string, 0, len(published.Countries))
+		for _, c := range published.Countries {
+			if c.Alpha2 != "" {
+				codes = append(codes, c.Alpha2)
+			}
+		}
+		return codes, nil
+	}
+
+	var codes []string
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		codes = append(codes, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	return codes, nil
+}
@@ -0,0 +1,98 @@
+package scrapers
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// StatusCircuitOpen is the ParseStatus of a scrape that skipped its fetches
+// because the source's host had already failed repeatedly this run.
+const StatusCircuitOpen = "circuit_open"
+
+// DefaultCircuitThreshold is the number of consecutive failures after which
+// DefaultRegistry stops sending requests to a host. No scraper hits a host
+// more than three times, so a higher value would rarely save a request.
+const DefaultCircuitThreshold = 2
+
+// ErrCircuitOpen is returned by Fetch when the host's circuit is open.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitBreaker tracks consecutive request failures per host and, once a
+// host reaches the threshold, refuses further requests to it. A breaker never
+// closes again, so share one per run rather than per process lifetime. It is
+// safe for concurrent use.
+type CircuitBreaker struct {
+	threshold int
+
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive
+// failures to the same host.
+func NewCircuitBreaker(threshold int) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		failures:  make(map[string]int),
+	}
+}
+
+// Allow reports whether a request to host may be sent. A nil breaker allows
+// everything.
+func (b *CircuitBreaker) Allow(host string) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures[host] < b.threshold
+}
+
+// Record notes the outcome of a request to host. A success resets the host's
+// failure count.
+func (b *CircuitBreaker) Record(host string, ok bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ok {
+		delete(b.failures, host)
+	} else {
+		b.failures[host]++
+	}
+}
+
+// fallbackStatus is the ParseStatus for a scraper that fell back to built-in
+// data after err.
+func fallbackStatus(err error) string {
+	if errors.Is(err, ErrCircuitOpen) {
+		return StatusCircuitOpen
+	}
+	return StatusFetchError
+}
+
+// errorStatus is the ParseStatus for a scraper that gave up after err.
+func errorStatus(err error) string {
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return StatusCircuitOpen
+	case errors.Is(err, ErrErrorPage):
+		return StatusErrorPage
+	}
+	return "error"
+}
+
+// Host returns the host name of rawURL, without any port and lowercased, or
+// rawURL itself if it can't be parsed. The circuit breaker and the
+// aggregator's per-host worker cap both key hosts with it, so they agree on
+// what counts as one host.
+func Host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,157 @@
+package scrapers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattsblocklist/tae/internal/clock"
+)
+
+// failingClient answers every request with status, or with err if status is
+// 0, and counts the requests.
+func failingClient(status int, err error, requests *int) HTTPClient {
+	return clientFunc(func(req *http.Request) (*http.Response, error) {
+		*requests++
+		if status == 0 {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	})
+}
+
+func TestCircuitBreakerTrips(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		err      error
+		wantTrip bool
+	}{
+		{name: "5xx", status: http.StatusServiceUnavailable, wantTrip: true},
+		{name: "timeout", err: context.DeadlineExceeded, wantTrip: true},
+		{name: "network error", err: errors.New("connection refused"), wantTrip: true},
+		{name: "4xx means the host is up", status: http.StatusNotFound},
+		{name: "429 means the host is up", status: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			breaker := NewCircuitBreaker(DefaultCircuitThreshold)
+			s := NewUSOFACScraper(failingClient(tt.status, tt.err, &requests))
+			s.SetCircuitBreaker(breaker)
+			s.SetRetries(0)
+			start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			s.SetClock(clock.NewFake(start))
+
+			const attempts = DefaultCircuitThreshold + 3
+			var last *ScrapeResult
+			for i := 0; i < attempts; i++ {
+				result, err := s.Scrape(context.Background())
+				if err != nil {
+					t.Fatalf("Scrape: %v", err)
+				}
+				last = result
+			}
+
+			if !last.FetchedAt.Equal(start) {
+				t.Errorf("FetchedAt = %s, want the fake clock's %s", last.FetchedAt, start)
+			}
+
+			if !tt.wantTrip {
+				if requests != attempts {
+					t.Errorf("requests = %d, want %d", requests, attempts)
+				}
+				if last.ParseStatus == StatusCircuitOpen {
+					t.Errorf("ParseStatus = %q, want the breaker closed", last.ParseStatus)
+				}
+				return
+			}
+
+			// Once open the breaker stays open, sending nothing more
+			if requests != DefaultCircuitThreshold {
+				t.Errorf("requests = %d, want %d", requests, DefaultCircuitThreshold)
+			}
+			if breaker.Allow(Host(s.URL())) {
+				t.Error("breaker closed again")
+			}
+			if last.ParseStatus != StatusCircuitOpen {
+				t.Errorf("ParseStatus = %q, want %q", last.ParseStatus, StatusCircuitOpen)
+			}
+			if !UsedFallback(last.ParseStatus, len(last.RawCountries)) {
+				t.Errorf("circuit-open result with %d countries doesn't count as a fallback", len(last.RawCountries))
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerIgnoresOwnCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var requests int
+	breaker := NewCircuitBreaker(1)
+	s := NewBaseScraper("test", "https://example.invalid/", CategorySanctions, failingClient(0, context.Canceled, &requests))
+	s.SetCircuitBreaker(breaker)
+	s.SetRetries(0)
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.Fetch(ctx, s.URL()); err == nil {
+			t.Fatal("Fetch succeeded with a cancelled context")
+		}
+	}
+	if !breaker.Allow("example.invalid") {
+		t.Error("our own cancellation opened the breaker")
+	}
+}
+
+func TestCircuitBreakerSuccessResets(t *testing.T) {
+	breaker := NewCircuitBreaker(2)
+	breaker.Record("a", false)
+	breaker.Record("a", true)
+	breaker.Record("a", false)
+	if !breaker.Allow("a") {
+		t.Error("failures separated by a success opened the breaker")
+	}
+	breaker.Record("a", false)
+	if breaker.Allow("a") {
+		t.Error("two consecutive failures didn't open the breaker")
+	}
+	if !breaker.Allow("b") {
+		t.Error("one host's failures opened another's breaker")
+	}
+
+	var nilBreaker *CircuitBreaker
+	nilBreaker.Record("a", false)
+	if !nilBreaker.Allow("a") {
+		t.Error("a nil breaker refused a request")
+	}
+}
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://example.com/a", want: "example.com"},
+		{url: "https://example.com:443/b", want: "example.com"},
+		{url: "https://EXAMPLE.com:8443/", want: "example.com"},
+		{url: "http://[::1]:8080/", want: "::1"},
+		{url: "not a url", want: "not a url"},
+	}
+
+	for _, tt := range tests {
+		if got := Host(tt.url); got != tt.want {
+			t.Errorf("Host(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
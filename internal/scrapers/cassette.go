@@ -0,0 +1,295 @@
+package scrapers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/mattsblocklist/tae/internal/fetch"
+)
+
+// ErrNotRecorded is returned by a replaying Cassette for a request it has no
+// (further) recorded response for. It is returned as fetch.Permanent, since
+// a retry won't find one either.
+var ErrNotRecorded = errors.New("no recorded response")
+
+// Cassette is an HTTPClient that records every request and response to a
+// file, or replays them from one, so scrapers can be run deterministically
+// offline against responses captured once. Bodies are kept exactly as they
+// came off the wire, still compressed if they were, so Fetch decodes,
+// hashes, and parses them the same way on replay: a replayed run yields the
+// same ContentHash and ParseStatus as the recorded one.
+//
+// Requests are matched by method and URL. A URL fetched several times is
+// replayed in the order it was recorded. Transport errors are recorded too,
+// with their kind, so fallback paths, retries, and the circuit breaker see
+// the same error on replay.
+type Cassette struct {
+	path   string
+	client HTTPClient // nil when replaying
+
+	mu           sync.Mutex
+	interactions []Interaction
+	next         map[string]int // replay cursor per request key
+}
+
+// Interaction is one recorded request and its outcome. Body is used when
+// the body was valid UTF-8 and BodyBase64 otherwise; Error is set instead of
+// a response when the request failed. ErrorKind and ErrorDetail describe the
+// error so replay can rebuild one that matches it; older cassettes without
+// them replay the message alone.
+type Interaction struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body,omitempty"`
+	BodyBase64  string      `json:"body_base64,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	ErrorKind   string      `json:"error_kind,omitempty"`
+	ErrorDetail string      `json:"error_detail,omitempty"`
+}
+
+// Values of Interaction.ErrorKind.
+const (
+	ErrorKindTimeout  = "timeout"
+	ErrorKindCanceled = "canceled"
+	ErrorKindDNS      = "dns"
+	ErrorKindOther    = "other"
+)
+
+// cassetteFile is the on-disk format.
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// NewRecordingCassette returns a cassette that sends requests with client
+// (nil = a plain http.Client) and records them; Save writes them to path.
+func NewRecordingCassette(path string, client HTTPClient) *Cassette {
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &Cassette{path: path, client: client}
+}
+
+// LoadCassette reads a recorded cassette for replay. It never touches the
+// network.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette: %w", err)
+	}
+	for i, in := range file.Interactions {
+		if in.BodyBase64 != "" {
+			if _, err := base64.StdEncoding.DecodeString(in.BodyBase64); err != nil {
+				return nil, fmt.Errorf("cassette interaction %d (%s): invalid body_base64: %w", i, in.URL, err)
+			}
+		}
+	}
+
+	return &Cassette{
+		path:         path,
+		interactions: file.Interactions,
+		next:         make(map[string]int),
+	}, nil
+}
+
+// Recording reports whether the cassette records rather than replays.
+func (c *Cassette) Recording() bool {
+	return c.client != nil
+}
+
+// Do records or replays req.
+func (c *Cassette) Do(req *http.Request) (*http.Response, error) {
+	if c.Recording() {
+		return c.record(req)
+	}
+	return c.replay(req)
+}
+
+func (c *Cassette) record(req *http.Request) (*http.Response, error) {
+	in := Interaction{Method: req.Method, URL: req.URL.String()}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		in.setError(err)
+		c.add(in)
+		return nil, err
+	}
+
+	// The whole body is kept, however large; Fetch still applies its limit
+	// when it reads the replayed copy
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		in.setError(err)
+		c.add(in)
+		return nil, err
+	}
+
+	in.Status = resp.StatusCode
+	in.Header = resp.Header
+	if utf8.Valid(body) {
+		in.Body = string(body)
+	} else {
+		in.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+	c.add(in)
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// setError records err and what kind of error it is. The detail is the part
+// describeFetchError reports: the DNS failure, or the error a *url.Error
+// wraps.
+func (in *Interaction) setError(err error) {
+	in.Error = err.Error()
+
+	var (
+		netErr net.Error
+		dnsErr *net.DNSError
+		urlErr *url.Error
+	)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		in.ErrorKind = ErrorKindTimeout
+	case errors.Is(err, context.Canceled):
+		in.ErrorKind = ErrorKindCanceled
+	case errors.As(err, &dnsErr):
+		in.ErrorKind = ErrorKindDNS
+		in.ErrorDetail = dnsErr.Err
+	default:
+		in.ErrorKind = ErrorKindOther
+		if errors.As(err, &urlErr) {
+			in.ErrorDetail = urlErr.Err.Error()
+		}
+	}
+}
+
+// replayError rebuilds a recorded error: its message is the recorded one,
+// and it wraps an error of the recorded kind.
+func (in *Interaction) replayError(req *http.Request) error {
+	var kind error
+	switch in.ErrorKind {
+	case ErrorKindTimeout:
+		kind = context.DeadlineExceeded
+	case ErrorKindCanceled:
+		kind = context.Canceled
+	case ErrorKindDNS:
+		kind = &net.DNSError{Err: in.ErrorDetail, Name: req.URL.Hostname(), IsNotFound: in.ErrorDetail == "no such host"}
+	default:
+		if in.ErrorDetail == "" {
+			return errors.New(in.Error)
+		}
+		kind = &url.Error{Op: req.Method, URL: req.URL.String(), Err: errors.New(in.ErrorDetail)}
+	}
+	return &replayedError{msg: in.Error, err: kind}
+}
+
+// replayedError is a recorded error as replayed.
+type replayedError struct {
+	msg string
+	err error
+}
+
+func (e *replayedError) Error() string { return e.msg }
+func (e *replayedError) Unwrap() error { return e.err }
+
+func (c *Cassette) add(in Interaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, in)
+}
+
+func (c *Cassette) replay(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	key := req.Method + " " + req.URL.String()
+
+	c.mu.Lock()
+	skip := c.next[key]
+	var found *Interaction
+	for i := range c.interactions {
+		in := &c.interactions[i]
+		if in.Method+" "+in.URL != key {
+			continue
+		}
+		if skip == 0 {
+			found = in
+			break
+		}
+		skip--
+	}
+	if found != nil {
+		c.next[key]++
+	}
+	c.mu.Unlock()
+
+	if found == nil {
+		return nil, fetch.Permanent(fmt.Errorf("%w for %s", ErrNotRecorded, key))
+	}
+	if found.Error != "" {
+		return nil, found.replayError(req)
+	}
+
+	body := []byte(found.Body)
+	if found.BodyBase64 != "" {
+		body, _ = base64.StdEncoding.DecodeString(found.BodyBase64)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", found.Status, http.StatusText(found.Status)),
+		StatusCode:    found.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        found.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// Save writes the recorded interactions to the cassette's path. Scrapers run
+// concurrently, so interactions are sorted by URL (keeping the order of
+// repeated requests) to make recordings of the same responses identical.
+func (c *Cassette) Save() error {
+	if !c.Recording() {
+		return nil
+	}
+
+	c.mu.Lock()
+	interactions := append([]Interaction(nil), c.interactions...)
+	c.mu.Unlock()
+
+	sort.SliceStable(interactions, func(i, j int) bool {
+		if interactions[i].URL != interactions[j].URL {
+			return interactions[i].URL < interactions[j].URL
+		}
+		return interactions[i].Method < interactions[j].Method
+	})
+
+	data, err := json.MarshalIndent(cassetteFile{Interactions: interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(data, '\n'), 0644)
+}
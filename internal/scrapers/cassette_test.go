@@ -0,0 +1,101 @@
+package scrapers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestCassetteReplayMatchesRecording(t *testing.T) {
+	var page bytes.Buffer
+	w := gzip.NewWriter(&page)
+	w.Write([]byte("<html><body>Programs: Cuba, Iran, North Korea, Syria</body></html>"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "timeout", err: &url.Error{Op: "Get", URL: "https://example.invalid/", Err: context.DeadlineExceeded}},
+		{name: "canceled", err: context.Canceled},
+		{name: "dns", err: &url.Error{Op: "Get", URL: "https://example.invalid/", Err: &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}}},
+		{name: "other", err: &url.Error{Op: "Get", URL: "https://example.invalid/", Err: errors.New("connection reset by peer")}},
+		{name: "plain", err: errors.New("offline")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok := NewUSOFACScraper(nil)
+			client := clientFunc(func(req *http.Request) (*http.Response, error) {
+				if req.URL.String() != ok.URL() {
+					return nil, tt.err
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": {"text/html"}, "Content-Encoding": {"gzip"}},
+					Body:       io.NopCloser(bytes.NewReader(page.Bytes())),
+					Request:    req,
+				}, nil
+			})
+
+			path := filepath.Join(t.TempDir(), "cassette.json")
+			recording := NewRecordingCassette(path, client)
+			recorded := scrapeWith(t, recording)
+			if err := recording.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			replaying, err := LoadCassette(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			replayed := scrapeWith(t, replaying)
+
+			if recorded[0].ParseStatus != "success" || recorded[0].ContentHash == "" {
+				t.Errorf("recorded %s: status %q, hash %q, want a parsed page", recorded[0].Source, recorded[0].ParseStatus, recorded[0].ContentHash)
+			}
+			if recorded[1].ParseStatus != StatusFetchError {
+				t.Errorf("recorded %s: status %q, want %q", recorded[1].Source, recorded[1].ParseStatus, StatusFetchError)
+			}
+			for i := range recorded {
+				r, p := recorded[i], replayed[i]
+				if r.ContentHash != p.ContentHash || r.ParseStatus != p.ParseStatus ||
+					r.FallbackReason != p.FallbackReason || r.FallbackDetail != p.FallbackDetail {
+					t.Errorf("%s: replay differs from recording\n recorded: hash %q status %q reason %q detail %q\n replayed: hash %q status %q reason %q detail %q",
+						r.Source, r.ContentHash, r.ParseStatus, r.FallbackReason, r.FallbackDetail,
+						p.ContentHash, p.ParseStatus, p.FallbackReason, p.FallbackDetail)
+				}
+			}
+		})
+	}
+}
+
+// sanctionsScrapers builds the scrapers the test records and replays.
+func sanctionsScrapers(client HTTPClient) []Scraper {
+	return []Scraper{NewUSOFACScraper(client), NewUKSanctionsScraper(client)}
+}
+
+// scrapeWith runs the sanctions scrapers through client, without retries,
+// and returns their results in order.
+func scrapeWith(t *testing.T, client HTTPClient) []*ScrapeResult {
+	t.Helper()
+	var results []*ScrapeResult
+	for _, s := range sanctionsScrapers(client) {
+		s.(interface{ SetRetries(int) }).SetRetries(0)
+		result, err := s.Scrape(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", s.Name(), err)
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,43 @@
+package scrapers
+
+import "fmt"
+
+// SourceInfo describes a scraper for listings such as aggregate -list-sources.
+type SourceInfo struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Category string `json:"category,omitempty"`
+	// HasFallback is true if the scraper returns a built-in list when the
+	// source can't be fetched.
+	HasFallback bool `json:"has_fallback"`
+	// Threshold describes the inclusion rule, if the source has one.
+	Threshold string `json:"threshold,omitempty"`
+}
+
+// Describe returns the catalog entry for s without running it.
+func Describe(s Scraper) SourceInfo {
+	info := SourceInfo{
+		Name: s.Name(),
+		URL:  s.URL(),
+	}
+	if c, ok := s.(interface{ Category() string }); ok {
+		info.Category = c.Category()
+	}
+
+	switch v := s.(type) {
+	case *FreedomHouseScraper:
+		info.Threshold = fmt.Sprintf("score < %d, or status \"not free\"", v.threshold)
+	case *RSFScraper:
+		info.Threshold = fmt.Sprintf("score >= %.0f, or \"very serious\" situation", v.threshold)
+	case *OONIScraper:
+		info.Threshold = fmt.Sprintf("confirmed blocks >= %d, or anomalies >= %d", v.minBlocks, v.minBlocks*2)
+	case *EUSanctionsScraper, *USOFACScraper, *UKSanctionsScraper, *UNSanctionsScraper, *FATFScraper:
+		info.HasFallback = true
+	}
+	if f, ok := s.(interface{ HasFallback() bool }); ok {
+		info.HasFallback = f.HasFallback()
+	}
+
+	return info
+}
+
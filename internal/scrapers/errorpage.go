@@ -0,0 +1,62 @@
+package scrapers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// StatusErrorPage is the ParseStatus of a scraper without built-in data
+// whose source answered with an HTML error page instead of its data.
+const StatusErrorPage = "error_page"
+
+// ErrErrorPage is returned by Fetch when a successful response is really an
+// error page, such as a CDN block or a "service unavailable" page served
+// with status 200.
+var ErrErrorPage = errors.New("error page")
+
+// errorPageMarkers are lowercase phrases whose presence in a page title marks
+// it as an error page rather than the source's content.
+var errorPageMarkers = []string{
+	"error", "403", "404", "429", "500", "502", "503", "504",
+	"forbidden", "access denied", "not found", "unavailable", "too many requests",
+	"cloudflare", "attention required", "just a moment", "captcha",
+}
+
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// errorPageTitle reports whether body, served with contentType, is an HTML
+// page whose title marks it as an error page, and returns the title. Only
+// the title is inspected, so real HTML sources that mention errors in their
+// text still parse.
+func errorPageTitle(contentType string, body []byte) (string, bool) {
+	if !strings.Contains(strings.ToLower(contentType), "text/html") &&
+		!strings.HasPrefix(http.DetectContentType(body), "text/html") {
+		return "", false
+	}
+
+	m := titlePattern.FindSubmatch(body)
+	if m == nil {
+		return "", false
+	}
+	title := strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+
+	lower := strings.ToLower(title)
+	for _, marker := range errorPageMarkers {
+		if strings.Contains(lower, marker) {
+			return title, true
+		}
+	}
+	return "", false
+}
+
+// checkErrorPage fails with ErrErrorPage if body is an error page.
+func checkErrorPage(contentType string, body []byte) error {
+	if title, ok := errorPageTitle(contentType, body); ok {
+		return fmt.Errorf("%w: %q", ErrErrorPage, truncate([]byte(title), 80))
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package scrapers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// ParseStatus values of a result that fell back to its built-in list, one per
+// failure mode. A source whose host's circuit was open falls back too but
+// keeps StatusCircuitOpen; see UsedFallback.
+const (
+	// StatusFetchError: the source couldn't be fetched, or answered with
+	// an error page.
+	StatusFetchError = "fetch_error"
+	// StatusParseEmpty: the source was fetched but no countries were found
+	// in it.
+	StatusParseEmpty = "parse_empty"
+	// StatusNotJSON: a JSON API returned something else.
+	StatusNotJSON = "not_json"
+)
+
+// UsedFallback reports whether a result with the given ParseStatus and raw
+// country count used its built-in list.
+func UsedFallback(status string, rawCount int) bool {
+	switch status {
+	case StatusFetchError, StatusParseEmpty, StatusNotJSON:
+		return true
+	case StatusCircuitOpen:
+		// Sources with built-in data still fall back when their host's
+		// circuit is open
+		return rawCount > 0
+	default:
+		return false
+	}
+}
+
+// Why a scraper fell back to its built-in list; see
+// ScrapeResult.FallbackReason. It matches the ParseStatus except that an
+// error page, a kind of fetch error, gets its own reason.
+const (
+	// FallbackFetchError: the source couldn't be fetched.
+	FallbackFetchError = "fetch_error"
+	// FallbackParseEmpty: the source was fetched but no countries were
+	// found in it.
+	FallbackParseEmpty = "parse_empty"
+	// FallbackNotJSON: a JSON API returned something else that isn't an
+	// error page.
+	FallbackNotJSON = "not_json"
+	// FallbackErrorPage: the source answered with an HTML error page, such
+	// as a CDN block; see ErrErrorPage.
+	FallbackErrorPage = "error_page"
+)
+
+// fetchFallback fills result with the built-in countries after the fetch
+// failed with err.
+func fetchFallback(result *ScrapeResult, countries []string, err error) {
+	result.RawCountries = countries
+	result.ParseStatus = fallbackStatus(err)
+	result.FallbackReason = FallbackFetchError
+	if errors.Is(err, ErrErrorPage) {
+		result.FallbackReason = FallbackErrorPage
+	}
+	result.FallbackDetail = describeFetchError(err)
+}
+
+// emptyFallback fills result with the built-in countries after content
+// yielded none. expectJSON marks a JSON API, whose non-JSON response is
+// reported as such.
+func emptyFallback(result *ScrapeResult, countries []string, content []byte, expectJSON bool) {
+	result.RawCountries = countries
+
+	trimmed := bytes.TrimSpace(content)
+	if expectJSON && !json.Valid(trimmed) {
+		result.ParseStatus = StatusNotJSON
+		result.FallbackReason = FallbackNotJSON
+		result.FallbackDetail = fmt.Sprintf("response starts with %q", truncate(trimmed, 24))
+		return
+	}
+	result.ParseStatus = StatusParseEmpty
+	result.FallbackReason = FallbackParseEmpty
+	result.FallbackDetail = fmt.Sprintf("no countries found in %d bytes", len(content))
+}
+
+// describeFetchError shortens the common fetch failures for the summary,
+// e.g. "timeout" instead of the full wrapped client error.
+func describeFetchError(err error) string {
+	var (
+		netErr net.Error
+		dnsErr *net.DNSError
+		urlErr *url.Error
+	)
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit open"
+	case errors.Is(err, ErrResponseTooLarge):
+		return "response too large"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &dnsErr):
+		return "dns: " + dnsErr.Err
+	case errors.As(err, &urlErr):
+		return urlErr.Err.Error()
+	default:
+		return err.Error()
+	}
+}
+
+// truncate returns at most n bytes of b as a string.
+func truncate(b []byte, n int) string {
+	if len(b) > n {
+		b = b[:n]
+	}
+	return string(b)
+}
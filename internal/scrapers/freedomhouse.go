@@ -21,6 +21,7 @@ func NewFreedomHouseScraper(client HTTPClient) *FreedomHouseScraper {
 		BaseScraper: NewBaseScraper(
 			"Freedom House",
 			"https://freedomhouse.org/countries/freedom-net/scores",
+			CategoryCensorship,
 			client,
 		),
 		threshold: 40, // Countries with score < 40 are "Not Free"
@@ -35,11 +36,14 @@ func (s *FreedomHouseScraper) Scrape(ctx context.Context) (*ScrapeResult, error)
 	apiURL := "https://freedomhouse.org/api/fotn-scores"
 	content, err := s.Fetch(ctx, apiURL)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return cancelled(result, ctxErr)
+		}
 		// Fallback: try to scrape the HTML page
 		content, err = s.Fetch(ctx, s.url)
 		if err != nil {
 			result.Error = fmt.Sprintf("failed to fetch: %v", err)
-			result.ParseStatus = "error"
+			result.ParseStatus = errorStatus(err)
 			return result, nil
 		}
 		// Parse HTML fallback
@@ -67,9 +71,10 @@ func (s *FreedomHouseScraper) parseJSON(data interface{}, result *ScrapeResult)
 	case []interface{}:
 		for _, item := range v {
 			if m, ok := item.(map[string]interface{}); ok {
-				country := s.extractCountryFromMap(m)
+				country, reason := s.extractCountryFromMap(m)
 				if country != "" {
 					countries = append(countries, country)
+					result.SetReason(country, reason)
 				}
 			}
 		}
@@ -78,9 +83,10 @@ func (s *FreedomHouseScraper) parseJSON(data interface{}, result *ScrapeResult)
 		if dataArr, ok := v["data"].([]interface{}); ok {
 			for _, item := range dataArr {
 				if m, ok := item.(map[string]interface{}); ok {
-					country := s.extractCountryFromMap(m)
+					country, reason := s.extractCountryFromMap(m)
 					if country != "" {
 						countries = append(countries, country)
+						result.SetReason(country, reason)
 					}
 				}
 			}
@@ -97,8 +103,9 @@ func (s *FreedomHouseScraper) parseJSON(data interface{}, result *ScrapeResult)
 	return result, nil
 }
 
-// extractCountryFromMap extracts a country name if it meets the threshold.
-func (s *FreedomHouseScraper) extractCountryFromMap(m map[string]interface{}) string {
+// extractCountryFromMap extracts a country name if it meets the threshold,
+// along with the status or score that qualified it.
+func (s *FreedomHouseScraper) extractCountryFromMap(m map[string]interface{}) (string, string) {
 	// Look for score field
 	score := 0.0
 	if v, ok := m["score"].(float64); ok {
@@ -122,11 +129,14 @@ func (s *FreedomHouseScraper) extractCountryFromMap(m map[string]interface{}) st
 	}
 
 	// Include if "Not Free" or score below threshold
-	if status == "not free" || status == "nf" || score < float64(s.threshold) {
-		return country
+	if status == "not free" || status == "nf" {
+		return country, `Freedom House status "not free"`
+	}
+	if score < float64(s.threshold) {
+		return country, fmt.Sprintf("Freedom House score %.0f < %d", score, s.threshold)
 	}
 
-	return ""
+	return "", ""
 }
 
 // parseHTML extracts countries from Freedom House HTML page.
@@ -166,6 +176,8 @@ func (s *FreedomHouseScraper) parseHTML(content []byte, result *ScrapeResult) (*
 		if !seen[c] {
 			seen[c] = true
 			unique = append(unique, c)
+			// Every HTML pattern matches on a "Not Free" status
+			result.SetReason(c, `Freedom House status "not free"`)
 		}
 	}
 
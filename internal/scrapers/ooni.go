@@ -6,25 +6,37 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 )
 
+// defaultOONISince is the start of the aggregation window when no lookback is set.
+var defaultOONISince = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
 // OONIScraper scrapes OONI (Open Observatory of Network Interference) data.
 type OONIScraper struct {
 	*BaseScraper
 	// Minimum confirmed blocks to include a country
 	minBlocks int
+	// Start of the aggregation window; lookback, if set, takes precedence
+	since    time.Time
+	lookback time.Duration
 }
 
 // NewOONIScraper creates a new OONI scraper.
 func NewOONIScraper(client HTTPClient) *OONIScraper {
-	return &OONIScraper{
+	s := &OONIScraper{
 		BaseScraper: NewBaseScraper(
 			"OONI (Open Observatory of Network Interference)",
 			"https://ooni.org/countries/",
+			CategoryCensorship,
 			client,
 		),
 		minBlocks: 100, // Minimum confirmed blocks to include
+		since:     defaultOONISince,
 	}
+	// The aggregation API covers every country and can be large
+	s.SetMaxResponseSize(32 << 20)
+	return s
 }
 
 // Scrape fetches and parses OONI data.
@@ -33,7 +45,7 @@ func (s *OONIScraper) Scrape(ctx context.Context) (*ScrapeResult, error) {
 
 	// OONI has an API for country-level stats
 	apiURLs := []string{
-		"https://api.ooni.io/api/v1/aggregation?probe_cc=*&since=2023-01-01",
+		"https://api.ooni.io/api/v1/aggregation?probe_cc=*&since=" + s.windowStart().Format("2006-01-02"),
 		"https://api.ooni.io/api/v1/countries",
 	}
 
@@ -41,6 +53,9 @@ func (s *OONIScraper) Scrape(ctx context.Context) (*ScrapeResult, error) {
 	var err error
 
 	for _, url := range apiURLs {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return cancelled(result, ctxErr)
+		}
 		content, err = s.Fetch(ctx, url)
 		if err == nil {
 			break
@@ -48,11 +63,14 @@ func (s *OONIScraper) Scrape(ctx context.Context) (*ScrapeResult, error) {
 	}
 
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return cancelled(result, ctxErr)
+		}
 		// Fallback to scraping the countries page
 		content, err = s.Fetch(ctx, s.url)
 		if err != nil {
 			result.Error = fmt.Sprintf("failed to fetch: %v", err)
-			result.ParseStatus = "error"
+			result.ParseStatus = errorStatus(err)
 			return result, nil
 		}
 		return s.parseHTML(content, result)
@@ -79,9 +97,10 @@ func (s *OONIScraper) parseJSON(data interface{}, result *ScrapeResult) (*Scrape
 			if arr, ok := v[key].([]interface{}); ok {
 				for _, item := range arr {
 					if m, ok := item.(map[string]interface{}); ok {
-						country := s.extractCountryFromMap(m)
+						country, reason := s.extractCountryFromMap(m)
 						if country != "" {
 							countries = append(countries, country)
+							result.SetReason(country, reason)
 						}
 					}
 				}
@@ -90,9 +109,10 @@ func (s *OONIScraper) parseJSON(data interface{}, result *ScrapeResult) (*Scrape
 	case []interface{}:
 		for _, item := range v {
 			if m, ok := item.(map[string]interface{}); ok {
-				country := s.extractCountryFromMap(m)
+				country, reason := s.extractCountryFromMap(m)
 				if country != "" {
 					countries = append(countries, country)
+					result.SetReason(country, reason)
 				}
 			}
 		}
@@ -108,8 +128,9 @@ func (s *OONIScraper) parseJSON(data interface{}, result *ScrapeResult) (*Scrape
 	return result, nil
 }
 
-// extractCountryFromMap extracts a country if it shows significant censorship.
-func (s *OONIScraper) extractCountryFromMap(m map[string]interface{}) string {
+// extractCountryFromMap extracts a country if it shows significant censorship,
+// along with the measurement count that qualified it.
+func (s *OONIScraper) extractCountryFromMap(m map[string]interface{}) (string, string) {
 	// Look for confirmed/anomaly counts
 	confirmed := 0
 	anomaly := 0
@@ -131,11 +152,14 @@ func (s *OONIScraper) extractCountryFromMap(m map[string]interface{}) string {
 	}
 
 	// Include if significant blocking detected
-	if confirmed >= s.minBlocks || anomaly >= s.minBlocks*2 {
-		return country
+	if confirmed >= s.minBlocks {
+		return country, fmt.Sprintf("OONI confirmed blocks %d >= %d", confirmed, s.minBlocks)
+	}
+	if anomaly >= s.minBlocks*2 {
+		return country, fmt.Sprintf("OONI anomalies %d >= %d", anomaly, s.minBlocks*2)
 	}
 
-	return ""
+	return "", ""
 }
 
 // parseHTML extracts country codes from OONI countries page.
@@ -179,3 +203,22 @@ func (s *OONIScraper) parseHTML(content []byte, result *ScrapeResult) (*ScrapeRe
 	return result, nil
 }
 
+// SetSince sets a fixed start date for the measurement window.
+func (s *OONIScraper) SetSince(since time.Time) {
+	s.since = since
+	s.lookback = 0
+}
+
+// SetLookback sets the measurement window relative to the time of each scrape.
+func (s *OONIScraper) SetLookback(d time.Duration) {
+	s.lookback = d
+}
+
+// windowStart returns the since date for the aggregation query.
+func (s *OONIScraper) windowStart() time.Time {
+	if s.lookback > 0 {
+		return s.clock.Now().UTC().Add(-s.lookback)
+	}
+	return s.since
+}
+
@@ -1,7 +1,15 @@
 package scrapers
 
-// DefaultRegistry creates a registry with all available scrapers.
+// DefaultRegistry creates a registry with all available scrapers, sharing
+// one circuit breaker.
 func DefaultRegistry(client HTTPClient) *Registry {
+	return DefaultRegistryWith(client)
+}
+
+// DefaultRegistryWith creates the default registry plus extra scrapers, such
+// as a private feed. An extra scraper replaces a built-in one with the same
+// name. Extras that embed *BaseScraper share the circuit breaker too.
+func DefaultRegistryWith(client HTTPClient, extra ...Scraper) *Registry {
 	r := NewRegistry()
 
 	// Censorship/Freedom indices
@@ -16,6 +24,17 @@ func DefaultRegistry(client HTTPClient) *Registry {
 	r.Register(NewUNSanctionsScraper(client))
 	r.Register(NewFATFScraper(client))
 
+	for _, s := range extra {
+		r.Register(s)
+	}
+
+	// Stop retrying a host once it has failed repeatedly this run
+	breaker := NewCircuitBreaker(DefaultCircuitThreshold)
+	for _, s := range r.All() {
+		if b, ok := s.(interface{ SetCircuitBreaker(*CircuitBreaker) }); ok {
+			b.SetCircuitBreaker(breaker)
+		}
+	}
+
 	return r
 }
-
@@ -21,6 +21,7 @@ func NewRSFScraper(client HTTPClient) *RSFScraper {
 		BaseScraper: NewBaseScraper(
 			"Reporters Without Borders (RSF)",
 			"https://rsf.org/en/index",
+			CategoryCensorship,
 			client,
 		),
 		threshold: 55.0, // Countries with score > 55 are in "very serious" situation
@@ -41,6 +42,9 @@ func (s *RSFScraper) Scrape(ctx context.Context) (*ScrapeResult, error) {
 	var err error
 
 	for _, url := range apiURLs {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return cancelled(result, ctxErr)
+		}
 		content, err = s.Fetch(ctx, url)
 		if err == nil {
 			break
@@ -48,11 +52,14 @@ func (s *RSFScraper) Scrape(ctx context.Context) (*ScrapeResult, error) {
 	}
 
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return cancelled(result, ctxErr)
+		}
 		// Fallback to HTML
 		content, err = s.Fetch(ctx, s.url)
 		if err != nil {
 			result.Error = fmt.Sprintf("failed to fetch: %v", err)
-			result.ParseStatus = "error"
+			result.ParseStatus = errorStatus(err)
 			return result, nil
 		}
 		return s.parseHTML(content, result)
@@ -77,9 +84,10 @@ func (s *RSFScraper) parseJSON(data interface{}, result *ScrapeResult) (*ScrapeR
 	case []interface{}:
 		for _, item := range v {
 			if m, ok := item.(map[string]interface{}); ok {
-				country := s.extractCountryFromMap(m)
+				country, reason := s.extractCountryFromMap(m)
 				if country != "" {
 					countries = append(countries, country)
+					result.SetReason(country, reason)
 				}
 			}
 		}
@@ -89,9 +97,10 @@ func (s *RSFScraper) parseJSON(data interface{}, result *ScrapeResult) (*ScrapeR
 			if arr, ok := v[key].([]interface{}); ok {
 				for _, item := range arr {
 					if m, ok := item.(map[string]interface{}); ok {
-						country := s.extractCountryFromMap(m)
+						country, reason := s.extractCountryFromMap(m)
 						if country != "" {
 							countries = append(countries, country)
+							result.SetReason(country, reason)
 						}
 					}
 				}
@@ -109,8 +118,9 @@ func (s *RSFScraper) parseJSON(data interface{}, result *ScrapeResult) (*ScrapeR
 	return result, nil
 }
 
-// extractCountryFromMap extracts a country if it has poor press freedom.
-func (s *RSFScraper) extractCountryFromMap(m map[string]interface{}) string {
+// extractCountryFromMap extracts a country if it has poor press freedom,
+// along with the zone or score that qualified it.
+func (s *RSFScraper) extractCountryFromMap(m map[string]interface{}) (string, string) {
 	// Look for score
 	score := 0.0
 	for _, key := range []string{"score", "global_score", "index"} {
@@ -142,15 +152,15 @@ func (s *RSFScraper) extractCountryFromMap(m map[string]interface{}) string {
 	badZones := []string{"very serious", "difficult", "black", "red"}
 	for _, bad := range badZones {
 		if strings.Contains(zone, bad) {
-			return country
+			return country, fmt.Sprintf("RSF %q situation", zone)
 		}
 	}
 
 	if score >= s.threshold {
-		return country
+		return country, fmt.Sprintf("RSF score %.2f >= %.2f", score, s.threshold)
 	}
 
-	return ""
+	return "", ""
 }
 
 // parseHTML extracts countries from RSF HTML page.
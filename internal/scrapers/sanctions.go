@@ -2,9 +2,10 @@ package scrapers
 
 import (
 	"context"
-	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 )
 
 // EUSanctionsScraper scrapes the EU sanctions map.
@@ -18,6 +19,7 @@ func NewEUSanctionsScraper(client HTTPClient) *EUSanctionsScraper {
 		BaseScraper: NewBaseScraper(
 			"EU Sanctions List",
 			"https://www.sanctionsmap.eu/api/v1/sanctions",
+			CategorySanctions,
 			client,
 		),
 	}
@@ -31,9 +33,8 @@ func (s *EUSanctionsScraper) Scrape(ctx context.Context) (*ScrapeResult, error)
 	content, err := s.Fetch(ctx, s.url)
 	if err != nil {
 		// Fallback to known sanctioned countries
-		result.RawCountries = euSanctionedCountries
-		result.ParseStatus = "fallback"
-		return result, nil
+		fetchFallback(result, euSanctionedCountries, err)
+		return withReason(result, "EU restrictive measures"), nil
 	}
 
 	result.ContentHash = HashContent(content)
@@ -45,11 +46,10 @@ func (s *EUSanctionsScraper) Scrape(ctx context.Context) (*ScrapeResult, error)
 		result.RawCountries = countries
 		result.ParseStatus = "success"
 	} else {
-		result.RawCountries = euSanctionedCountries
-		result.ParseStatus = "fallback"
+		emptyFallback(result, euSanctionedCountries, content, true)
 	}
 
-	return result, nil
+	return withReason(result, "EU restrictive measures"), nil
 }
 
 // US OFAC Sanctions
@@ -62,6 +62,7 @@ func NewUSOFACScraper(client HTTPClient) *USOFACScraper {
 		BaseScraper: NewBaseScraper(
 			"US OFAC Sanctions List",
 			"https://home.treasury.gov/policy-issues/financial-sanctions/sanctions-programs-and-country-information",
+			CategorySanctions,
 			client,
 		),
 	}
@@ -72,9 +73,8 @@ func (s *USOFACScraper) Scrape(ctx context.Context) (*ScrapeResult, error) {
 
 	content, err := s.Fetch(ctx, s.url)
 	if err != nil {
-		result.RawCountries = usOFACSanctionedCountries
-		result.ParseStatus = "fallback"
-		return result, nil
+		fetchFallback(result, usOFACSanctionedCountries, err)
+		return withReason(result, "OFAC sanctioned"), nil
 	}
 
 	result.ContentHash = HashContent(content)
@@ -86,11 +86,10 @@ func (s *USOFACScraper) Scrape(ctx context.Context) (*ScrapeResult, error) {
 		result.RawCountries = countries
 		result.ParseStatus = "success"
 	} else {
-		result.RawCountries = usOFACSanctionedCountries
-		result.ParseStatus = "fallback"
+		emptyFallback(result, usOFACSanctionedCountries, content, false)
 	}
 
-	return result, nil
+	return withReason(result, "OFAC sanctioned"), nil
 }
 
 // UK Sanctions
@@ -103,6 +102,7 @@ func NewUKSanctionsScraper(client HTTPClient) *UKSanctionsScraper {
 		BaseScraper: NewBaseScraper(
 			"UK Sanctions List",
 			"https://www.gov.uk/government/collections/financial-sanctions-regime-specific-consolidated-lists-and-releases",
+			CategorySanctions,
 			client,
 		),
 	}
@@ -113,9 +113,8 @@ func (s *UKSanctionsScraper) Scrape(ctx context.Context) (*ScrapeResult, error)
 
 	content, err := s.Fetch(ctx, s.url)
 	if err != nil {
-		result.RawCountries = ukSanctionedCountries
-		result.ParseStatus = "fallback"
-		return result, nil
+		fetchFallback(result, ukSanctionedCountries, err)
+		return withReason(result, "UK financial sanctions"), nil
 	}
 
 	result.ContentHash = HashContent(content)
@@ -126,11 +125,10 @@ func (s *UKSanctionsScraper) Scrape(ctx context.Context) (*ScrapeResult, error)
 		result.RawCountries = countries
 		result.ParseStatus = "success"
 	} else {
-		result.RawCountries = ukSanctionedCountries
-		result.ParseStatus = "fallback"
+		emptyFallback(result, ukSanctionedCountries, content, false)
 	}
 
-	return result, nil
+	return withReason(result, "UK financial sanctions"), nil
 }
 
 // UN Sanctions
@@ -143,6 +141,7 @@ func NewUNSanctionsScraper(client HTTPClient) *UNSanctionsScraper {
 		BaseScraper: NewBaseScraper(
 			"UN Sanctions List",
 			"https://www.un.org/securitycouncil/sanctions/information",
+			CategorySanctions,
 			client,
 		),
 	}
@@ -153,9 +152,8 @@ func (s *UNSanctionsScraper) Scrape(ctx context.Context) (*ScrapeResult, error)
 
 	content, err := s.Fetch(ctx, s.url)
 	if err != nil {
-		result.RawCountries = unSanctionedCountries
-		result.ParseStatus = "fallback"
-		return result, nil
+		fetchFallback(result, unSanctionedCountries, err)
+		return withReason(result, "UN Security Council sanctions"), nil
 	}
 
 	result.ContentHash = HashContent(content)
@@ -166,11 +164,10 @@ func (s *UNSanctionsScraper) Scrape(ctx context.Context) (*ScrapeResult, error)
 		result.RawCountries = countries
 		result.ParseStatus = "success"
 	} else {
-		result.RawCountries = unSanctionedCountries
-		result.ParseStatus = "fallback"
+		emptyFallback(result, unSanctionedCountries, content, false)
 	}
 
-	return result, nil
+	return withReason(result, "UN Security Council sanctions"), nil
 }
 
 // FATF Grey List
@@ -183,6 +180,7 @@ func NewFATFScraper(client HTTPClient) *FATFScraper {
 		BaseScraper: NewBaseScraper(
 			"FATF Grey List",
 			"https://www.fatf-gafi.org/en/countries/black-and-grey-lists.html",
+			CategorySanctions,
 			client,
 		),
 	}
@@ -193,9 +191,8 @@ func (s *FATFScraper) Scrape(ctx context.Context) (*ScrapeResult, error) {
 
 	content, err := s.Fetch(ctx, s.url)
 	if err != nil {
-		result.RawCountries = fatfGreyListCountries
-		result.ParseStatus = "fallback"
-		return result, nil
+		fetchFallback(result, fatfGreyListCountries, err)
+		return withReason(result, "FATF increased monitoring (grey list)"), nil
 	}
 
 	result.ContentHash = HashContent(content)
@@ -206,34 +203,73 @@ func (s *FATFScraper) Scrape(ctx context.Context) (*ScrapeResult, error) {
 		result.RawCountries = countries
 		result.ParseStatus = "success"
 	} else {
-		result.RawCountries = fatfGreyListCountries
-		result.ParseStatus = "fallback"
+		emptyFallback(result, fatfGreyListCountries, content, false)
 	}
 
-	return result, nil
+	return withReason(result, "FATF increased monitoring (grey list)"), nil
+}
+
+// withReason attaches the same listing reason to every country in the result.
+func withReason(result *ScrapeResult, reason string) *ScrapeResult {
+	for _, c := range result.RawCountries {
+		result.SetReason(c, reason)
+	}
+	return result
 }
 
 // extractCountriesFromText extracts country names from text using regex patterns.
+// The text is scanned once with a combined pattern. Where names overlap, the
+// longest match wins, so "Guinea-Bissau" doesn't also count as "Guinea".
+// Results follow the order of knownCountryNames.
 func extractCountriesFromText(text string) []string {
+	found := make(map[string]bool)
+	for _, m := range countryPattern().FindAllString(text, -1) {
+		found[canonicalCountryNames[strings.ToLower(m)]] = true
+	}
+
 	var countries []string
 	seen := make(map[string]bool)
-
-	// Look for country names in the text
 	for _, country := range knownCountryNames {
-		pattern := fmt.Sprintf(`\b%s\b`, regexp.QuoteMeta(country))
-		re := regexp.MustCompile("(?i)" + pattern)
-		if re.MatchString(text) {
-			lower := strings.ToLower(country)
-			if !seen[lower] {
-				seen[lower] = true
-				countries = append(countries, country)
-			}
+		lower := strings.ToLower(country)
+		if found[country] && !seen[lower] {
+			seen[lower] = true
+			countries = append(countries, country)
 		}
 	}
 
 	return countries
 }
 
+var (
+	countryPatternOnce sync.Once
+	countryRegexp      *regexp.Regexp
+	// canonicalCountryNames maps a lowercased match to its knownCountryNames entry
+	canonicalCountryNames map[string]string
+)
+
+// countryPattern returns a single case-insensitive alternation over all
+// knownCountryNames, compiled once on first use. Go's regexp tries
+// alternatives in order, so names are listed longest first to make the
+// longest name at any position win.
+func countryPattern() *regexp.Regexp {
+	countryPatternOnce.Do(func() {
+		names := append([]string(nil), knownCountryNames...)
+		sort.SliceStable(names, func(i, j int) bool {
+			return len(names[i]) > len(names[j])
+		})
+
+		canonicalCountryNames = make(map[string]string, len(names))
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			canonicalCountryNames[strings.ToLower(name)] = name
+			quoted[i] = regexp.QuoteMeta(name)
+		}
+
+		countryRegexp = regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	})
+	return countryRegexp
+}
+
 // Fallback country lists (as of 2024)
 var euSanctionedCountries = []string{
 	"Russia", "Belarus", "Iran", "Syria", "North Korea", "Myanmar",
@@ -268,19 +304,22 @@ var fatfGreyListCountries = []string{
 	"Tanzania", "Venezuela", "Vietnam", "Yemen",
 }
 
-// knownCountryNames is a list of country names for text matching.
+// knownCountryNames is a list of country names for text matching. Names that
+// contain other names (e.g. "Republic of the Congo" and "Congo") are safe to
+// list together since extraction prefers the longest match.
 var knownCountryNames = []string{
 	"Afghanistan", "Albania", "Algeria", "Angola", "Argentina", "Armenia",
 	"Azerbaijan", "Bahrain", "Bangladesh", "Belarus", "Benin", "Bolivia",
 	"Bosnia", "Botswana", "Brazil", "Bulgaria", "Burkina Faso", "Burundi",
 	"Cambodia", "Cameroon", "Central African Republic", "Chad", "China",
 	"Colombia", "Comoros", "Congo", "Croatia", "Cuba", "Cyprus",
-	"Democratic Republic of the Congo", "Djibouti", "Dominican Republic",
+	"Democratic Republic of the Congo", "DR Congo", "Congo-Kinshasa",
+	"Republic of the Congo", "Congo-Brazzaville", "Djibouti", "Dominican Republic",
 	"Ecuador", "Egypt", "El Salvador", "Equatorial Guinea", "Eritrea",
 	"Eswatini", "Ethiopia", "Gabon", "Gambia", "Georgia", "Ghana",
 	"Guatemala", "Guinea", "Guinea-Bissau", "Haiti", "Honduras", "Hungary",
 	"India", "Indonesia", "Iran", "Iraq", "Israel", "Ivory Coast",
-	"Jamaica", "Jordan", "Kazakhstan", "Kenya", "Kosovo", "Kuwait",
+	"Jamaica", "Jordan", "Kazakhstan", "Kenya", "Kuwait",
 	"Kyrgyzstan", "Laos", "Lebanon", "Lesotho", "Liberia", "Libya",
 	"Madagascar", "Malawi", "Malaysia", "Maldives", "Mali", "Mauritania",
 	"Mexico", "Moldova", "Monaco", "Mongolia", "Montenegro", "Morocco",
@@ -296,3 +335,17 @@ var knownCountryNames = []string{
 	"Vietnam", "Yemen", "Zambia", "Zimbabwe",
 }
 
+// BuiltinCountryNames returns the hardcoded country name lists the scrapers
+// depend on, keyed by a description of each list. Every name must be
+// resolvable by the countries normalizer or it silently matches nothing.
+func BuiltinCountryNames() map[string][]string {
+	return map[string][]string{
+		"EU sanctions fallback":   euSanctionedCountries,
+		"US OFAC fallback":        usOFACSanctionedCountries,
+		"UK sanctions fallback":   ukSanctionedCountries,
+		"UN sanctions fallback":   unSanctionedCountries,
+		"FATF grey list fallback": fatfGreyListCountries,
+		"known country names":     knownCountryNames,
+	}
+}
+
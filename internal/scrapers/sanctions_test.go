@@ -0,0 +1,67 @@
+package scrapers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mattsblocklist/tae/internal/countries"
+)
+
+// extractedCodes runs extractCountriesFromText and normalizes the names it
+// finds, the way the aggregator does.
+func extractedCodes(t *testing.T, text string) []string {
+	t.Helper()
+	n := countries.NewNormalizer()
+	var codes []string
+	for _, name := range extractCountriesFromText(text) {
+		code, ok := n.Normalize(name)
+		if !ok {
+			t.Fatalf("extracted name %q doesn't normalize", name)
+		}
+		codes = append(codes, code)
+	}
+	return countries.Dedupe(codes)
+}
+
+func TestExtractCountriesPrefersLongestGuinea(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "Sanctions apply to Papua New Guinea.", want: []string{"PG"}},
+		{text: "Sanctions apply to Equatorial Guinea.", want: []string{"GQ"}},
+		{text: "Sanctions apply to Guinea-Bissau.", want: []string{"GW"}},
+		{text: "Sanctions apply to Guinea.", want: []string{"GN"}},
+		{text: "Guinea and Guinea-Bissau are listed.", want: []string{"GN", "GW"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := extractedCodes(t, tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("codes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractCountriesTellsTheCongosApart(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "Sanctions apply to the Democratic Republic of the Congo.", want: []string{"CD"}},
+		{text: "Sanctions apply to the DR Congo.", want: []string{"CD"}},
+		{text: "Sanctions apply to Congo-Kinshasa.", want: []string{"CD"}},
+		{text: "Sanctions apply to the Republic of the Congo.", want: []string{"CG"}},
+		{text: "Sanctions apply to Congo-Brazzaville.", want: []string{"CG"}},
+		{text: "Both the Democratic Republic of the Congo and the Republic of the Congo.", want: []string{"CD", "CG"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := extractedCodes(t, tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("codes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
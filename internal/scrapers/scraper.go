@@ -7,12 +7,23 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
+
+	"github.com/mattsblocklist/tae/internal/clock"
+	"github.com/mattsblocklist/tae/internal/fetch"
 )
 
-// Scraper is the interface for all country list scrapers.
+// Scraper is the interface for all country list scrapers. Custom sources
+// implement it (usually by embedding *BaseScraper) and are added with
+// Registry.Register or DefaultRegistryWith.
+//
+// Scrape should report source problems in the result rather than as an
+// error: set ParseStatus ("success", "no_data", "error", or one of the
+// fallback statuses such as StatusFetchError) and Error, and return the
+// result with a nil error. A non-nil error drops the source from the run.
+// RawCountries may hold names or alpha-2 codes; they are normalized during
+// aggregation.
 type Scraper interface {
 	// Name returns the name of this data source.
 	Name() string
@@ -24,43 +35,162 @@ type Scraper interface {
 	Scrape(ctx context.Context) (*ScrapeResult, error)
 }
 
+// Source categories used to explain why a country is listed.
+const (
+	CategorySanctions  = "sanctions"
+	CategoryCensorship = "censorship"
+)
+
+// StatusCancelled is the ParseStatus of a scrape stopped by its context.
+const StatusCancelled = "cancelled"
+
 // ScrapeResult contains the output of a scrape operation.
 type ScrapeResult struct {
 	Source       string    `json:"source"`
+	Category     string    `json:"category"`
 	URL          string    `json:"url"`
 	FetchedAt    time.Time `json:"fetched_at"`
 	ContentHash  string    `json:"content_hash"`
 	RawCountries []string  `json:"raw_countries"`
-	ParseStatus  string    `json:"parse_status"`
-	Error        string    `json:"error,omitempty"`
+	// Reasons maps a raw country token to the signal that caused it to be listed.
+	Reasons     map[string]string `json:"reasons,omitempty"`
+	ParseStatus string            `json:"parse_status"`
+	Error       string            `json:"error,omitempty"`
+	// FallbackReason says why a result fell back to built-in data, one of
+	// the Fallback* values, and FallbackDetail gives the error or finding
+	// that triggered it. Both are empty for results that didn't.
+	FallbackReason string `json:"fallback_reason,omitempty"`
+	FallbackDetail string `json:"fallback_detail,omitempty"`
+	// Changed reports that ContentHash differs from the previous run's; see
+	// aggregate.MarkChanged.
+	Changed bool `json:"changed,omitempty"`
+	// Cached reports that the result was reused from an earlier run instead
+	// of fetched; see aggregate.ResultCache.
+	Cached bool `json:"-"`
 }
 
-// HTTPClient is an interface for making HTTP requests.
-type HTTPClient interface {
-	Do(req *http.Request) (*http.Response, error)
+// RawCountry pairs a raw country token with the reason it was listed.
+type RawCountry struct {
+	Token  string `json:"token"`
+	Reason string `json:"reason,omitempty"`
 }
 
+// Entries returns RawCountries paired with their reasons, in order.
+func (r *ScrapeResult) Entries() []RawCountry {
+	entries := make([]RawCountry, 0, len(r.RawCountries))
+	for _, token := range r.RawCountries {
+		entries = append(entries, RawCountry{Token: token, Reason: r.Reasons[token]})
+	}
+	return entries
+}
+
+// SetReason records why a raw country token was included.
+func (r *ScrapeResult) SetReason(token, reason string) {
+	if reason == "" {
+		return
+	}
+	if r.Reasons == nil {
+		r.Reasons = make(map[string]string)
+	}
+	r.Reasons[token] = reason
+}
+
+// HTTPClient is an interface for making HTTP requests.
+type HTTPClient = fetch.HTTPClient
+
+// DefaultMaxResponseSize caps how much of a response Fetch will read, before
+// and after decompression, so a misbehaving source can't exhaust memory.
+const DefaultMaxResponseSize = fetch.DefaultMaxSize
+
+// ErrResponseTooLarge is returned by Fetch when a body exceeds the limit.
+var ErrResponseTooLarge = fetch.ErrTooLarge
+
+// DefaultFetchRetries is how many times Fetch retries a network error, 429,
+// or 5xx before the scraper falls back.
+const DefaultFetchRetries = 1
+
+// Default request headers sent by Fetch; SetHeader overrides them.
+const (
+	defaultUserAgent = "Mozilla/5.0 (compatible; tae-blocklist-aggregator/1.0)"
+	defaultAccept    = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+)
+
 // BaseScraper provides common functionality for scrapers.
 type BaseScraper struct {
-	name       string
-	url        string
-	httpClient HTTPClient
+	name        string
+	url         string
+	category    string
+	httpClient  HTTPClient
+	maxBodySize int64
+	breaker     *CircuitBreaker
+	timeout     time.Duration
+	retries     int
+	clock       clock.Clock
+	header      http.Header
 }
 
 // NewBaseScraper creates a new base scraper.
-func NewBaseScraper(name, url string, client HTTPClient) *BaseScraper {
+func NewBaseScraper(name, url, category string, client HTTPClient) *BaseScraper {
 	if client == nil {
 		client = &http.Client{
 			Timeout: 30 * time.Second,
 		}
 	}
 	return &BaseScraper{
-		name:       name,
-		url:        url,
-		httpClient: client,
+		name:        name,
+		url:         url,
+		category:    category,
+		httpClient:  client,
+		maxBodySize: DefaultMaxResponseSize,
+		retries:     DefaultFetchRetries,
+		clock:       clock.Real{},
+		header: http.Header{
+			"User-Agent": {defaultUserAgent},
+			"Accept":     {defaultAccept},
+		},
 	}
 }
 
+// SetMaxResponseSize overrides the response size limit for sources known
+// to return large bodies.
+func (b *BaseScraper) SetMaxResponseSize(n int64) {
+	b.maxBodySize = n
+}
+
+// SetCircuitBreaker shares a per-host failure breaker with other scrapers so
+// a dead host is only tried until the breaker opens.
+func (b *BaseScraper) SetCircuitBreaker(cb *CircuitBreaker) {
+	b.breaker = cb
+}
+
+// SetTimeout limits each request to d, on top of any timeout the HTTP
+// client has. 0 leaves requests to the client's timeout.
+func (b *BaseScraper) SetTimeout(d time.Duration) {
+	b.timeout = d
+}
+
+// SetRetries sets how many times Fetch retries a transient failure; 0
+// makes a single attempt.
+func (b *BaseScraper) SetRetries(n int) {
+	b.retries = n
+}
+
+// SetHeader sets a header sent with every request, replacing the default
+// User-Agent or Accept if key names one of them. An empty value removes it.
+func (b *BaseScraper) SetHeader(key, value string) {
+	if value == "" {
+		b.header.Del(key)
+		return
+	}
+	b.header.Set(key, value)
+}
+
+// SetClock replaces the clock results are stamped with, e.g. with a fake
+// one in tests.
+func (b *BaseScraper) SetClock(c clock.Clock) {
+	b.clock = c
+}
+
 // Name returns the scraper name.
 func (b *BaseScraper) Name() string {
 	return b.name
@@ -71,34 +201,60 @@ func (b *BaseScraper) URL() string {
 	return b.url
 }
 
-// Fetch retrieves content from a URL.
+// Category returns the source category (sanctions or censorship).
+func (b *BaseScraper) Category() string {
+	return b.category
+}
+
+// Fetch retrieves content from a URL with fetch.Get, sending the scraper's
+// headers and applying its timeout, retries, and size limit. Compressed
+// responses are decoded before returning, so callers (and HashContent) always
+// see the same bytes regardless of transfer encoding.
 func (b *BaseScraper) Fetch(ctx context.Context, url string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	host := Host(url)
+	if !b.breaker.Allow(host) {
+		return nil, fmt.Errorf("%w for %s", ErrCircuitOpen, host)
 	}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; tae-blocklist-aggregator/1.0)")
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-
-	resp, err := b.httpClient.Do(req)
+	resp, err := fetch.Get(ctx, url, fetch.Options{
+		Client:  b.httpClient,
+		Header:  b.header,
+		Timeout: b.timeout,
+		Retries: b.retries,
+		MaxSize: b.maxBodySize,
+		OnAttempt: func(status int, err error) {
+			switch {
+			case status > 0:
+				// Only server errors count against the host; a 404 means
+				// it's up
+				b.breaker.Record(host, status < 500)
+			case ctx.Err() == nil:
+				// Our own cancellation says nothing about the host, but
+				// running out of the per-request timeout does
+				b.breaker.Record(host, false)
+			}
+		},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	body := resp.Body
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	// Parsing an error page would find nothing and look like an empty list
+	if err := checkErrorPage(resp.Header.Get("Content-Type"), body); err != nil {
+		return nil, err
 	}
 
 	return body, nil
 }
 
+// cancelled marks a result as stopped by context cancellation.
+func cancelled(result *ScrapeResult, err error) (*ScrapeResult, error) {
+	result.ParseStatus = StatusCancelled
+	result.Error = fmt.Sprintf("cancelled: %v", err)
+	return result, nil
+}
+
 // HashContent returns a SHA256 hash of the content.
 func HashContent(content []byte) string {
 	hash := sha256.Sum256(content)
@@ -109,8 +265,9 @@ func HashContent(content []byte) string {
 func (b *BaseScraper) NewResult() *ScrapeResult {
 	return &ScrapeResult{
 		Source:    b.name,
+		Category:  b.category,
 		URL:       b.url,
-		FetchedAt: time.Now(),
+		FetchedAt: b.clock.Now(),
 	}
 }
 
@@ -0,0 +1,100 @@
+package scrapers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clientFunc adapts a function to HTTPClient.
+type clientFunc func(*http.Request) (*http.Response, error)
+
+func (f clientFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestFetchDecodesGzipBeforeHashing(t *testing.T) {
+	plain := []byte(strings.Repeat("Cuba\nIran\nNorth Korea\nSyria\n", 50))
+
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write(plain)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		if r.URL.Path == "/gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed.Bytes())
+			return
+		}
+		w.Write(plain)
+	}))
+	defer srv.Close()
+
+	s := NewBaseScraper("test", srv.URL, "sanctions", nil)
+	s.SetRetries(0)
+
+	plainBody, err := s.Fetch(context.Background(), srv.URL+"/plain")
+	if err != nil {
+		t.Fatalf("plain fetch: %v", err)
+	}
+	gzipBody, err := s.Fetch(context.Background(), srv.URL+"/gzip")
+	if err != nil {
+		t.Fatalf("gzip fetch: %v", err)
+	}
+
+	if !bytes.Equal(gzipBody, plainBody) {
+		t.Errorf("gzip body differs from plain body (%d bytes, want %d)", len(gzipBody), len(plainBody))
+	}
+	if got, want := HashContent(gzipBody), HashContent(plainBody); got != want {
+		t.Errorf("HashContent(gzip) = %s, want %s", got, want)
+	}
+}
+
+func TestScrapeStopsBetweenCandidatesWhenCancelled(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func(HTTPClient) Scraper
+	}{
+		{name: "rsf", new: func(c HTTPClient) Scraper { return NewRSFScraper(c) }},
+		{name: "ooni", new: func(c HTTPClient) Scraper { return NewOONIScraper(c) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// The first candidate fails and the run is cancelled while it's
+			// in flight, so no later candidate or the HTML page is tried
+			var requests []string
+			client := clientFunc(func(req *http.Request) (*http.Response, error) {
+				requests = append(requests, req.URL.String())
+				cancel()
+				return nil, errors.New("connection reset")
+			})
+
+			start := time.Now()
+			result, err := tt.new(client).Scrape(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Scrape took %s after cancellation, want it to return at once", elapsed)
+			}
+			if result.ParseStatus != StatusCancelled {
+				t.Errorf("ParseStatus = %q, want %q", result.ParseStatus, StatusCancelled)
+			}
+			if len(requests) != 1 {
+				t.Errorf("requests = %v, want only the first candidate", requests)
+			}
+		})
+	}
+}
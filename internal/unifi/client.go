@@ -4,14 +4,21 @@ package unifi
 import (
 	"bytes"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/mattsblocklist/tae/internal/console"
 )
 
 // Client represents a UniFi API client.
@@ -22,6 +29,21 @@ type Client struct {
 	csrfToken     string
 	authenticated bool
 	verbose       bool
+	loginMaxWait  time.Duration
+	userAgent     string
+	headers       map[string]string
+	// disableCSRF stops the client sending or recording X-Csrf-Token
+	disableCSRF bool
+	// controllerVersion caches ControllerVersion
+	controllerVersion string
+	// settingKey is the setting that holds region blocking, normally "usg"
+	settingKey string
+	// applied is the setting echoed by the last region blocking set
+	// response, nil if it echoed none
+	applied map[string]interface{}
+	// username and password are kept for Relogin
+	username string
+	password string
 }
 
 // ClientConfig holds configuration for creating a new client.
@@ -33,8 +55,53 @@ type ClientConfig struct {
 	SkipTLSVerify bool
 	Verbose       bool
 	Timeout       time.Duration
+	// UserAgent overrides Go's default User-Agent on every request.
+	UserAgent string
+	// Headers are added to every request, e.g. for an auth proxy in front of
+	// the controller. They cannot override the CSRF token.
+	Headers map[string]string
+	// RegionBlockingPath overrides where region blocking is read from and
+	// written to, e.g. "set/setting/usg". See ParseSettingPath.
+	RegionBlockingPath string
+	// LoginMaxWait bounds how long login backs off while the controller
+	// rate-limits it. 0 uses DefaultLoginMaxWait; negative disables retries.
+	LoginMaxWait time.Duration
+	// CACertFile is a PEM bundle of CAs to trust for the controller's
+	// certificate, e.g. an internal CA, in addition to the system roots.
+	CACertFile string
+	// DisableCSRF stops the client sending an X-Csrf-Token header or
+	// capturing one from responses, for reverse proxies that handle CSRF
+	// themselves and reject requests carrying the header.
+	DisableCSRF bool
+}
+
+// DefaultLoginMaxWait is how long login keeps retrying a rate-limited
+// controller when ClientConfig.LoginMaxWait is unset.
+const DefaultLoginMaxWait = 60 * time.Second
+
+// ErrLoginRateLimited is returned when the controller kept refusing logins
+// because of too many attempts.
+var ErrLoginRateLimited = errors.New("login rate limited")
+
+// ErrMFARequired is returned when the account needs a second factor, which
+// this client can't provide. Use a local account without MFA.
+var ErrMFARequired = errors.New("multi-factor authentication required; use a local account without MFA")
+
+// ErrAuthFailed is matched by every login error where the controller
+// answered but refused the login: bad credentials, missing permissions, a
+// required second factor, or rate limiting. Network failures don't match it.
+var ErrAuthFailed = errors.New("login rejected")
+
+// rejectedLogin marks err as a refused login, so that errors.Is matches both
+// ErrAuthFailed and whatever err wraps.
+type rejectedLogin struct {
+	err error
 }
 
+func (e rejectedLogin) Error() string { return e.err.Error() }
+
+func (e rejectedLogin) Unwrap() []error { return []error{e.err, ErrAuthFailed} }
+
 // NewClient creates a new UniFi API client.
 func NewClient(cfg ClientConfig) (*Client, error) {
 	if cfg.Host == "" {
@@ -46,6 +113,18 @@ func NewClient(cfg ClientConfig) (*Client, error) {
 	if cfg.Timeout == 0 {
 		cfg.Timeout = 30 * time.Second
 	}
+	if cfg.LoginMaxWait == 0 {
+		cfg.LoginMaxWait = DefaultLoginMaxWait
+	}
+
+	settingKey := defaultSettingKey
+	if cfg.RegionBlockingPath != "" {
+		key, err := ParseSettingPath(cfg.RegionBlockingPath)
+		if err != nil {
+			return nil, err
+		}
+		settingKey = key
+	}
 
 	// Create cookie jar for session management
 	jar, err := cookiejar.New(nil)
@@ -54,10 +133,18 @@ func NewClient(cfg ClientConfig) (*Client, error) {
 	}
 
 	// Create HTTP client with TLS configuration
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.SkipTLSVerify,
+	}
+	if cfg.CACertFile != "" {
+		pool, err := loadCACertPool(cfg.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
 	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: cfg.SkipTLSVerify,
-		},
+		TLSClientConfig: tlsConfig,
 	}
 
 	httpClient := &http.Client{
@@ -69,10 +156,17 @@ func NewClient(cfg ClientConfig) (*Client, error) {
 	baseURL := strings.TrimSuffix(cfg.Host, "/")
 
 	client := &Client{
-		baseURL:    baseURL,
-		site:       cfg.Site,
-		httpClient: httpClient,
-		verbose:    cfg.Verbose,
+		baseURL:      baseURL,
+		site:         cfg.Site,
+		httpClient:   httpClient,
+		verbose:      cfg.Verbose,
+		loginMaxWait: cfg.LoginMaxWait,
+		userAgent:    cfg.UserAgent,
+		headers:      cfg.Headers,
+		disableCSRF:  cfg.DisableCSRF,
+		settingKey:   settingKey,
+		username:     cfg.Username,
+		password:     cfg.Password,
 	}
 
 	// Authenticate
@@ -80,11 +174,139 @@ func NewClient(cfg ClientConfig) (*Client, error) {
 		return nil, fmt.Errorf("authentication failed: %w", err)
 	}
 
+	// Catch a misnamed site now rather than as a 404 on every later call
+	if err := client.resolveSite(); err != nil {
+		return nil, err
+	}
+
 	return client, nil
 }
 
+// loadCACertPool returns the system roots plus every certificate in the PEM
+// file at path. It fails if the file holds anything but valid certificates.
+func loadCACertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA cert file: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	count := 0
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("CA cert file %s: unexpected PEM block %q", path, block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("CA cert file %s: certificate %d: %w", path, count+1, err)
+		}
+		pool.AddCert(cert)
+		count++
+	}
+
+	if count == 0 {
+		return nil, fmt.Errorf("CA cert file %s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// Site describes a site on the controller.
+type Site struct {
+	ID   string `json:"_id"`
+	Name string `json:"name"` // Short name used in API paths
+	Desc string `json:"desc"` // Display name shown in the UI
+}
+
+// ListSites returns the sites visible to the authenticated user.
+func (c *Client) ListSites() ([]Site, error) {
+	body, status, err := c.Get("api/self/sites")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sites: %w", err)
+	}
+	data, err := parseEnvelope(status, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sites: %w", err)
+	}
+
+	var sites []Site
+	if err := json.Unmarshal(data, &sites); err != nil {
+		return nil, fmt.Errorf("failed to parse sites: %w", err)
+	}
+
+	return sites, nil
+}
+
+// resolveSite checks the configured site against the controller's site list.
+// If the site doesn't exist and the controller has exactly one site, that site
+// is selected instead; otherwise the error lists the available site names.
+// Controllers that don't allow listing sites are left unvalidated.
+func (c *Client) resolveSite() error {
+	sites, err := c.ListSites()
+	if err != nil || len(sites) == 0 {
+		if c.verbose {
+			console.Printf("[DEBUG] Skipping site validation: %v\n", err)
+		}
+		return nil
+	}
+
+	var names []string
+	for _, s := range sites {
+		if s.Name == c.site {
+			return nil
+		}
+		names = append(names, fmt.Sprintf("%s (%s)", s.Name, s.Desc))
+	}
+
+	if len(sites) == 1 {
+		console.Printf("Site %q not found; using the only available site %q\n", c.site, sites[0].Name)
+		c.site = sites[0].Name
+		return nil
+	}
+
+	return fmt.Errorf("site %q not found; available sites: %s", c.site, strings.Join(names, ", "))
+}
+
 // login authenticates with the UniFi controller.
 func (c *Client) login(username, password string) error {
+	deadline := time.Now().Add(c.loginMaxWait)
+	delay := 2 * time.Second
+
+	for attempt := 1; ; attempt++ {
+		retryAfter, err := c.loginOnce(username, password)
+		if !errors.Is(err, ErrLoginRateLimited) {
+			return err
+		}
+
+		// Honor the controller's hint when it gives one
+		wait := delay
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 || wait > remaining {
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+
+		if c.verbose {
+			console.Printf("Login rate limited, retrying in %s\n", wait)
+		}
+		time.Sleep(wait)
+		delay *= 2
+	}
+}
+
+// loginOnce makes a single login attempt. A rate-limited response returns an
+// error wrapping ErrLoginRateLimited and the Retry-After delay, if any.
+func (c *Client) loginOnce(username, password string) (time.Duration, error) {
 	loginURL := c.baseURL + "/api/auth/login"
 
 	payload := map[string]interface{}{
@@ -95,34 +317,114 @@ func (c *Client) login(username, password string) error {
 
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal login payload: %w", err)
+		return 0, fmt.Errorf("failed to marshal login payload: %w", err)
 	}
 
 	req, err := http.NewRequest("POST", loginURL, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("failed to create login request: %w", err)
+		return 0, fmt.Errorf("failed to create login request: %w", err)
 	}
 
+	c.addCustomHeaders(req)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("login request failed: %w", err)
+		return 0, fmt.Errorf("login request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("login failed with status %d: %s", resp.StatusCode, string(respBody))
+		if isLoginRateLimited(resp.StatusCode, respBody) {
+			return parseRetryAfter(resp.Header.Get("Retry-After")),
+				rejectedLogin{fmt.Errorf("%w: status %d: %s", ErrLoginRateLimited, resp.StatusCode, string(respBody))}
+		}
+		return 0, rejectedLogin{loginError(resp.StatusCode, respBody)}
 	}
 
-	// Extract CSRF token from response header
-	if token := resp.Header.Get("X-Csrf-Token"); token != "" {
-		c.csrfToken = token
-	}
+	c.captureCSRFToken(resp)
 
 	c.authenticated = true
-	return nil
+	return 0, nil
+}
+
+// loginErrorMessages translates the error codes UniFi returns from login,
+// either as meta.msg (classic controllers) or code (UniFi OS).
+var loginErrorMessages = map[string]string{
+	"api.err.Invalid":                           "invalid username or password",
+	"api.err.LoginRequired":                     "the controller rejected the session; check the account is a local admin",
+	"api.err.NoPermission":                      "the account does not have permission to use the API",
+	"api.err.NoSiteContext":                     "the account has no access to any site",
+	"AUTHENTICATION_FAILED_INVALID_CREDENTIALS": "invalid username or password",
+}
+
+// loginMFACodes are the login error codes that mean a second factor is needed.
+var loginMFACodes = map[string]bool{
+	"api.err.Ubic2faRequired":      true,
+	"api.err.Ubic2faTokenRequired": true,
+	"api.err.2faTokenRequired":     true,
+	"MFA_AUTH_REQUIRED":            true,
+}
+
+// loginError turns a failed login response into an actionable error. Known
+// codes get a plain explanation, and the raw code or body is kept for
+// reference.
+func loginError(status int, body []byte) error {
+	var parsed struct {
+		Meta struct {
+			Msg string `json:"msg"`
+		} `json:"meta"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(body, &parsed)
+
+	code := parsed.Meta.Msg
+	if code == "" {
+		code = parsed.Code
+	}
+
+	// UniFi OS answers a login that needs a second factor with 499
+	if loginMFACodes[code] || status == 499 {
+		return fmt.Errorf("login failed: %w (status %d: %s)", ErrMFARequired, status, code)
+	}
+	if msg, ok := loginErrorMessages[code]; ok {
+		return fmt.Errorf("login failed: %s (status %d: %s)", msg, status, code)
+	}
+	if code != "" {
+		detail := code
+		if parsed.Message != "" {
+			detail += ": " + parsed.Message
+		}
+		return fmt.Errorf("login failed with status %d: %s", status, detail)
+	}
+	return fmt.Errorf("login failed with status %d: %s", status, string(body))
+}
+
+// isLoginRateLimited recognizes brute-force protection responses: a plain 429,
+// or UniFi OS's AUTHENTICATION_FAILED_LIMIT_REACHED error code.
+func isLoginRateLimited(status int, body []byte) bool {
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+	return bytes.Contains(body, []byte("AUTHENTICATION_FAILED_LIMIT_REACHED"))
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date. It returns 0 if the header is missing or unparseable.
+func parseRetryAfter(v string) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
 }
 
 // Logout ends the current session.
@@ -148,21 +450,57 @@ func (c *Client) Logout() error {
 	return nil
 }
 
-// addHeaders adds required headers to a request.
+// addHeaders adds required headers to a request. Content-Type and Accept
+// default to JSON but are left alone if the caller already set them.
 func (c *Client) addHeaders(req *http.Request) {
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	if c.csrfToken != "" {
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	c.addCustomHeaders(req)
+	if c.csrfToken != "" && !c.disableCSRF {
 		req.Header.Set("X-Csrf-Token", c.csrfToken)
 	}
 }
 
+// addCustomHeaders applies the configured User-Agent and extra headers.
+func (c *Client) addCustomHeaders(req *http.Request) {
+	for name, value := range c.headers {
+		if http.CanonicalHeaderKey(name) == "X-Csrf-Token" {
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+}
+
 // Get performs a GET request to the specified path.
 // The path should not include the /proxy/network prefix - it will be added automatically.
 func (c *Client) Get(path string) ([]byte, int, error) {
 	return c.request("GET", path, nil)
 }
 
+// GetStream performs a GET request and returns the unread response body,
+// which the caller must close. Use it for large responses that should be
+// decoded or measured without buffering them in memory.
+func (c *Client) GetStream(path string) (io.ReadCloser, int, error) {
+	req, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return resp.Body, resp.StatusCode, nil
+}
+
 // Post performs a POST request to the specified path.
 func (c *Client) Post(path string, body interface{}) ([]byte, int, error) {
 	return c.request("POST", path, body)
@@ -178,81 +516,205 @@ func (c *Client) Delete(path string) ([]byte, int, error) {
 	return c.request("DELETE", path, nil)
 }
 
-// request performs an HTTP request to the UniFi API.
+// request performs an HTTP request to the UniFi API. If the controller
+// rejects the CSRF token, the token is refreshed and the request is retried
+// once; the session itself is kept.
 func (c *Client) request(method, path string, body interface{}) ([]byte, int, error) {
-	if !c.authenticated {
-		return nil, 0, fmt.Errorf("not authenticated")
-	}
-
-	// Build full URL with proxy prefix
-	fullURL := c.buildURL(path)
-
-	var bodyReader io.Reader
+	var bodyBytes []byte
 	if body != nil {
-		bodyBytes, err := json.Marshal(body)
+		var err error
+		bodyBytes, err = json.Marshal(body)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to marshal request body: %w", err)
 		}
+	}
+
+	respBody, status, err := c.send(method, path, bodyBytes)
+	if err != nil || c.disableCSRF || !isCSRFRejected(status, respBody) {
+		return respBody, status, err
+	}
+
+	if c.verbose {
+		console.Printf("[DEBUG] CSRF token rejected for %s %s; refreshing and retrying\n", method, path)
+	}
+	if err := c.refreshCSRFToken(); err != nil {
+		// Report the original rejection rather than the refresh failure
+		return respBody, status, nil
+	}
+
+	return c.send(method, path, bodyBytes)
+}
+
+// send makes one request with a JSON body (nil for none) and reads the response.
+func (c *Client) send(method, path string, bodyBytes []byte) ([]byte, int, error) {
+	var bodyReader io.Reader
+	if bodyBytes != nil {
 		bodyReader = bytes.NewReader(bodyBytes)
 	}
 
-	req, err := http.NewRequest(method, fullURL, bodyReader)
+	req, err := http.NewRequest(method, path, bodyReader)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return respBody, resp.StatusCode, nil
+}
+
+// isCSRFRejected recognizes a controller refusing a request because of a
+// stale or missing CSRF token, as opposed to an expired session or a missing
+// permission: a 401 or 403 whose body mentions the token.
+func isCSRFRejected(status int, body []byte) bool {
+	if status != http.StatusForbidden && status != http.StatusUnauthorized {
+		return false
+	}
+	return bytes.Contains(bytes.ToLower(body), []byte("csrf"))
+}
+
+// refreshCSRFToken fetches a current token with a cheap authenticated GET.
+// Do records the X-Csrf-Token header of every response, so the call only has
+// to succeed.
+func (c *Client) refreshCSRFToken() error {
+	req, err := http.NewRequest("GET", "api/self", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d refreshing CSRF token", resp.StatusCode)
+	}
+	if resp.Header.Get("X-Csrf-Token") == "" {
+		return fmt.Errorf("no CSRF token in refresh response")
+	}
+	return nil
+}
+
+// Do sends req with the session's auth and CSRF headers and returns the raw
+// response, which the caller must close. A request with a relative URL (no
+// host) is rewritten like Get paths, adding the proxy/network prefix; an
+// absolute URL is sent as-is.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if !c.authenticated {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	if req.URL.Host == "" {
+		fullURL, err := url.Parse(c.buildURL(req.URL.Path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to build request URL: %w", err)
+		}
+		fullURL.RawQuery = req.URL.RawQuery
+		req.URL = fullURL
+	}
+
 	c.addHeaders(req)
 
 	if c.verbose {
-		fmt.Printf("[DEBUG] %s %s\n", method, fullURL)
+		console.Printf("[DEBUG] %s %s\n", req.Method, req.URL)
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, 0, fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
-	defer resp.Body.Close()
 
-	// Update CSRF token if present in response
+	c.captureCSRFToken(resp)
+
+	return resp, nil
+}
+
+// captureCSRFToken records the CSRF token a response carries, unless CSRF
+// handling is disabled.
+func (c *Client) captureCSRFToken(resp *http.Response) {
+	if c.disableCSRF {
+		return
+	}
 	if token := resp.Header.Get("X-Csrf-Token"); token != "" {
 		c.csrfToken = token
 	}
+}
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, resp.StatusCode, fmt.Errorf("failed to read response body: %w", err)
-	}
+// URL-building rules reported by resolveURL's trace.
+const (
+	urlRuleProxy      = "proxy-prefixed"
+	urlRuleV2         = "v2"
+	urlRuleSite       = "api/s"
+	urlRuleAPI        = "api"
+	urlRuleController = "controller-scoped"
+	urlRuleSiteScope  = "site-scoped default"
+)
 
-	return respBody, resp.StatusCode, nil
+// BuildURL returns the full URL a request for path is sent to, applying the
+// same rules as Get and the other path-based methods.
+func (c *Client) BuildURL(path string) string {
+	fullURL, _ := c.resolveURL(path)
+	return fullURL
 }
 
-// buildURL constructs the full URL for an API path.
+// buildURL constructs the full URL for an API path. In verbose mode it
+// traces which rule produced the URL.
 func (c *Client) buildURL(path string) string {
+	fullURL, rule := c.resolveURL(path)
+	if c.verbose {
+		console.Printf("[DEBUG] URL %q -> %s (%s)\n", path, fullURL, rule)
+	}
+	return fullURL
+}
+
+// resolveURL constructs the full URL for an API path and names the rule
+// that matched.
+func (c *Client) resolveURL(path string) (string, string) {
 	// Remove leading slash if present
 	path = strings.TrimPrefix(path, "/")
 
 	// If path already has proxy/network prefix, use it as-is
 	if strings.HasPrefix(path, "proxy/network/") {
-		return c.baseURL + "/" + path
+		return c.baseURL + "/" + path, urlRuleProxy
 	}
 
 	// For v2 API paths, they go directly under /proxy/network/
 	if strings.HasPrefix(path, "v2/") {
-		return c.baseURL + "/proxy/network/" + path
+		return c.baseURL + "/proxy/network/" + path, urlRuleV2
 	}
 
 	// For api/s/{site}/... paths, add proxy/network prefix
 	if strings.HasPrefix(path, "api/s/") {
-		return c.baseURL + "/proxy/network/" + path
+		return c.baseURL + "/proxy/network/" + path, urlRuleSite
 	}
 
 	// For api/... paths (without site), add proxy/network prefix
 	if strings.HasPrefix(path, "api/") {
-		return c.baseURL + "/proxy/network/" + path
+		return c.baseURL + "/proxy/network/" + path, urlRuleAPI
+	}
+
+	// Known controller-level resources, e.g. self or stat/sites
+	if isControllerScoped(path) {
+		return c.baseURL + "/proxy/network/api/" + path, urlRuleController
 	}
 
 	// Default: assume it's a site-scoped path
-	return c.baseURL + "/proxy/network/api/s/" + c.site + "/" + path
+	return c.baseURL + "/proxy/network/api/s/" + c.site + "/" + path, urlRuleSiteScope
+}
+
+// Site returns the site name the client is operating on.
+func (c *Client) Site() string {
+	return c.site
 }
 
 // GetSitePath returns the API path prefix for the current site.
@@ -270,38 +732,120 @@ func (c *Client) IsAuthenticated() bool {
 	return c.authenticated
 }
 
+// SessionValid checks the session with a cheap authenticated GET. It is
+// false when the controller answers 401, i.e. the session has expired.
+func (c *Client) SessionValid() (bool, error) {
+	_, status, err := c.Get("api/self")
+	if err != nil {
+		return false, err
+	}
+	return status != http.StatusUnauthorized, nil
+}
+
+// Relogin starts a new session with the credentials the client was created
+// with, e.g. after SessionValid reports the old one expired. The old CSRF
+// token is dropped, since it belonged to the expired session. It must not
+// run concurrently with other requests.
+func (c *Client) Relogin() error {
+	if c.verbose {
+		console.Println("[DEBUG] Logging in again")
+	}
+	c.csrfToken = ""
+	c.authenticated = false
+	if err := c.login(c.username, c.password); err != nil {
+		return fmt.Errorf("re-login failed: %w", err)
+	}
+	return nil
+}
+
 // TestEndpoint tests if an endpoint exists and returns useful information.
+// The body is streamed so large responses are measured and checked for valid
+// JSON without being held in memory.
 func (c *Client) TestEndpoint(path string) (*EndpointResult, error) {
 	startTime := time.Now()
-	body, statusCode, err := c.Get(path)
-	duration := time.Since(startTime)
+	body, statusCode, err := c.GetStream(path)
 
 	result := &EndpointResult{
 		Path:       path,
-		FullURL:    c.buildURL(path),
+		FullURL:    c.BuildURL(path),
 		StatusCode: statusCode,
-		Duration:   duration,
 	}
+	result.Scope = endpointScope(result.FullURL)
 
 	if err != nil {
+		result.Duration = time.Since(startTime)
 		result.Error = err.Error()
 		return result, nil
 	}
+	defer body.Close()
+
+	sampler := &sizeSampler{max: sampleSize + 1}
+	tee := io.TeeReader(body, sampler)
+
+	// Walk the JSON tokens to check validity without building the value
+	dec := json.NewDecoder(tee)
+	tokens := 0
+	isJSON := true
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			isJSON = false
+			break
+		}
+		tokens++
+	}
+
+	// Count whatever the decoder didn't read
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		result.Duration = time.Since(startTime)
+		result.Error = fmt.Sprintf("failed to read response body: %v", err)
+		return result, nil
+	}
+	result.Duration = time.Since(startTime)
 
 	result.Exists = statusCode == http.StatusOK
-	result.ResponseSize = len(body)
+	result.ResponseSize = sampler.n
 
-	// Try to parse as JSON to check validity
-	var jsonData interface{}
-	if err := json.Unmarshal(body, &jsonData); err == nil {
+	// Error envelopes are small enough for the sample to hold them whole
+	if sampler.n <= sampleSize || !result.Exists {
+		if _, apiErr := parseEnvelope(statusCode, sampler.sample); apiErr != nil {
+			result.Exists = false
+			result.Error = apiErr.Error()
+		}
+	}
+
+	if isJSON && tokens > 0 {
 		result.IsJSON = true
-		result.ResponseSample = truncateJSON(body, 500)
+		result.ResponseSample = truncateJSON(sampler.sample, sampleSize)
 	}
 
 	return result, nil
 }
 
-// EndpointResult contains information about an endpoint test.
+// sampleSize is how much of a response body TestEndpoint keeps for display.
+const sampleSize = 500
+
+// sizeSampler counts bytes written to it and keeps the first max of them.
+type sizeSampler struct {
+	n      int
+	max    int
+	sample []byte
+}
+
+func (s *sizeSampler) Write(p []byte) (int, error) {
+	s.n += len(p)
+	if room := s.max - len(s.sample); room > 0 {
+		if room > len(p) {
+			room = len(p)
+		}
+		s.sample = append(s.sample, p[:room]...)
+	}
+	return len(p), nil
+}
+
+// EndpointResult contains information about an endpoint test. Sources lists
+// the candidate lists the path came from, when the caller tracks them.
 type EndpointResult struct {
 	Path           string        `json:"path"`
 	FullURL        string        `json:"full_url"`
@@ -312,6 +856,11 @@ type EndpointResult struct {
 	ResponseSample string        `json:"response_sample,omitempty"`
 	Duration       time.Duration `json:"duration"`
 	Error          string        `json:"error,omitempty"`
+	Sources        []string      `json:"sources,omitempty"`
+	Scope          string        `json:"scope"`
+	// AfterReauth is set by callers that renew an expired session mid-run,
+	// for results obtained with the renewed session
+	AfterReauth bool `json:"after_reauth,omitempty"`
 }
 
 // truncateJSON truncates a JSON response for display.
@@ -324,40 +873,89 @@ func truncateJSON(data []byte, maxLen int) string {
 
 // RawRequest performs a raw HTTP request without the proxy/network prefix.
 func (c *Client) RawRequest(method, fullPath string, body interface{}) ([]byte, int, error) {
-	if !c.authenticated {
-		return nil, 0, fmt.Errorf("not authenticated")
-	}
+	respBody, status, _, err := c.RawRequestFull(method, fullPath, body)
+	return respBody, status, err
+}
 
+// RawRequestFull is like RawRequest but also returns the response headers.
+// The returned headers are unmodified; verbose logging redacts them.
+func (c *Client) RawRequestFull(method, fullPath string, body interface{}) ([]byte, int, http.Header, error) {
 	fullURL := c.baseURL + "/" + strings.TrimPrefix(fullPath, "/")
 
 	var bodyReader io.Reader
 	if body != nil {
 		bodyBytes, err := json.Marshal(body)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to marshal request body: %w", err)
+			return nil, 0, nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
 		bodyReader = bytes.NewReader(bodyBytes)
 	}
 
 	req, err := http.NewRequest(method, fullURL, bodyReader)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+		return nil, 0, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	c.addHeaders(req)
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.Do(req)
 	if err != nil {
-		return nil, 0, fmt.Errorf("request failed: %w", err)
+		return nil, 0, nil, err
 	}
 	defer resp.Body.Close()
 
+	if c.verbose {
+		for name, values := range RedactHeaders(resp.Header) {
+			console.Printf("[DEBUG] < %s: %s\n", name, strings.Join(values, ", "))
+		}
+	}
+
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, resp.StatusCode, fmt.Errorf("failed to read response body: %w", err)
+		return nil, resp.StatusCode, resp.Header, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	return respBody, resp.StatusCode, nil
+	return respBody, resp.StatusCode, resp.Header, nil
+}
+
+// sensitiveHeaders carry session credentials and are redacted before display.
+var sensitiveHeaders = []string{"Set-Cookie", "Cookie", "Authorization", "X-Csrf-Token"}
+
+// RedactHeaders returns a copy of h that is safe to log or save, with
+// credential-bearing headers replaced. Cookie names are kept so it is still
+// clear which cookies the controller set.
+func RedactHeaders(h http.Header) http.Header {
+	redacted := h.Clone()
+	for _, name := range sensitiveHeaders {
+		values := redacted.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		out := make([]string, len(values))
+		for i, v := range values {
+			out[i] = redactValue(name, v)
+		}
+		redacted[http.CanonicalHeaderKey(name)] = out
+	}
+	return redacted
+}
+
+// redactValue hides a header value, keeping cookie names and attributes.
+func redactValue(name, value string) string {
+	if !strings.EqualFold(name, "Set-Cookie") && !strings.EqualFold(name, "Cookie") {
+		return "[REDACTED]"
+	}
+
+	parts := strings.Split(value, ";")
+	for i, part := range parts {
+		// Set-Cookie carries one cookie followed by attributes; Cookie
+		// carries several name=value pairs
+		if i > 0 && strings.EqualFold(name, "Set-Cookie") {
+			break
+		}
+		if cookieName, _, ok := strings.Cut(part, "="); ok {
+			parts[i] = cookieName + "=[REDACTED]"
+		}
+	}
+	return strings.Join(parts, ";")
 }
 
 // ParseURL parses a URL string.
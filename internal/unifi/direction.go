@@ -0,0 +1,163 @@
+package unifi
+
+import (
+	"fmt"
+
+	"github.com/mattsblocklist/tae/internal/countries"
+)
+
+// Per-direction country fields. Firmware that supports a different list per
+// traffic direction has these alongside geo_ip_filtering_countries, which
+// may then be left empty.
+const (
+	inboundCountriesField  = "geo_ip_filtering_countries_inbound"
+	outboundCountriesField = "geo_ip_filtering_countries_outbound"
+)
+
+// Values of geo_ip_filtering_traffic_direction.
+const (
+	DirectionBoth     = "both"
+	DirectionInbound  = "inbound"
+	DirectionOutbound = "outbound"
+)
+
+// DirectionalCountries is the blocked country list split by direction.
+// Directional is false when the setting has no per-direction fields; Inbound
+// and Outbound are then derived from the combined list and the traffic
+// direction.
+type DirectionalCountries struct {
+	Inbound     []string
+	Outbound    []string
+	Directional bool
+}
+
+// hasDirectionalFields reports whether setting has per-direction fields.
+func hasDirectionalFields(setting map[string]interface{}) bool {
+	_, in := setting[inboundCountriesField]
+	_, out := setting[outboundCountriesField]
+	return in || out
+}
+
+// DirectionalCountriesFromSetting reads the per-direction lists of a USG
+// setting. Without per-direction fields the combined list applies to the
+// directions geo_ip_filtering_traffic_direction names.
+func DirectionalCountriesFromSetting(setting map[string]interface{}) DirectionalCountries {
+	if hasDirectionalFields(setting) {
+		return DirectionalCountries{
+			Inbound:     decodeCountries(setting[inboundCountriesField]),
+			Outbound:    decodeCountries(setting[outboundCountriesField]),
+			Directional: true,
+		}
+	}
+
+	codes := decodeCountries(setting["geo_ip_filtering_countries"])
+	direction, _ := setting["geo_ip_filtering_traffic_direction"].(string)
+	var dc DirectionalCountries
+	if direction != DirectionOutbound {
+		dc.Inbound = codes
+	}
+	if direction != DirectionInbound {
+		dc.Outbound = codes
+	}
+	return dc
+}
+
+// GetBlockedCountriesByDirection returns the current blocked countries per
+// direction. Both lists are empty while region blocking is disabled.
+func (c *Client) GetBlockedCountriesByDirection() (DirectionalCountries, error) {
+	setting, err := c.GetRegionBlockingSettings()
+	if err != nil {
+		return DirectionalCountries{}, err
+	}
+
+	dc := DirectionalCountriesFromSetting(setting)
+	if enabled, _ := setting["geo_ip_filtering_enabled"].(bool); !enabled {
+		dc.Inbound, dc.Outbound = []string{}, []string{}
+	}
+	return dc, nil
+}
+
+// UpdateRegionBlockingDirectional is UpdateRegionBlockingSettings with a
+// separate list per direction. On firmware without per-direction fields the
+// lists must be equal or one of them empty, so they can be expressed as one
+// list and a traffic direction.
+func (c *Client) UpdateRegionBlockingDirectional(enabled bool, inbound, outbound []string, block string) error {
+	current, err := c.GetRegionBlockingSettings()
+	if err != nil {
+		return fmt.Errorf("failed to get current settings: %w", err)
+	}
+
+	payload, err := buildDirectionalPayload(current, c.geoIPLayout(), c.settingKey, enabled, inbound, outbound, block)
+	if err != nil {
+		return err
+	}
+	return c.postRegionBlocking(payload)
+}
+
+// buildDirectionalPayload is buildRegionBlockingPayload with a list per
+// direction, written to the per-direction fields where current has them.
+func buildDirectionalPayload(
+	current map[string]interface{},
+	layout geoIPLayout,
+	key string,
+	enabled bool,
+	inbound, outbound []string,
+	block string,
+) (map[string]interface{}, error) {
+	inbound, outbound = countries.Dedupe(inbound), countries.Dedupe(outbound)
+	combined := ApplyCodeChanges(inbound, outbound, nil)
+
+	var direction string
+	switch {
+	case len(outbound) == 0 && len(inbound) > 0:
+		direction = DirectionInbound
+	case len(inbound) == 0 && len(outbound) > 0:
+		direction = DirectionOutbound
+	default:
+		direction = DirectionBoth
+	}
+
+	directional := hasDirectionalFields(current)
+	if !directional && direction == DirectionBoth && !equalStrings(inbound, outbound) {
+		return nil, fmt.Errorf("controller has no per-direction country fields; inbound and outbound lists must match or one must be empty")
+	}
+
+	payload := buildRegionBlockingPayload(current, layout, key, enabled, combined, block, direction)
+	if directional {
+		payload[inboundCountriesField] = layout.encodeCountries(current[inboundCountriesField], inbound)
+		payload[outboundCountriesField] = layout.encodeCountries(current[outboundCountriesField], outbound)
+	}
+	return payload, nil
+}
+
+// setDirectionalFields keeps per-direction fields, where the setting has
+// them, in line with a combined list written for direction, so a later read
+// sees what was written.
+func setDirectionalFields(current map[string]interface{}, layout geoIPLayout, codes []string, direction string) {
+	if !hasDirectionalFields(current) {
+		return
+	}
+
+	var inbound, outbound []string
+	if direction != DirectionOutbound {
+		inbound = codes
+	}
+	if direction != DirectionInbound {
+		outbound = codes
+	}
+	current[inboundCountriesField] = layout.encodeCountries(current[inboundCountriesField], inbound)
+	current[outboundCountriesField] = layout.encodeCountries(current[outboundCountriesField], outbound)
+}
+
+// equalStrings reports whether two slices hold the same strings in order.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
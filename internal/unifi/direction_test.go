@@ -0,0 +1,85 @@
+package unifi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// directionalSetting has per-direction lists and an empty combined field, as
+// firmware with per-direction blocking leaves it.
+const directionalSetting = `{"_id":"1","key":"usg","site_id":"s","geo_ip_filtering_enabled":true,` +
+	`"geo_ip_filtering_countries":"","geo_ip_filtering_countries_inbound":"RU",` +
+	`"geo_ip_filtering_countries_outbound":"CN,RU","geo_ip_filtering_block":"block",` +
+	`"geo_ip_filtering_traffic_direction":"both"}`
+
+func TestDirectionalFieldsWithEmptyCombinedList(t *testing.T) {
+	setting := decodeSetting(t, directionalSetting)
+
+	dc := DirectionalCountriesFromSetting(setting)
+	want := DirectionalCountries{Inbound: []string{"RU"}, Outbound: []string{"CN", "RU"}, Directional: true}
+	if !reflect.DeepEqual(dc, want) {
+		t.Fatalf("DirectionalCountriesFromSetting = %+v, want %+v", dc, want)
+	}
+	// A code blocked in only one direction is still blocked
+	if got := CountryCodesFromSetting(setting); !reflect.DeepEqual(got, []string{"CN", "RU"}) {
+		t.Errorf("CountryCodesFromSetting = %v, want [CN RU]", got)
+	}
+
+	// Writing back what was read leaves the per-direction lists as they were
+	payload, err := buildDirectionalPayload(setting, layoutForVersion(""), "usg", true, dc.Inbound, dc.Outbound, "block")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := payload[inboundCountriesField]; got != "RU" {
+		t.Errorf("inbound field = %#v, want \"RU\"", got)
+	}
+	if got := payload[outboundCountriesField]; got != "CN,RU" {
+		t.Errorf("outbound field = %#v, want \"CN,RU\"", got)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := DirectionalCountriesFromSetting(decodeSetting(t, string(body))); !reflect.DeepEqual(got, want) {
+		t.Errorf("read back %+v, want %+v", got, want)
+	}
+}
+
+func TestDirectionalPayloadWithoutDirectionalFields(t *testing.T) {
+	tests := []struct {
+		name          string
+		inbound       []string
+		outbound      []string
+		wantDirection string
+		wantErr       bool
+	}{
+		{name: "same lists", inbound: []string{"RU"}, outbound: []string{"RU"}, wantDirection: DirectionBoth},
+		{name: "inbound only", inbound: []string{"RU"}, wantDirection: DirectionInbound},
+		{name: "outbound only", outbound: []string{"RU"}, wantDirection: DirectionOutbound},
+		{name: "different lists", inbound: []string{"RU"}, outbound: []string{"CN"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting := decodeSetting(t, commaStringSetting)
+			payload, err := buildDirectionalPayload(setting, layoutForVersion(""), "usg", true, tt.inbound, tt.outbound, "block")
+			if tt.wantErr {
+				if err == nil {
+					t.Error("want an error for lists one field can't express")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := payload["geo_ip_filtering_traffic_direction"]; got != tt.wantDirection {
+				t.Errorf("direction = %v, want %s", got, tt.wantDirection)
+			}
+			if hasDirectionalFields(payload) {
+				t.Error("payload gained per-direction fields the controller doesn't have")
+			}
+		})
+	}
+}
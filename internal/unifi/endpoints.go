@@ -1,5 +1,57 @@
 package unifi
 
+import "strings"
+
+// Candidate list names, recorded in EndpointResult.Sources.
+const (
+	EndpointSourceKnown          = "known"
+	EndpointSourceV2             = "v2"
+	EndpointSourceRegionBlocking = "region_blocking"
+	EndpointSourceFile           = "file" // discover -endpoint-file
+)
+
+// Endpoint scopes, recorded in EndpointResult.Scope. Controller-scoped
+// endpoints live under api/ and don't depend on the site; site-scoped ones
+// live under api/s/{site}/ or v2/api/site/{site}/.
+const (
+	ScopeController = "controller"
+	ScopeSite       = "site"
+)
+
+// ControllerScopedPaths are the bare paths recognized as controller-level
+// resources. They resolve to api/<path> instead of the site-scoped default,
+// so "stat/sites" means api/stat/sites, not api/s/{site}/stat/sites. Other
+// controller-level paths must be written with the api/ prefix.
+var ControllerScopedPaths = []string{
+	"self",
+	"self/sites",
+	"stat/sites",
+	"stat/admin",
+}
+
+// isControllerScoped reports whether path, without any query string, is one
+// of ControllerScopedPaths.
+func isControllerScoped(path string) bool {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	path = strings.TrimSuffix(path, "/")
+	for _, p := range ControllerScopedPaths {
+		if path == p {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointScope classifies a resolved URL as ScopeSite or ScopeController.
+func endpointScope(fullURL string) string {
+	if strings.Contains(fullURL, "/api/s/") || strings.Contains(fullURL, "/v2/api/site/") {
+		return ScopeSite
+	}
+	return ScopeController
+}
+
 // KnownEndpoints contains documented UniFi API endpoints from ubntwiki.com.
 var KnownEndpoints = []string{
 	// Controller endpoints
@@ -9,7 +61,6 @@ var KnownEndpoints = []string{
 	"api/stat/admin",
 
 	// Site-scoped endpoints (will be prefixed with api/s/{site}/)
-	"self",
 	"stat/ccode",
 	"stat/current-channel",
 	"stat/health",
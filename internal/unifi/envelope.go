@@ -0,0 +1,78 @@
+package unifi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors an APIError can match with errors.Is, by meta.msg or by status.
+var (
+	ErrLoginRequired = errors.New("session not authenticated")
+	ErrNoPermission  = errors.New("permission denied")
+	ErrNotFound      = errors.New("not found")
+)
+
+// APIError is a failure reported by the controller: a non-2xx status, or a
+// 2xx response whose envelope has meta.rc "error".
+type APIError struct {
+	Status int
+	// RC and Msg are the envelope's meta.rc and meta.msg, empty if the
+	// response had no envelope.
+	RC  string
+	Msg string
+}
+
+func (e *APIError) Error() string {
+	if e.Msg != "" {
+		return fmt.Sprintf("controller error %s (status %d)", e.Msg, e.Status)
+	}
+	return fmt.Sprintf("unexpected status %d", e.Status)
+}
+
+// Is matches the sentinel errors for well-known messages and statuses.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrLoginRequired:
+		return e.Msg == "api.err.LoginRequired" || e.Status == http.StatusUnauthorized
+	case ErrNoPermission:
+		return e.Msg == "api.err.NoPermission" || e.Status == http.StatusForbidden
+	case ErrNotFound:
+		return e.Msg == "api.err.NotFound" || e.Status == http.StatusNotFound
+	}
+	return false
+}
+
+// parseEnvelope checks a response and returns its payload. Classic
+// endpoints wrap results as {"meta": {"rc": "ok"}, "data": ...}; for those
+// data is returned, and meta.rc "error" is an *APIError even with status
+// 200. A response without an envelope is returned whole if the status is
+// 2xx. Every other response is an *APIError.
+func parseEnvelope(status int, body []byte) (json.RawMessage, error) {
+	var envelope struct {
+		Meta *struct {
+			RC  string `json:"rc"`
+			Msg string `json:"msg"`
+		} `json:"meta"`
+		Data json.RawMessage `json:"data"`
+	}
+	ok := status >= 200 && status < 300
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '{' || json.Unmarshal(trimmed, &envelope) != nil || envelope.Meta == nil {
+		if !ok {
+			return nil, &APIError{Status: status}
+		}
+		return body, nil
+	}
+
+	if !ok || envelope.Meta.RC == "error" {
+		return nil, &APIError{Status: status, RC: envelope.Meta.RC, Msg: envelope.Meta.Msg}
+	}
+	if len(envelope.Data) == 0 {
+		return json.RawMessage("null"), nil
+	}
+	return envelope.Data, nil
+}
@@ -0,0 +1,93 @@
+package unifi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mattsblocklist/tae/internal/console"
+)
+
+// Firewall group types, the group_type of a rest/firewallgroup object.
+const (
+	FirewallGroupAddress     = "address-group"
+	FirewallGroupIPv6Address = "ipv6-address-group"
+	FirewallGroupPort        = "port-group"
+	// FirewallGroupCountry groups hold country codes, so a firewall rule can
+	// block countries where region blocking isn't available.
+	FirewallGroupCountry = "country"
+)
+
+// baseFirewallGroupTypes are supported by every controller that serves
+// rest/firewallgroup.
+var baseFirewallGroupTypes = []string{FirewallGroupAddress, FirewallGroupIPv6Address, FirewallGroupPort}
+
+// countryGroupMinVersion is the oldest Network application version assumed
+// to offer country groups when the site has none to show it directly.
+const countryGroupMinVersion = "7.0.0"
+
+// FirewallGroupTypes returns the firewall group types the controller
+// supports, sorted. The endpoint has no schema to read, so the types are
+// those of the site's existing groups plus the base types, and country if
+// the controller version is new enough to offer it. It fails if
+// rest/firewallgroup can't be read.
+func (c *Client) FirewallGroupTypes() ([]string, error) {
+	body, status, err := c.Get("rest/firewallgroup")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get firewall groups: %w", err)
+	}
+	data, err := parseEnvelope(status, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get firewall groups: %w", err)
+	}
+
+	var groups []struct {
+		GroupType string `json:"group_type"`
+	}
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return nil, fmt.Errorf("failed to parse firewall groups: %w", err)
+	}
+
+	set := make(map[string]bool)
+	for _, t := range baseFirewallGroupTypes {
+		set[t] = true
+	}
+	for _, g := range groups {
+		if g.GroupType != "" {
+			set[g.GroupType] = true
+		}
+	}
+	if !set[FirewallGroupCountry] {
+		version, err := c.ControllerVersion()
+		switch {
+		case err != nil:
+			if c.verbose {
+				console.Printf("[DEBUG] Can't tell whether country groups are supported: %v\n", err)
+			}
+		case compareVersions(version, countryGroupMinVersion) >= 0:
+			set[FirewallGroupCountry] = true
+		}
+	}
+
+	types := make([]string, 0, len(set))
+	for t := range set {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types, nil
+}
+
+// SupportsCountryGroups reports whether FirewallGroupTypes includes
+// FirewallGroupCountry.
+func (c *Client) SupportsCountryGroups() (bool, error) {
+	types, err := c.FirewallGroupTypes()
+	if err != nil {
+		return false, err
+	}
+	for _, t := range types {
+		if t == FirewallGroupCountry {
+			return true, nil
+		}
+	}
+	return false, nil
+}
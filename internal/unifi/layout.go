@@ -0,0 +1,226 @@
+package unifi
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/countries"
+)
+
+// countryEncoding is how the blocked country list is stored in the USG setting.
+type countryEncoding int
+
+const (
+	// encodeCommaString stores codes as one string, e.g. "CN,RU".
+	encodeCommaString countryEncoding = iota
+	// encodeArray stores codes as a JSON array, e.g. ["CN", "RU"].
+	encodeArray
+)
+
+// geoIPLayout describes the region blocking fields for a range of
+// controller versions.
+type geoIPLayout struct {
+	// minVersion is the oldest Network application version using this layout.
+	minVersion     string
+	countriesField string
+	encoding       countryEncoding
+}
+
+// geoIPLayouts is ordered newest first. The first entry is the default and
+// matches current firmware; it is also used when the version is unknown.
+// Older controllers stored the country list as an array. Whatever encoding
+// the fetched setting already uses is kept regardless of this table.
+var geoIPLayouts = []geoIPLayout{
+	{minVersion: "7.0.0", countriesField: "geo_ip_filtering_countries", encoding: encodeCommaString},
+	{minVersion: "0", countriesField: "geo_ip_filtering_countries", encoding: encodeArray},
+}
+
+// layoutForVersion returns the layout for a controller version string.
+func layoutForVersion(version string) geoIPLayout {
+	if version == "" {
+		return geoIPLayouts[0]
+	}
+	for _, l := range geoIPLayouts {
+		if compareVersions(version, l.minVersion) >= 0 {
+			return l
+		}
+	}
+	return geoIPLayouts[0]
+}
+
+// compareVersions compares dotted numeric versions, returning -1, 0, or 1.
+// Non-numeric suffixes within a part (e.g. "3-beta") are ignored.
+func compareVersions(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = leadingInt(as[i])
+		}
+		if i < len(bs) {
+			y = leadingInt(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// leadingInt parses the leading digits of s, returning 0 if there are none.
+func leadingInt(s string) int {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
+
+// ControllerVersion returns the Network application version reported by
+// stat/sysinfo. The result is cached for the life of the client.
+func (c *Client) ControllerVersion() (string, error) {
+	if c.controllerVersion != "" {
+		return c.controllerVersion, nil
+	}
+
+	body, status, err := c.Get("stat/sysinfo")
+	if err != nil {
+		return "", fmt.Errorf("failed to get sysinfo: %w", err)
+	}
+	data, err := parseEnvelope(status, body)
+	if err != nil {
+		return "", fmt.Errorf("failed to get sysinfo: %w", err)
+	}
+
+	var info []struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return "", fmt.Errorf("failed to parse sysinfo: %w", err)
+	}
+	if len(info) == 0 || info[0].Version == "" {
+		return "", fmt.Errorf("sysinfo did not include a version")
+	}
+
+	c.controllerVersion = info[0].Version
+	return c.controllerVersion, nil
+}
+
+// geoIPLayout picks the field layout for this controller, falling back to
+// the default when the version can't be determined.
+func (c *Client) geoIPLayout() geoIPLayout {
+	version, err := c.ControllerVersion()
+	if err != nil && c.verbose {
+		console.Printf("[DEBUG] Using default geo-ip layout: %v\n", err)
+	}
+	return layoutForVersion(version)
+}
+
+// encodeCountries renders codes for the layout. An existing value that is
+// already an array keeps the array encoding.
+func (l geoIPLayout) encodeCountries(existing interface{}, codes []string) interface{} {
+	encoding := l.encoding
+	switch existing.(type) {
+	case []interface{}:
+		encoding = encodeArray
+	case string:
+		encoding = encodeCommaString
+	}
+
+	if encoding == encodeArray {
+		return append([]string{}, codes...)
+	}
+	return strings.Join(codes, ",")
+}
+
+// decodeCountries reads a country list stored as a comma string, an array,
+// or an object wrapping either under "countries". Some firmware pads the
+// string or quotes its entries, so each token is cleaned with cleanCode; the
+// result is deduplicated and sorted, so it compares equal however the
+// controller formatted it.
+func decodeCountries(value interface{}) []string {
+	var raw []string
+	switch v := value.(type) {
+	case string:
+		raw = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	case []string:
+		raw = v
+	case map[string]interface{}:
+		return decodeCountries(v["countries"])
+	}
+
+	seen := make(map[string]bool, len(raw))
+	codes := []string{}
+	for _, token := range raw {
+		code, ok := cleanCode(token)
+		if !ok || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// knownCodes checks stored codes against the country table, territories
+// included, since the controller may list codes such as XK.
+var knownCodes = func() *countries.Normalizer {
+	n := countries.NewNormalizer()
+	n.IncludeTerritories()
+	return n
+}()
+
+// warnedCodes remembers the tokens already warned about, since the setting
+// is read several times per run.
+var warnedCodes sync.Map
+
+// cleanCode upper-cases a stored token and strips quotes and whitespace
+// anywhere in it. Empty tokens are dropped silently, and tokens that aren't
+// two letters are dropped with a warning. A two-letter code the country
+// table doesn't know is kept, since the controller accepted it, but warned
+// about.
+func cleanCode(token string) (string, bool) {
+	code := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '"' || r == '\'' || r == '`' {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, token)
+
+	switch {
+	case code == "":
+		return "", false
+	case !isCountryCode(code):
+		warnCode(token, "not a two-letter code; ignored")
+		return "", false
+	case !knownCodes.IsValidCode(code):
+		warnCode(token, "not a known country code")
+	}
+	return code, true
+}
+
+func warnCode(token, problem string) {
+	if _, warned := warnedCodes.LoadOrStore(token, true); !warned {
+		fmt.Fprintf(os.Stderr, "[WARN] Controller country list entry %q: %s\n", token, problem)
+	}
+}
+
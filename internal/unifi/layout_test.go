@@ -0,0 +1,95 @@
+package unifi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Settings as stored by current firmware, by older controllers, and by
+// firmware that wraps the list in an object.
+const (
+	commaStringSetting = `{"_id":"1","key":"usg","site_id":"s","geo_ip_filtering_enabled":true,"geo_ip_filtering_countries":"RU, cn ,\"IR\"","geo_ip_filtering_block":"block","geo_ip_filtering_traffic_direction":"both"}`
+	arraySetting       = `{"_id":"1","key":"usg","site_id":"s","geo_ip_filtering_enabled":true,"geo_ip_filtering_countries":["RU","cn","IR","RU"],"geo_ip_filtering_block":"block","geo_ip_filtering_traffic_direction":"both"}`
+	objectSetting      = `{"_id":"1","key":"usg","site_id":"s","geo_ip_filtering_enabled":true,"geo_ip_filtering_countries":{"countries":["IR","RU","CN"]},"geo_ip_filtering_block":"block","geo_ip_filtering_traffic_direction":"both"}`
+)
+
+func decodeSetting(t *testing.T, fixture string) map[string]interface{} {
+	t.Helper()
+	var setting map[string]interface{}
+	if err := json.Unmarshal([]byte(fixture), &setting); err != nil {
+		t.Fatalf("fixture: %v", err)
+	}
+	return setting
+}
+
+func TestLayoutForVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    countryEncoding
+	}{
+		{version: "", want: encodeCommaString},
+		{version: "8.1.113", want: encodeCommaString},
+		{version: "7.0.0", want: encodeCommaString},
+		{version: "6.5.55", want: encodeArray},
+		{version: "5.14.23-beta", want: encodeArray},
+	}
+
+	for _, tt := range tests {
+		if got := layoutForVersion(tt.version).encoding; got != tt.want {
+			t.Errorf("layoutForVersion(%q).encoding = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestCountryLayoutsDecodeAndRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		version string
+		want    interface{} // encoded countries in the update payload
+	}{
+		{name: "comma string", fixture: commaStringSetting, version: "8.1.113", want: "CN,IR,KP"},
+		{name: "array", fixture: arraySetting, version: "6.5.55", want: []interface{}{"CN", "IR", "KP"}},
+		// The existing encoding wins over the version's layout
+		{name: "array on new firmware", fixture: arraySetting, version: "8.1.113", want: []interface{}{"CN", "IR", "KP"}},
+		{name: "comma string on old firmware", fixture: commaStringSetting, version: "6.5.55", want: "CN,IR,KP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setting := decodeSetting(t, tt.fixture)
+			if got, want := CountryCodesFromSetting(setting), []string{"CN", "IR", "RU"}; !reflect.DeepEqual(got, want) {
+				t.Fatalf("decoded %v, want %v", got, want)
+			}
+
+			codes := []string{"CN", "IR", "KP"}
+			payload := buildRegionBlockingPayload(setting, layoutForVersion(tt.version), "usg", true, codes, "block", DirectionBoth)
+
+			// Round-trip through JSON, as the controller stores and returns it
+			body, err := json.Marshal(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stored := decodeSetting(t, string(body))
+			if got := stored["geo_ip_filtering_countries"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("payload countries = %#v, want %#v", got, tt.want)
+			}
+			if got := CountryCodesFromSetting(stored); !reflect.DeepEqual(got, codes) {
+				t.Errorf("read back %v, want %v", got, codes)
+			}
+			for _, key := range []string{"_id", "key", "site_id"} {
+				if stored[key] == nil {
+					t.Errorf("payload dropped %q", key)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeCountriesObjectLayout(t *testing.T) {
+	setting := decodeSetting(t, objectSetting)
+	if got, want := CountryCodesFromSetting(setting), []string{"CN", "IR", "RU"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %v, want %v", got, want)
+	}
+}
@@ -3,49 +3,70 @@ package unifi
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+
+	"github.com/mattsblocklist/tae/internal/console"
+	"github.com/mattsblocklist/tae/internal/countries"
 )
 
+// RegionBlockingClient is the subset of Client used to read and apply region
+// blocking settings. Commands depend on it so their logic can run against a
+// fake controller.
+type RegionBlockingClient interface {
+	GetRegionBlockingSettings() (map[string]interface{}, error)
+	GetBlockedCountries() ([]string, error)
+	UpdateRegionBlockingSettings(enabled bool, countryCodes []string, block string, trafficDirection string) error
+	ValidateRegionBlockingSettings(enabled bool, countryCodes []string, block string, trafficDirection string) ([]string, error)
+	RegionBlockingPayload(enabled bool, countryCodes []string, block string, trafficDirection string) (map[string]interface{}, error)
+	EnsureBlockedCountries(add, remove []string) ([]string, error)
+	GetSupportedCountries() ([]string, error)
+	GetBlockedCountriesByDirection() (DirectionalCountries, error)
+	UpdateRegionBlockingDirectional(enabled bool, inbound, outbound []string, block string) error
+}
+
+var _ RegionBlockingClient = (*Client)(nil)
+
 // GetRegionBlockingSettings fetches the current USG setting containing region blocking configuration.
 // Returns the full setting as a map to preserve all fields when updating.
 func (c *Client) GetRegionBlockingSettings() (map[string]interface{}, error) {
 	// Try to get the usg setting - it's usually an array with one element
-	path := fmt.Sprintf("api/s/%s/rest/setting/usg", c.site)
+	path := fmt.Sprintf("api/s/%s/rest/setting/%s", c.site, c.settingKey)
 	body, status, err := c.Get(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get usg settings: %w", err)
+		return nil, fmt.Errorf("failed to get %s settings: %w", c.settingKey, err)
 	}
 
-	if status != 200 {
-		return nil, fmt.Errorf("unexpected status %d when getting usg settings", status)
+	data, err := parseEnvelope(status, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s settings: %w", c.settingKey, err)
 	}
 
-	// Parse response - could be array or single object
-	var settings []map[string]interface{}
-	var singleSetting map[string]interface{}
-
-	// Try array first
-	if err := json.Unmarshal(body, &settings); err == nil && len(settings) > 0 {
-		// Use the first setting (usually there's only one)
-		return settings[0], nil
+	settings, err := decodeSettings(data)
+	if err != nil || len(settings) == 0 {
+		return nil, fmt.Errorf("could not parse %s settings response", c.settingKey)
 	}
 
-	// Try single object
-	if err := json.Unmarshal(body, &singleSetting); err == nil {
-		if id, ok := singleSetting["_id"].(string); ok && id != "" {
-			return singleSetting, nil
-		}
-	}
+	// Use the first setting (usually there's only one)
+	return settings[0], nil
+}
 
-	// Try wrapped format { "data": [...] }
-	var wrapper struct {
-		Data []map[string]interface{} `json:"data"`
+// SupportsGeoIPFiltering reports whether the controller exposes region blocking.
+// Controllers and firmware that support it include geo_ip_filtering_* fields in
+// the USG setting; older or unsupported hardware omits them entirely.
+func (c *Client) SupportsGeoIPFiltering() (bool, error) {
+	setting, err := c.GetRegionBlockingSettings()
+	if err != nil {
+		return false, err
 	}
-	if err := json.Unmarshal(body, &wrapper); err == nil && len(wrapper.Data) > 0 {
-		return wrapper.Data[0], nil
+
+	for key := range setting {
+		if strings.HasPrefix(key, "geo_ip_filtering_") {
+			return true, nil
+		}
 	}
 
-	return nil, fmt.Errorf("could not parse usg settings response")
+	return false, nil
 }
 
 // UpdateRegionBlockingSettings updates the region blocking configuration.
@@ -57,15 +78,157 @@ func (c *Client) UpdateRegionBlockingSettings(
 	block string,          // Usually "block"
 	trafficDirection string, // "both", "inbound", or "outbound"
 ) error {
-	// First, get the current setting (as a map to preserve all fields)
+	payload, err := c.RegionBlockingPayload(enabled, countryCodes, block, trafficDirection)
+	if err != nil {
+		return err
+	}
+
+	return c.postRegionBlocking(payload)
+}
+
+// postRegionBlocking posts an updated USG setting and keeps the setting the
+// response echoes, for AppliedSetting.
+func (c *Client) postRegionBlocking(payload map[string]interface{}) error {
+	c.applied = nil
+
+	path := fmt.Sprintf("api/s/%s/set/setting/%s", c.site, c.settingKey)
+	body, status, err := c.Post(path, payload)
+	if err != nil {
+		return fmt.Errorf("failed to update settings: %w", err)
+	}
+
+	data, err := parseEnvelope(status, body)
+	if err != nil {
+		return fmt.Errorf("failed to update settings: %w", err)
+	}
+
+	c.applied = c.echoedSetting(data)
+	if c.verbose && c.applied == nil {
+		console.Printf("[DEBUG] Set response didn't echo the %s setting\n", c.settingKey)
+	}
+
+	return nil
+}
+
+// AppliedSetting returns the setting as stored by the last region blocking
+// update, taken from the set response, so it can be verified without reading
+// it back. ok is false if the response didn't echo it; the setting must then
+// be read back with GetRegionBlockingSettings.
+func (c *Client) AppliedSetting() (setting map[string]interface{}, ok bool) {
+	return c.applied, c.applied != nil
+}
+
+// echoedSetting picks the region blocking setting out of a set response's
+// data. Controllers answer with an array of the updated objects, a single
+// object, or an empty array; an entry only counts if it is this setting and
+// carries geo-ip fields.
+func (c *Client) echoedSetting(data []byte) map[string]interface{} {
+	settings, err := decodeSettings(data)
+	if err != nil {
+		return nil
+	}
+
+	for _, setting := range settings {
+		if key, ok := setting["key"].(string); ok && key != c.settingKey {
+			continue
+		}
+		if _, ok := setting["geo_ip_filtering_enabled"]; ok {
+			return setting
+		}
+	}
+	return nil
+}
+
+// RegionBlockingPayload returns the exact body UpdateRegionBlockingSettings
+// would POST, including every preserved field of the current setting,
+// without sending it.
+func (c *Client) RegionBlockingPayload(
+	enabled bool,
+	countryCodes []string,
+	block string,
+	trafficDirection string,
+) (map[string]interface{}, error) {
+	// Get the current setting (as a map to preserve all fields)
+	current, err := c.GetRegionBlockingSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current settings: %w", err)
+	}
+
+	return buildRegionBlockingPayload(current, c.geoIPLayout(), c.settingKey, enabled, countryCodes, block, trafficDirection), nil
+}
+
+// ValidateRegionBlockingSettings builds the body UpdateRegionBlockingSettings
+// would send and checks it without persisting anything. UniFi has no
+// validate-only endpoint for settings, so the checks are local: required
+// fields, fields dropped from the fetched object, and geo-ip value formats.
+// It returns the list of problems found (empty if the payload looks valid).
+func (c *Client) ValidateRegionBlockingSettings(
+	enabled bool,
+	countryCodes []string,
+	block string,
+	trafficDirection string,
+) ([]string, error) {
 	current, err := c.GetRegionBlockingSettings()
 	if err != nil {
-		return fmt.Errorf("failed to get current settings: %w", err)
+		return nil, fmt.Errorf("failed to get current settings: %w", err)
+	}
+
+	// Remember the original keys before the payload adds to them
+	originalKeys := make([]string, 0, len(current))
+	for key := range current {
+		originalKeys = append(originalKeys, key)
 	}
 
+	payload := buildRegionBlockingPayload(current, c.geoIPLayout(), c.settingKey, enabled, countryCodes, block, trafficDirection)
+
+	var problems []string
+
+	for _, key := range requiredUSGFields {
+		if v, ok := payload[key]; !ok || v == nil || v == "" {
+			problems = append(problems, fmt.Sprintf("missing required field %q", key))
+		}
+	}
+
+	for _, key := range originalKeys {
+		if _, ok := payload[key]; !ok {
+			problems = append(problems, fmt.Sprintf("field %q from current setting would be dropped", key))
+		}
+	}
+
+	for _, code := range countryCodes {
+		if !isCountryCode(code) {
+			problems = append(problems, fmt.Sprintf("invalid country code %q", code))
+		}
+	}
+
+	if b, _ := payload["geo_ip_filtering_block"].(string); b != "block" && b != "allow" {
+		problems = append(problems, fmt.Sprintf("invalid geo_ip_filtering_block %q (expected block or allow)", b))
+	}
+
+	if d, _ := payload["geo_ip_filtering_traffic_direction"].(string); d != "both" && d != "inbound" && d != "outbound" {
+		problems = append(problems, fmt.Sprintf("invalid geo_ip_filtering_traffic_direction %q (expected both, inbound, or outbound)", d))
+	}
+
+	return problems, nil
+}
+
+// requiredUSGFields must be present for set/setting/<key> to accept the update.
+var requiredUSGFields = []string{"_id", "key", "site_id"}
+
+// buildRegionBlockingPayload applies the geo-ip fields to a fetched USG setting
+// stored under key.
+func buildRegionBlockingPayload(
+	current map[string]interface{},
+	layout geoIPLayout,
+	key string,
+	enabled bool,
+	countryCodes []string,
+	block string,
+	trafficDirection string,
+) map[string]interface{} {
 	// Update the geo-ip filtering fields
 	current["geo_ip_filtering_enabled"] = enabled
-	current["geo_ip_filtering_countries"] = strings.Join(countryCodes, ",")
+	current[layout.countriesField] = layout.encodeCountries(current[layout.countriesField], countryCodes)
 	if block != "" {
 		current["geo_ip_filtering_block"] = block
 	} else {
@@ -76,49 +239,141 @@ func (c *Client) UpdateRegionBlockingSettings(
 	} else {
 		current["geo_ip_filtering_traffic_direction"] = "both" // Default
 	}
+	setDirectionalFields(current, layout, countryCodes, current["geo_ip_filtering_traffic_direction"].(string))
 
 	// Ensure required fields exist
 	if current["key"] == nil {
-		current["key"] = "usg"
+		current["key"] = key
 	}
 
-	// Post the updated setting
-	path := fmt.Sprintf("api/s/%s/set/setting/usg", c.site)
-	body, status, err := c.Post(path, current)
+	return current
+}
+
+// GetBlockedCountries returns the current list of blocked country codes.
+func (c *Client) GetBlockedCountries() ([]string, error) {
+	setting, err := c.GetRegionBlockingSettings()
 	if err != nil {
-		return fmt.Errorf("failed to update settings: %w", err)
+		return nil, err
 	}
 
-	if status != 200 {
-		return fmt.Errorf("unexpected status %d when updating settings: %s", status, string(body))
+	enabled, _ := setting["geo_ip_filtering_enabled"].(bool)
+	if !enabled {
+		return []string{}, nil
 	}
 
-	return nil
+	return CountryCodesFromSetting(setting), nil
 }
 
-// GetBlockedCountries returns the current list of blocked country codes.
-func (c *Client) GetBlockedCountries() ([]string, error) {
+// ccodeFields are the entry fields of a stat/ccode response that may hold the
+// alpha-2 code, in the order they are tried. Most versions use "key" for the
+// alpha-2 code and "code" for the numeric one.
+var ccodeFields = []string{"key", "code", "alpha2"}
+
+// GetSupportedCountries returns the alpha-2 codes listed in the controller's
+// country table (stat/ccode), sorted. Region blocking silently ignores codes
+// missing from this table, so callers can check a list before applying it.
+func (c *Client) GetSupportedCountries() ([]string, error) {
+	body, status, err := c.Get("stat/ccode")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get country table: %w", err)
+	}
+
+	data, err := parseEnvelope(status, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get country table: %w", err)
+	}
+
+	var entries []map[string]interface{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("could not parse country table: %w", err)
+	}
+
+	set := make(map[string]bool)
+	for _, entry := range entries {
+		for _, field := range ccodeFields {
+			code, _ := entry[field].(string)
+			code = strings.ToUpper(code)
+			if isCountryCode(code) {
+				set[code] = true
+				break
+			}
+		}
+	}
+	if len(set) == 0 {
+		return nil, fmt.Errorf("country table has no alpha-2 codes")
+	}
+
+	codes := make([]string, 0, len(set))
+	for code := range set {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	return codes, nil
+}
+
+// CountryCodesFromSetting returns the country codes stored in a USG setting,
+// whether or not region blocking is currently enabled. Both the comma string
+// and array encodings are understood. On firmware with per-direction lists
+// the result is the sorted union of those and the combined list, so a code
+// blocked in only one direction still counts; if both per-direction lists
+// are empty the combined list is used as is.
+func CountryCodesFromSetting(setting map[string]interface{}) []string {
+	combined := decodeCountries(setting["geo_ip_filtering_countries"])
+	if !hasDirectionalFields(setting) {
+		return combined
+	}
+
+	dc := DirectionalCountriesFromSetting(setting)
+	if len(dc.Inbound) == 0 && len(dc.Outbound) == 0 {
+		return combined
+	}
+	return ApplyCodeChanges(combined, append(append([]string{}, dc.Inbound...), dc.Outbound...), nil)
+}
+
+// EnsureBlockedCountries makes sure the add codes are blocked and the remove
+// codes are not, leaving every other code, the enabled flag, and the
+// block/direction settings as they are. It returns the resulting code list.
+func (c *Client) EnsureBlockedCountries(add, remove []string) ([]string, error) {
+	for _, code := range append(append([]string{}, add...), remove...) {
+		if !isCountryCode(code) {
+			return nil, fmt.Errorf("invalid country code %q", code)
+		}
+	}
+
 	setting, err := c.GetRegionBlockingSettings()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get current settings: %w", err)
 	}
 
 	enabled, _ := setting["geo_ip_filtering_enabled"].(bool)
-	countriesStr, _ := setting["geo_ip_filtering_countries"].(string)
+	block, _ := setting["geo_ip_filtering_block"].(string)
+	direction, _ := setting["geo_ip_filtering_traffic_direction"].(string)
 
-	if !enabled || countriesStr == "" {
-		return []string{}, nil
+	codes := ApplyCodeChanges(CountryCodesFromSetting(setting), add, remove)
+	if err := c.UpdateRegionBlockingSettings(enabled, codes, block, direction); err != nil {
+		return nil, err
 	}
 
-	// Parse comma-separated string
-	codes := strings.Split(countriesStr, ",")
-	var result []string
-	for _, code := range codes {
-		code = strings.TrimSpace(strings.ToUpper(code))
-		if code != "" {
-			result = append(result, code)
+	return codes, nil
+}
+
+// ApplyCodeChanges returns current plus add, minus remove, sorted and without
+// duplicates. A code in both add and remove is removed.
+func ApplyCodeChanges(current, add, remove []string) []string {
+	return countries.Difference(countries.Union(current, add), remove)
+}
+
+// isCountryCode reports whether code looks like an ISO 3166-1 alpha-2 code.
+func isCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
 		}
 	}
-
-	return result, nil
+	return true
 }
+
@@ -0,0 +1,79 @@
+package unifi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// GetAllSettings fetches every setting object for the site in one call.
+func (c *Client) GetAllSettings() ([]map[string]interface{}, error) {
+	body, status, err := c.Get("rest/setting")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+
+	data, err := parseEnvelope(status, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+
+	return decodeSettings(data)
+}
+
+// FindSettingByKey returns the setting with the given key, or nil if none matches.
+func FindSettingByKey(settings []map[string]interface{}, key string) map[string]interface{} {
+	for _, s := range settings {
+		if k, ok := s["key"].(string); ok && k == key {
+			return s
+		}
+	}
+	return nil
+}
+
+// defaultSettingKey is the setting that holds region blocking on most controllers.
+const defaultSettingKey = "usg"
+
+// settingPathPattern matches set/setting/<key> or rest/setting/<key>,
+// optionally under a proxy/network/api/s/<site>/ prefix.
+var settingPathPattern = regexp.MustCompile(`^/?(?:(?:proxy/network/)?api/s/[^/]+/)?(?:set|rest)/setting/([a-z0-9_]+)/?$`)
+
+// ParseSettingPath checks that path looks like a settings endpoint and
+// returns the setting key it names. Any site in the path is ignored; requests
+// always go to the client's site.
+func ParseSettingPath(path string) (string, error) {
+	m := settingPathPattern.FindStringSubmatch(path)
+	if m == nil {
+		return "", fmt.Errorf("invalid settings path %q (expected e.g. set/setting/usg or api/s/default/set/setting/usg)", path)
+	}
+	return m[1], nil
+}
+
+// decodeSettings parses a settings response. Depending on the controller
+// version it is a bare array, a single object, or wrapped as { "data": [...] }.
+func decodeSettings(body []byte) ([]map[string]interface{}, error) {
+	// Try array first
+	var settings []map[string]interface{}
+	if err := json.Unmarshal(body, &settings); err == nil {
+		return settings, nil
+	}
+
+	// Try single object
+	var single map[string]interface{}
+	if err := json.Unmarshal(body, &single); err == nil {
+		if id, ok := single["_id"].(string); ok && id != "" {
+			return []map[string]interface{}{single}, nil
+		}
+	}
+
+	// Try wrapped format
+	var wrapper struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to parse settings: %w", err)
+	}
+
+	return wrapper.Data, nil
+}
+
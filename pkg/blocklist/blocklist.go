@@ -0,0 +1,108 @@
+// Package blocklist is the public entry point for running the aggregation
+// pipeline from Go, including with custom sources.
+//
+// A custom source implements Scraper, usually by embedding *BaseScraper for
+// fetching, size limits, and the shared circuit breaker:
+//
+//	type threatFeed struct{ *blocklist.BaseScraper }
+//
+//	func (f *threatFeed) Scrape(ctx context.Context) (*blocklist.ScrapeResult, error) {
+//		result := f.NewResult()
+//		body, err := f.Fetch(ctx, f.URL())
+//		if err != nil {
+//			result.ParseStatus = "error"
+//			result.Error = err.Error()
+//			return result, nil
+//		}
+//		result.ContentHash = blocklist.HashContent(body)
+//		result.RawCountries = strings.Fields(string(body))
+//		result.ParseStatus = "success"
+//		return result, nil
+//	}
+//
+// Register it alongside the built-in sources and run the pipeline:
+//
+//	feed := &threatFeed{blocklist.NewBaseScraper("Threat Feed",
+//		"https://feed.example.com/countries.txt", blocklist.CategorySanctions, nil)}
+//	registry := blocklist.DefaultRegistryWith(nil, feed)
+//	result := blocklist.Run(ctx, blocklist.Options{Registry: registry})
+//
+// Use NewRegistry and Registry.Register instead to run only your own sources.
+package blocklist
+
+import (
+	"context"
+	"io"
+
+	"github.com/mattsblocklist/tae/internal/aggregate"
+	"github.com/mattsblocklist/tae/internal/countries"
+	"github.com/mattsblocklist/tae/internal/scrapers"
+)
+
+// Types for implementing and registering sources.
+type (
+	Scraper      = scrapers.Scraper
+	BaseScraper  = scrapers.BaseScraper
+	ScrapeResult = scrapers.ScrapeResult
+	HTTPClient   = scrapers.HTTPClient
+	Registry     = scrapers.Registry
+)
+
+// Types for running the pipeline and reading its output.
+type (
+	Options               = aggregate.Options
+	Result                = aggregate.AggregationResult
+	CountryWithProvenance = aggregate.CountryWithProvenance
+	SourceStats           = aggregate.SourceStats
+	Normalizer            = countries.Normalizer
+)
+
+// Source categories.
+const (
+	CategorySanctions  = scrapers.CategorySanctions
+	CategoryCensorship = scrapers.CategoryCensorship
+)
+
+// NewBaseScraper creates the base for a custom scraper. A nil client uses a
+// default HTTP client with a 30s timeout.
+func NewBaseScraper(name, url, category string, client HTTPClient) *BaseScraper {
+	return scrapers.NewBaseScraper(name, url, category, client)
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return scrapers.NewRegistry()
+}
+
+// DefaultRegistry creates a registry with the built-in sources.
+func DefaultRegistry(client HTTPClient) *Registry {
+	return scrapers.DefaultRegistry(client)
+}
+
+// DefaultRegistryWith creates a registry with the built-in sources plus extra.
+func DefaultRegistryWith(client HTTPClient, extra ...Scraper) *Registry {
+	return scrapers.DefaultRegistryWith(client, extra...)
+}
+
+// NewNormalizerWithOverrides creates a normalizer with the CODE,ACTION,ALIAS
+// overrides read from r applied on top of the built-in names. Set it as
+// Options.Normalizer.
+func NewNormalizerWithOverrides(r io.Reader) (*Normalizer, error) {
+	return countries.NewNormalizerWithOverrides(r)
+}
+
+// HashContent returns the SHA256 hex digest used for ScrapeResult.ContentHash.
+func HashContent(content []byte) string {
+	return scrapers.HashContent(content)
+}
+
+// Run scrapes and aggregates the sources in opts.Registry (the built-ins if
+// nil) and returns the result with metadata populated.
+func Run(ctx context.Context, opts Options) *Result {
+	return aggregate.Run(ctx, opts)
+}
+
+// WriteOutputs writes the text and JSON renderings of a result to disk.
+func WriteOutputs(result *Result, txtPath, jsonPath string) error {
+	return aggregate.WriteOutputs(result, txtPath, jsonPath)
+}